@@ -2,27 +2,44 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/weatherman/dgx-manager/internal/alert"
 	"github.com/weatherman/dgx-manager/internal/config"
+	"github.com/weatherman/dgx-manager/internal/diagnostics"
+	"github.com/weatherman/dgx-manager/internal/exporter"
+	"github.com/weatherman/dgx-manager/internal/firmware"
+	"github.com/weatherman/dgx-manager/internal/fleet"
 	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/health"
+	"github.com/weatherman/dgx-manager/internal/output"
 	"github.com/weatherman/dgx-manager/internal/playbook"
 	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/internal/support"
+	"github.com/weatherman/dgx-manager/internal/tune"
 	"github.com/weatherman/dgx-manager/internal/tunnel"
 	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
 var (
-	cfgManager *config.Manager
-	Version    = "0.1.0"
+	cfgManager   *config.Manager
+	outputFormat = output.FormatTable
+	Version      = "0.1.0"
 )
 
 func main() {
@@ -35,7 +52,7 @@ func main() {
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(output.ExitUsage)
 	}
 }
 
@@ -51,8 +68,34 @@ var rootCmd = &cobra.Command{
 			strings.Contains(cmdPath, "help") ||
 			strings.Contains(cmdPath, "completion")
 
+		// Resolve the target profile: --profile flag, then DGX_PROFILE, then current
+		profileName, _ := cmd.Flags().GetString("profile")
+		format, _ := cmd.Flags().GetString("output")
+		if cmd.DisableFlagParsing {
+			profileName, format, _ = splitGlobalArgs(args)
+		}
+
+		var err error
+		if outputFormat, err = output.ParseFormat(format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(output.ExitUsage)
+		}
+
+		if profileName == "" {
+			profileName = os.Getenv(config.ProfileEnvVar)
+		}
+		if profileName != "" {
+			if err := cfgManager.Select(profileName); err != nil {
+				if !noConfigRequired {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
 		if !noConfigRequired && !cfgManager.IsConfigured() {
-			fmt.Fprintf(os.Stderr, "Error: DGX not configured. Run 'dgx config set' first.\n")
+			fmt.Fprintf(os.Stderr, "Error: DGX profile %q not configured. Run 'dgx config set' first.\n", cfgManager.Get().Name)
 			os.Exit(1)
 		}
 	},
@@ -219,7 +262,21 @@ var configShowCmd = &cobra.Command{
 	Short: "Show current configuration",
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := cfgManager.Get()
+		if output.Structured(outputFormat) {
+			writeOutput(profileInfo{
+				Profile:      cfg.Name,
+				Host:         cfg.Host,
+				Port:         cfg.Port,
+				User:         cfg.User,
+				IdentityFile: cfg.IdentityFile,
+				Current:      cfg.Name == cfgManager.ActiveProfile(),
+				ConfigPath:   cfgManager.GetConfigPath(),
+			})
+			return
+		}
+
 		fmt.Println("DGX Configuration:")
+		fmt.Printf("  Profile:      %s\n", cfg.Name)
 		fmt.Printf("  Host:         %s\n", cfg.Host)
 		fmt.Printf("  Port:         %d\n", cfg.Port)
 		fmt.Printf("  User:         %s\n", cfg.User)
@@ -228,6 +285,157 @@ var configShowCmd = &cobra.Command{
 	},
 }
 
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List configured DGX profiles",
+	Aliases: []string{"ls"},
+	Run: func(cmd *cobra.Command, args []string) {
+		names := cfgManager.Profiles()
+		if output.Structured(outputFormat) {
+			profiles := make([]profileInfo, 0, len(names))
+			for _, name := range names {
+				cfg, _ := cfgManager.GetProfile(name)
+				profiles = append(profiles, profileInfo{
+					Profile:      name,
+					Host:         cfg.Host,
+					Port:         cfg.Port,
+					User:         cfg.User,
+					IdentityFile: cfg.IdentityFile,
+					Current:      name == cfgManager.ActiveProfile(),
+				})
+			}
+			writeOutput(profiles)
+			return
+		}
+
+		if len(names) == 0 {
+			fmt.Println("No profiles configured")
+			fmt.Println("\nTo add one:")
+			fmt.Println("  dgx config set")
+			fmt.Println("  dgx config add <name> --host <host> --user <user>")
+			return
+		}
+
+		fmt.Println("DGX Profiles:")
+		fmt.Println("-------------")
+		for _, name := range names {
+			cfg, _ := cfgManager.GetProfile(name)
+			marker := " "
+			if name == cfgManager.ActiveProfile() {
+				marker = "*"
+			}
+			fmt.Printf("%s %-20s %s@%s:%d\n", marker, name, cfg.User, cfg.Host, cfg.Port)
+		}
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch the current DGX profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cfgManager.Use(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg := cfgManager.Get()
+		fmt.Printf("Switched to profile %s (%s@%s:%d)\n", cfg.Name, cfg.User, cfg.Host, cfg.Port)
+	},
+}
+
+var configAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a named DGX profile",
+	Long: `Add a named DGX profile without touching the others.
+
+Examples:
+  dgx config add spark-a --host 192.168.1.20 --user alice
+  dgx config add spark-b --host spark-b.lab --user alice --port 2222 --identity-file ~/.ssh/spark_b --use`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		host, _ := cmd.Flags().GetString("host")
+		user, _ := cmd.Flags().GetString("user")
+		port, _ := cmd.Flags().GetInt("port")
+		identityFile, _ := cmd.Flags().GetString("identity-file")
+		use, _ := cmd.Flags().GetBool("use")
+
+		if host == "" || user == "" {
+			fmt.Fprintf(os.Stderr, "Error: --host and --user are required\n")
+			os.Exit(1)
+		}
+
+		if identityFile == "" {
+			home, _ := os.UserHomeDir()
+			identityFile = filepath.Join(home, ".ssh", "id_ed25519")
+		} else {
+			expanded, err := expandPath(identityFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			identityFile = expanded
+		}
+
+		cfg := &types.Config{
+			Host:         host,
+			Port:         port,
+			User:         user,
+			IdentityFile: identityFile,
+			Tunnels:      []types.Tunnel{},
+		}
+		if err := cfgManager.AddProfile(args[0], cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Added profile %s (%s@%s:%d)\n", args[0], user, host, cfg.Port)
+
+		if use {
+			if err := cfgManager.Use(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Switched to profile %s\n", args[0])
+		}
+	},
+}
+
+var configRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Short:   "Remove a DGX profile",
+	Aliases: []string{"rm"},
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cfgManager.RemoveProfile(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed profile %s\n", args[0])
+		if current := cfgManager.CurrentProfile(); current != "" {
+			fmt.Printf("Current profile: %s\n", current)
+		}
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import every NVIDIA Sync host as a profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		added, err := cfgManager.ImportNVSyncProfiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: unable to inspect NVIDIA Sync config: %v\n", err)
+			os.Exit(1)
+		}
+		if len(added) == 0 {
+			fmt.Println("No new NVIDIA Sync hosts found")
+			return
+		}
+		for _, name := range added {
+			cfg, _ := cfgManager.GetProfile(name)
+			fmt.Printf("Imported profile %s (%s@%s:%d)\n", name, cfg.User, cfg.Host, cfg.Port)
+		}
+	},
+}
+
 // connect command
 var connectCmd = &cobra.Command{
 	Use:     "connect",
@@ -251,7 +459,18 @@ var connectCmd = &cobra.Command{
 // status command
 var statusCmd = &cobra.Command{
 	Use:   "status",
-	Short: "Check DGX connection status",
+	Short: "Check DGX connection and host health",
+	Long: `Check the SSH connection and report the health of the DGX.
+
+Each probe reports ok, warn or fail: OS and kernel, uptime, load average,
+unified memory (available and pressure), free disk on / and the Docker data
+root, thermals, GPUs, playbook services (ollama, the vllm-server container,
+Docker Model Runner) and the local tunnels. Remote probes share one SSH
+round trip.
+
+Examples:
+  dgx status
+  dgx status -o json | jq '.checks[] | select(.status != "ok")'`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := cfgManager.Get()
 		client, err := ssh.NewClient(cfg)
@@ -260,19 +479,68 @@ var statusCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Checking connection to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
-		latency, err := client.CheckConnection()
-		if err != nil {
-			fmt.Printf("Connection failed: %v\n", err)
-			os.Exit(1)
+		structured := output.Structured(outputFormat)
+		if !structured {
+			fmt.Printf("Checking connection to %s@%s:%d...\n", cfg.User, cfg.Host, cfg.Port)
 		}
 
-		fmt.Printf("Connected (latency: %v)\n", latency)
+		status := types.ConnectionStatus{
+			Profile: cfg.Name,
+			Host:    cfg.Host,
+			Port:    cfg.Port,
+			User:    cfg.User,
+		}
+		probes := health.Probes()
+		var in health.Input
+
+		start := time.Now()
+		err = client.Connect()
+		latency := time.Since(start)
+		collected := false
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Connected = true
+			status.LatencyMS = float64(latency.Microseconds()) / 1000
+
+			// Every remote probe shares the connection and one remote command
+			in.Snapshot, err = gpu.NewMonitor(client).Collect(health.Sections(probes)...)
+			client.Close()
+			if err != nil {
+				status.Error = err.Error()
+			} else {
+				collected = true
+				status.System = &in.Snapshot.Host
+				status.GPUs = in.Snapshot.GPUs
+				status.Services = health.Services(in.Snapshot)
+			}
+		}
 
-		// Check for active tunnels
+		// Saved tunnels joined with the live ones from the daemon and ssh
 		tm := tunnel.NewManager(cfg)
-		tunnels, _ := tm.List()
-		fmt.Printf("Active tunnels: %d\n", len(tunnels))
+		live, _ := tm.List()
+		for _, s := range tunnel.Reconcile(cfg.Tunnels, live) {
+			in.Tunnels = append(in.Tunnels, s.Tunnel())
+			if s.Live != nil {
+				status.ActiveTunnels++
+			}
+		}
+		status.Tunnels = in.Tunnels
+
+		status.Checks = append([]types.HealthCheck{health.Connection(latency, status.Error)},
+			health.Evaluate(probes, in, collected)...)
+		status.Health = health.Worst(status.Checks)
+
+		if structured {
+			writeOutput(status)
+		} else {
+			fmt.Println()
+			fmt.Print(health.Format(status.Checks))
+		}
+
+		if !status.Connected {
+			os.Exit(output.ExitUnreachable)
+		}
 	},
 }
 
@@ -284,48 +552,108 @@ var tunnelCmd = &cobra.Command{
 }
 
 var tunnelCreateCmd = &cobra.Command{
-	Use:     "create <local-port>:<remote-port> [description]",
-	Short:   "Create a new SSH tunnel",
+	Use:   "create <local-port>:<remote-port> [description]",
+	Short: "Create a new SSH tunnel",
+	Long: `Create a new SSH tunnel over the active profile.
+
+By default localhost:<local-port> is forwarded to port <remote-port> on the DGX.
+With --reverse the direction is flipped: port <remote-port> on the DGX's
+loopback connects back to localhost:<local-port>, so jobs on the DGX can reach
+a service on this machine. With --socks <port> a SOCKS5 proxy is started on
+localhost:<port> whose connections leave from the DGX; every argument is then
+the description.
+
+Examples:
+  dgx tunnel create 8888:8888 "Jupyter"
+  dgx tunnel create --reverse 5000:5000 "Local MLflow"
+  dgx tunnel create --socks 1080 "Spark LAN"`,
 	Aliases: []string{"add", "new"},
-	Args:    cobra.MinimumNArgs(1),
+	Args:    cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		parts := strings.Split(args[0], ":")
-		if len(parts) != 2 {
-			fmt.Fprintf(os.Stderr, "Error: Invalid format. Use <local-port>:<remote-port>\n")
-			os.Exit(1)
-		}
+		reverse, _ := cmd.Flags().GetBool("reverse")
+		socksPort, _ := cmd.Flags().GetInt("socks")
+
+		kind := tunnel.KindLocal
+		var localPort, remotePort int
+		var descArgs []string
+		switch {
+		case socksPort > 0:
+			if reverse {
+				fmt.Fprintf(os.Stderr, "Error: --reverse and --socks cannot be combined\n")
+				os.Exit(1)
+			}
+			kind = tunnel.KindDynamic
+			localPort = socksPort
+			descArgs = args
+		default:
+			if len(args) < 1 {
+				fmt.Fprintf(os.Stderr, "Error: Missing port mapping. Use <local-port>:<remote-port>\n")
+				os.Exit(1)
+			}
+			if reverse {
+				kind = tunnel.KindRemote
+			}
 
-		localPort, err := strconv.Atoi(parts[0])
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid local port: %s\n", parts[0])
-			os.Exit(1)
-		}
+			parts := strings.Split(args[0], ":")
+			if len(parts) != 2 {
+				fmt.Fprintf(os.Stderr, "Error: Invalid format. Use <local-port>:<remote-port>\n")
+				os.Exit(1)
+			}
 
-		remotePort, err := strconv.Atoi(parts[1])
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid remote port: %s\n", parts[1])
-			os.Exit(1)
-		}
+			var err error
+			localPort, err = strconv.Atoi(parts[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid local port: %s\n", parts[0])
+				os.Exit(1)
+			}
 
-		description := ""
-		if len(args) > 1 {
-			description = strings.Join(args[1:], " ")
+			remotePort, err = strconv.Atoi(parts[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Invalid remote port: %s\n", parts[1])
+				os.Exit(1)
+			}
+			descArgs = args[1:]
 		}
 
+		description := strings.Join(descArgs, " ")
+
 		tm := tunnel.NewManager(cfgManager.Get())
 
-		// Check if port is already in use
-		if tm.IsPortInUse(localPort) {
+		// Reverse tunnels connect to a local service rather than listening locally
+		if kind != tunnel.KindRemote && tm.IsPortInUse(localPort) {
 			fmt.Fprintf(os.Stderr, "Error: Local port %d is already in use\n", localPort)
 			os.Exit(1)
 		}
 
+		backend := tunnel.BackendDaemon
+		if useSSH, _ := cmd.Flags().GetBool("ssh"); useSSH {
+			backend = tunnel.BackendSSH
+		}
+
+		autostart, _ := cmd.Flags().GetBool("autostart")
+		healthPath, _ := cmd.Flags().GetString("health-path")
+
+		id := fmt.Sprintf("tunnel-%d", localPort)
+		switch kind {
+		case tunnel.KindRemote:
+			id = fmt.Sprintf("reverse-%d", remotePort)
+		case tunnel.KindDynamic:
+			id = fmt.Sprintf("socks-%d", localPort)
+		}
+
 		t := types.Tunnel{
-			ID:          fmt.Sprintf("tunnel-%d", time.Now().Unix()),
+			ID:          id,
 			LocalPort:   localPort,
 			RemotePort:  remotePort,
 			RemoteHost:  "localhost",
 			Description: description,
+			Backend:     backend,
+			Autostart:   autostart,
+			HealthPath:  healthPath,
+			CreatedAt:   time.Now(),
+		}
+		if kind != tunnel.KindLocal {
+			t.Kind = kind
 		}
 
 		if err := tm.Create(t); err != nil {
@@ -333,156 +661,979 @@ var tunnelCreateCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Save to config
-		cfgManager.AddTunnel(t)
+		// Save to config, replacing any stale definition on the same port
+		for _, st := range tunnel.Reconcile(cfgManager.Get().Tunnels, []types.Tunnel{t}) {
+			if st.Saved != nil && st.Live != nil {
+				if err := cfgManager.RemoveTunnel(st.Saved.ID); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to remove stale tunnel definition %s: %v\n", st.Saved.ID, err)
+				}
+			}
+		}
+		if err := cfgManager.AddTunnel(t); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save tunnel definition: %v\n", err)
+		}
 	},
 }
 
 var tunnelListCmd = &cobra.Command{
 	Use:     "list",
-	Short:   "List active SSH tunnels",
+	Short:   "List saved and active SSH tunnels",
 	Aliases: []string{"ls"},
+	Long: `List saved tunnel definitions next to live tunnels, joined on the port
+they listen on (the DGX port for reverse tunnels).
+
+Kinds:
+  local    localhost:<local> forwards to <remote> on the DGX (-L)
+  remote   <remote> on the DGX forwards back to localhost:<local> (-R)
+  dynamic  SOCKS5 proxy on localhost:<local> via the DGX (-D)
+
+States:
+  up        saved and running
+  down      saved but not running (bring it up with 'dgx tunnel up')
+  unsaved   running but not saved in config
+  mismatch  saved and running on the same port with a different target
+
+Health (daemon tunnels):
+  up            SSH connection alive and the forwarded service answers
+  degraded      SSH connection alive but the forwarded service does not answer
+  reconnecting  SSH connection lost; the daemon is re-dialing with backoff`,
 	Run: func(cmd *cobra.Command, args []string) {
 		tm := tunnel.NewManager(cfgManager.Get())
-		tunnels, err := tm.List()
+		live, err := tm.List()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		if len(tunnels) == 0 {
-			fmt.Println("No active tunnels")
+		statuses := tunnel.Reconcile(cfgManager.Get().Tunnels, live)
+		if output.Structured(outputFormat) {
+			tunnels := make([]types.Tunnel, 0, len(statuses))
+			for _, st := range statuses {
+				tunnels = append(tunnels, st.Tunnel())
+			}
+			writeOutput(tunnels)
+			return
+		}
+
+		if len(statuses) == 0 {
+			fmt.Println("No saved or active tunnels")
 			return
 		}
 
-		fmt.Println("Active SSH Tunnels:")
-		fmt.Println("-------------------")
-		for _, t := range tunnels {
-			fmt.Printf("PID %d: localhost:%d -> %s:%d\n",
-				t.PID, t.LocalPort, t.RemoteHost, t.RemotePort)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tKIND\tFORWARD\tBACKEND\tSTATE\tHEALTH\tDESCRIPTION")
+		for _, st := range statuses {
+			t := st.Tunnel()
+			state := t.State
+			if t.Autostart {
+				state += " (autostart)"
+			}
+			health := t.Health
+			if health == "" {
+				health = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				t.ID, tunnel.KindOf(t), tunnel.Describe(t), t.Backend, state, health, t.Description)
 		}
+		tw.Flush()
 	},
 }
 
-var tunnelKillCmd = &cobra.Command{
-	Use:     "kill <pid>",
-	Short:   "Kill a specific tunnel by PID",
-	Aliases: []string{"stop", "rm"},
-	Args:    cobra.ExactArgs(1),
+var tunnelUpCmd = &cobra.Command{
+	Use:   "up [id|local-port...]",
+	Short: "Bring up saved tunnels that are not running",
 	Run: func(cmd *cobra.Command, args []string) {
-		pid, err := strconv.Atoi(args[0])
+		all, _ := cmd.Flags().GetBool("all")
+		saved := selectSavedTunnels(args, all)
+
+		tm := tunnel.NewManager(cfgManager.Get())
+		live, err := tm.List()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Invalid PID: %s\n", args[0])
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		tm := tunnel.NewManager(cfgManager.Get())
-		if err := tm.Kill(pid); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed := false
+		for _, st := range tunnel.Reconcile(saved, live) {
+			if st.Saved == nil {
+				continue
+			}
+			if st.Live != nil {
+				fmt.Printf("%s already running: %s\n", st.Saved.ID, tunnel.Describe(*st.Saved))
+				continue
+			}
+			if tunnel.KindOf(*st.Saved) != tunnel.KindRemote && tm.IsPortInUse(st.Saved.LocalPort) {
+				fmt.Fprintf(os.Stderr, "Error: %s: local port %d is already in use\n", st.Saved.ID, st.Saved.LocalPort)
+				failed = true
+				continue
+			}
+			if err := tm.Create(*st.Saved); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", st.Saved.ID, err)
+				failed = true
+			}
+		}
+		if failed {
 			os.Exit(1)
 		}
 	},
 }
 
-var tunnelKillAllCmd = &cobra.Command{
-	Use:   "kill-all",
-	Short: "Kill all active tunnels",
+var tunnelDownCmd = &cobra.Command{
+	Use:   "down [id|local-port...]",
+	Short: "Tear down running tunnels but keep their saved definitions",
 	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		saved := selectSavedTunnels(args, all)
+
 		tm := tunnel.NewManager(cfgManager.Get())
-		if err := tm.KillAll(); err != nil {
+		live, err := tm.List()
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("All tunnels terminated")
+
+		failed := false
+		for _, st := range tunnel.Reconcile(saved, live) {
+			if st.Saved == nil || st.Live == nil {
+				continue
+			}
+			if err := tm.Kill(st.Live.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", st.Saved.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
 	},
 }
 
-// gpu command
-var gpuCmd = &cobra.Command{
-	Use:   "gpu",
-	Short: "Monitor GPU status",
+var tunnelKillCmd = &cobra.Command{
+	Use:     "kill <id|pid>",
+	Short:   "Kill a specific tunnel by ID (or PID for ssh-backend tunnels)",
+	Aliases: []string{"stop", "rm"},
+	Args:    cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := ssh.NewClient(cfgManager.Get())
+		forget, _ := cmd.Flags().GetBool("forget")
+		tm := tunnel.NewManager(cfgManager.Get())
+
+		live, err := tm.List()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		defer client.Close()
-
-		monitor := gpu.NewMonitor(client)
 
-		// Check if --raw flag is set
-		raw, _ := cmd.Flags().GetBool("raw")
+		// Resolve the saved definition and the live tunnel, joined on their port
+		var target *tunnel.Status
+		statuses := tunnel.Reconcile(cfgManager.Get().Tunnels, live)
+		for i, st := range statuses {
+			if (st.Saved != nil && st.Saved.ID == args[0]) ||
+				(st.Live != nil && (st.Live.ID == args[0] || fmt.Sprint(st.Live.PID) == args[0] && st.Live.Backend == tunnel.BackendSSH)) {
+				target = &statuses[i]
+				break
+			}
+		}
+		if target == nil {
+			fmt.Fprintf(os.Stderr, "Error: tunnel not found: %s\n", args[0])
+			os.Exit(1)
+		}
 
-		if raw {
-			output, err := monitor.GetStatusText()
-			if err != nil {
+		if target.Live != nil {
+			if err := tm.Kill(target.Live.ID); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-			fmt.Println(output)
-		} else {
-			gpus, err := monitor.GetStatus()
-			if err != nil {
+		} else if !forget {
+			fmt.Printf("Tunnel %s is not running. Use --forget to remove its saved definition.\n", args[0])
+		}
+
+		if forget && target.Saved != nil {
+			if err := cfgManager.RemoveTunnel(target.Saved.ID); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-
-			fmt.Println(gpu.FormatGPUStatus(gpus))
+			fmt.Printf("Removed saved definition %s\n", target.Saved.ID)
 		}
 	},
 }
 
-// sync command
-var syncCmd = &cobra.Command{
-	Use:   "sync <source> <destination>",
-	Short: "Sync files between local and DGX",
-	Long: `Sync files using rsync.
-Examples:
-  dgx sync ./code dgx:~/projects/  # Upload to DGX
-  dgx sync dgx:~/results ./        # Download from DGX`,
-	Args: cobra.ExactArgs(2),
+var tunnelKillAllCmd = &cobra.Command{
+	Use:   "kill-all",
+	Short: "Kill all active tunnels",
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := ssh.NewClient(cfgManager.Get())
-		if err != nil {
+		tm := tunnel.NewManager(cfgManager.Get())
+		if err := tm.KillAll(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		fmt.Println("All tunnels terminated")
+	},
+}
 
-		source := args[0]
-		dest := args[1]
-		cfg := cfgManager.Get()
+var tunnelOpenCmd = &cobra.Command{
+	Use:   "open [service]",
+	Short: "Tunnel a well-known service by name and print its URL",
+	Long: `Open a tunnel to a well-known service on the DGX by name.
 
-		// Replace "dgx:" with actual SSH path
-		source = strings.ReplaceAll(source, "dgx:", fmt.Sprintf("%s@%s:", cfg.User, cfg.Host))
-		dest = strings.ReplaceAll(dest, "dgx:", fmt.Sprintf("%s@%s:", cfg.User, cfg.Host))
+A free local port is chosen starting at the service's own port, the tunnel is
+created (or reused if it is already running) and saved, and the command waits
+until the endpoint answers before printing its URL. Run without arguments to
+list the known services.
 
-		deleteFlag, _ := cmd.Flags().GetBool("delete")
+Examples:
+  dgx tunnel open vllm
+  dgx tunnel open ollama --wait 30s
+  dgx tunnel open jupyter --wait 0`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			if output.Structured(outputFormat) {
+				writeOutput(tunnel.Services())
+				return
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "SERVICE\tPORT\tDESCRIPTION")
+			for _, s := range tunnel.Services() {
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.Port, s.Description)
+			}
+			tw.Flush()
+			return
+		}
 
-		fmt.Printf("Syncing %s -> %s\n", args[0], args[1])
-		if err := client.Rsync(source, dest, deleteFlag); err != nil {
+		wait, _ := cmd.Flags().GetDuration("wait")
+		if err := openServiceTunnel(args[0], wait); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-
-		fmt.Println("Sync complete")
 	},
 }
 
-// setup-key command
-var setupKeyCmd = &cobra.Command{
-	Use:   "setup-key",
-	Short: "Setup SSH key authentication with DGX",
-	Long:  `Helps you copy your SSH public key to the DGX for passwordless authentication.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		cfg := cfgManager.Get()
+// openServiceTunnel tunnels a registered service, saves the definition and
+// waits for the endpoint to answer before printing its URL
+func openServiceTunnel(name string, wait time.Duration) error {
+	svc, err := tunnel.LookupService(name)
+	if err != nil {
+		return err
+	}
 
-		// Check if public key exists
-		pubKeyPath := cfg.IdentityFile + ".pub"
-		pubKeyData, err := os.ReadFile(pubKeyPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Cannot read public key at %s\n", pubKeyPath)
-			fmt.Fprintf(os.Stderr, "Make sure your SSH key pair exists.\n")
+	tm := tunnel.NewManager(cfgManager.Get())
+	t, err := tm.Open(svc)
+	if err != nil {
+		return err
+	}
+
+	// Save to config, replacing any stale definition for the service or port
+	for _, saved := range cfgManager.Get().Tunnels {
+		if saved.ID == t.ID || (tunnel.KindOf(saved) == tunnel.KindLocal && saved.LocalPort == t.LocalPort) {
+			if err := cfgManager.RemoveTunnel(saved.ID); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove stale tunnel definition %s: %v\n", saved.ID, err)
+			}
+		}
+	}
+	if err := cfgManager.AddTunnel(t); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save tunnel definition: %v\n", err)
+	}
+
+	url := svc.URL(t.LocalPort)
+	if wait > 0 {
+		fmt.Printf("Waiting for %s on localhost:%d...\n", svc.Description, t.LocalPort)
+		if err := tunnel.WaitForService(svc, t.LocalPort, wait); err != nil {
+			return fmt.Errorf("%w; the tunnel stays open at %s", err, url)
+		}
+	}
+
+	fmt.Printf("%s: %s\n", svc.Description, url)
+	return nil
+}
+
+// selectSavedTunnels returns the saved tunnels named by ID or local port,
+// or all of them with --all
+func selectSavedTunnels(args []string, all bool) []types.Tunnel {
+	saved := cfgManager.Get().Tunnels
+	if all {
+		if len(saved) == 0 {
+			fmt.Println("No saved tunnels")
+		}
+		return saved
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: specify tunnel IDs/local ports or --all\n")
+		os.Exit(1)
+	}
+
+	var selected []types.Tunnel
+	for _, arg := range args {
+		found := false
+		for _, t := range saved {
+			if t.ID == arg || strconv.Itoa(t.LocalPort) == arg {
+				selected = append(selected, t)
+				found = true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Error: saved tunnel not found: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+	return selected
+}
+
+var tunnelDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the tunnel daemon in the foreground",
+	Long: `Run the native Go tunnel daemon for the active profile.
+
+The daemon holds a single SSH connection and owns every tunnel created with
+'dgx tunnel create'. It is started automatically in the background when needed
+and exits once its last tunnel is killed. Control requests are served on
+~/.config/dgx/run/tunnel-<profile>.sock; logs go to tunnel-<profile>.log.
+
+The daemon sends SSH keepalives, re-dials with exponential backoff when the
+connection drops (laptop sleep, Wi-Fi changes), and probes each forwarded
+service. State transitions are recorded in tunnel-<profile>.events and shown
+by 'dgx tunnel events'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon := tunnel.NewDaemon(cfgManager.Get())
+		daemon.KeepaliveInterval, _ = cmd.Flags().GetDuration("keepalive")
+		daemon.ProbeInterval, _ = cmd.Flags().GetDuration("probe-interval")
+		if daemon.KeepaliveInterval <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --keepalive must be greater than 0\n")
+			os.Exit(output.ExitUsage)
+		}
+		if daemon.ProbeInterval <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --probe-interval must be greater than 0\n")
+			os.Exit(output.ExitUsage)
+		}
+		if err := daemon.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var tunnelEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show tunnel state transitions recorded by the tunnel daemon",
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+		tm := tunnel.NewManager(cfgManager.Get())
+		events, err := tm.Events(limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if output.Structured(outputFormat) {
+			if events == nil {
+				events = []tunnel.Event{}
+			}
+			writeOutput(events)
+			return
+		}
+		if len(events) == 0 {
+			fmt.Println("No tunnel events recorded")
+			return
+		}
+		for _, e := range events {
+			fmt.Println(e.String())
+		}
+	},
+}
+
+// gpu command
+var gpuCmd = &cobra.Command{
+	Use:   "gpu",
+	Short: "Monitor GPU status",
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ssh.NewClient(cfgManager.Get())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		monitor := gpu.NewMonitor(client)
+
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			if output.Structured(outputFormat) {
+				fmt.Fprintf(os.Stderr, "Error: --watch only supports table output\n")
+				os.Exit(output.ExitUsage)
+			}
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if err := watchGPUs(monitor, interval); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Check if --raw flag is set
+		raw, _ := cmd.Flags().GetBool("raw")
+
+		if raw {
+			output, err := monitor.GetStatusText()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(output)
+		} else {
+			gpus, err := monitor.GetStatus()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if output.Structured(outputFormat) {
+				writeOutput(gpus)
+				return
+			}
+			fmt.Println(gpu.FormatGPUStatus(gpus))
+		}
+	},
+}
+
+// watchGPUs redraws a live GPU dashboard until Ctrl-C
+func watchGPUs(monitor *gpu.Monitor, interval time.Duration) error {
+	if interval < 100*time.Millisecond {
+		return fmt.Errorf("--interval must be at least 100ms")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cfg := cfgManager.Get()
+	dashboard := gpu.NewDashboard(fmt.Sprintf("%s (%s@%s)", cfg.Name, cfg.User, cfg.Host), 40)
+	dashboard.Start(os.Stdout)
+	defer dashboard.Stop(os.Stdout)
+
+	samples, errs := monitor.Watch(ctx, interval)
+	for sample := range samples {
+		dashboard.Add(sample)
+		dashboard.Render(os.Stdout, sample, interval)
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+var gpuRecordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record GPU metrics in the background",
+	Long: `Sample GPU utilization, memory, temperature and power at an interval and
+append them to a local history in ~/.config/dgx/metrics/.
+
+The recorder runs detached from the terminal, keeps one SSH session open,
+and reconnects when the connection drops. It keeps samples at full
+resolution for --raw-retention, then averages them into --resolution
+buckets. It drops samples older than --retention.
+
+Examples:
+  dgx gpu record                  # Start recording every 10s
+  dgx gpu record --interval 30s   # Sample less often
+  dgx gpu record --status         # Is the recorder running?
+  dgx gpu record --stop           # Stop recording
+  dgx gpu history --since 6h      # Summarize what was recorded`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+
+		if stop, _ := cmd.Flags().GetBool("stop"); stop {
+			pid, err := gpu.StopRecorder(cfg.Name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ GPU recorder stopped (PID %d)\n", pid)
+			return
+		}
+
+		if status, _ := cmd.Flags().GetBool("status"); status {
+			showRecorderStatus(cfg.Name)
+			return
+		}
+
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval < time.Second {
+			fmt.Fprintf(os.Stderr, "Error: --interval must be at least 1s\n")
+			os.Exit(output.ExitUsage)
+		}
+		store, err := openGPUStore(cmd, cfg.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(output.ExitUsage)
+		}
+
+		if foreground, _ := cmd.Flags().GetBool("foreground"); foreground {
+			if err := recordGPUs(cfg, store, interval); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		if pid := gpu.RecorderPID(cfg.Name); pid != 0 {
+			fmt.Printf("GPU recorder is already running (PID %d)\n", pid)
+			return
+		}
+
+		// Connect once in the foreground so host key prompts reach the user
+		client, err := ssh.NewClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if _, err := client.CheckConnection(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(output.ExitUnreachable)
+		}
+		client.Close()
+
+		childArgs := []string{"gpu", "record", "--foreground", "--interval", interval.String()}
+		for _, name := range []string{"retention", "raw-retention", "resolution"} {
+			value, _ := cmd.Flags().GetString(name)
+			childArgs = append(childArgs, "--"+name, value)
+		}
+		pid, err := gpu.StartRecorder(cfg.Name, childArgs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		paths, _ := gpu.PathsForRecorder(cfg.Name)
+		fmt.Printf("✓ GPU recorder started (PID %d), sampling every %v\n", pid, interval)
+		fmt.Printf("  History: %s\n", store.Path())
+		fmt.Printf("  Log:     %s\n", paths.Log)
+		fmt.Println("\nView it with: dgx gpu history --since 6h")
+		fmt.Println("Stop it with: dgx gpu record --stop")
+	},
+}
+
+// openGPUStore opens the profile's GPU history with the retention flags
+func openGPUStore(cmd *cobra.Command, profile string) (*gpu.Store, error) {
+	store, err := gpu.OpenStore(profile)
+	if err != nil {
+		return nil, err
+	}
+	for name, dest := range map[string]*time.Duration{
+		"retention":     &store.Retention,
+		"raw-retention": &store.RawRetention,
+		"resolution":    &store.Resolution,
+	} {
+		value, _ := cmd.Flags().GetString(name)
+		d, err := gpu.ParseAge(value)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %w", name, err)
+		}
+		*dest = d
+	}
+	if store.RawRetention > store.Retention {
+		return nil, fmt.Errorf("--raw-retention must not exceed --retention")
+	}
+	return store, nil
+}
+
+// recordGPUs runs the recorder in this process until SIGINT/SIGTERM
+func recordGPUs(cfg *types.Config, store *gpu.Store, interval time.Duration) error {
+	removePID, err := gpu.WritePIDFile(cfg.Name)
+	if err != nil {
+		return err
+	}
+	defer removePID()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	client, err := ssh.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	client.SetBatchMode(true)
+	defer client.Close()
+
+	fmt.Printf("%s GPU recorder started for %s@%s (PID %d), sampling every %v into %s\n",
+		time.Now().Format(time.RFC3339), cfg.User, cfg.Host, os.Getpid(), interval, store.Path())
+	err = gpu.NewMonitor(client).Record(ctx, store, interval)
+	fmt.Printf("%s GPU recorder stopped\n", time.Now().Format(time.RFC3339))
+	return err
+}
+
+// showRecorderStatus prints whether the recorder is running and where its
+// history lives
+func showRecorderStatus(profile string) {
+	if pid := gpu.RecorderPID(profile); pid != 0 {
+		fmt.Printf("GPU recorder: running (PID %d)\n", pid)
+	} else {
+		fmt.Println("GPU recorder: not running")
+	}
+
+	path, err := gpu.StorePath(profile)
+	if err != nil {
+		return
+	}
+	if info, err := os.Stat(path); err == nil {
+		size := uint64(info.Size())
+		fmt.Printf("History:      %s (%s, last written %s)\n", path, gpu.FormatBytes(&size),
+			info.ModTime().Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Printf("History:      %s (empty)\n", path)
+	}
+	if paths, err := gpu.PathsForRecorder(profile); err == nil {
+		fmt.Printf("Log:          %s\n", paths.Log)
+	}
+}
+
+var gpuHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Summarize recorded GPU metrics",
+	Long: `Show min, average, max and 95th percentile of GPU utilization, memory,
+temperature and power over a time range recorded by 'dgx gpu record'.
+
+--export writes the recorded samples instead of a summary, one row per GPU
+per sample. Downsampled rows have a samples count above 1.
+
+Examples:
+  dgx gpu history                          # Last 6 hours
+  dgx gpu history --since 12h --gpu 0      # One GPU overnight
+  dgx gpu history -o json                  # Summary as JSON
+  dgx gpu history --since 7d --export csv > gpu.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		since, err := gpu.ParseAge(sinceFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --since: %v\n", err)
+			os.Exit(output.ExitUsage)
+		}
+		export, _ := cmd.Flags().GetString("export")
+		if export != "" && export != "csv" && export != "json" {
+			fmt.Fprintf(os.Stderr, "Error: --export must be csv or json\n")
+			os.Exit(output.ExitUsage)
+		}
+
+		store, err := gpu.OpenStore(cfg.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		points, err := store.Query(time.Now().Add(-since))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read GPU history: %v\n", err)
+			os.Exit(1)
+		}
+		if cmd.Flags().Changed("gpu") {
+			id, _ := cmd.Flags().GetInt("gpu")
+			var filtered []gpu.Point
+			for _, p := range points {
+				if p.GPU == id {
+					filtered = append(filtered, p)
+				}
+			}
+			points = filtered
+		}
+
+		switch export {
+		case "csv":
+			if err := gpu.WriteCSV(os.Stdout, points); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "json":
+			if points == nil {
+				points = []gpu.Point{}
+			}
+			if err := output.Write(os.Stdout, output.FormatJSON, points); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		summaries := gpu.Summarize(points)
+		if output.Structured(outputFormat) {
+			writeOutput(summaries)
+			return
+		}
+		if len(summaries) == 0 {
+			fmt.Printf("No GPU history recorded for %s in the last %s\n", cfg.Name, sinceFlag)
+			if gpu.RecorderPID(cfg.Name) == 0 {
+				fmt.Println("\nStart recording with: dgx gpu record")
+			}
+			return
+		}
+		fmt.Print(gpu.FormatHistory(summaries))
+	},
+}
+
+var gpuAlertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Manage GPU alert rules and notifications",
+	Long: `Define GPU alert rules for the active profile and watch them.
+
+Rules are stored in the profile's config. Quote them so the shell does not
+treat > as a redirect:
+
+  <metric> <op> <value> [for <duration>] [while <container>]
+  process <name> disappeared [for <duration>]
+
+Metrics are temperature (°C), utilization (%), power (W) and memory
+(% or GiB). On GB10 unified memory, memory % is relative to host memory.
+Prefix a rule with "gpu N" to check a single GPU.
+
+Alerts always print to stdout. They are also sent to every notifier
+configured with 'dgx gpu alert notify'.
+
+Examples:
+  dgx gpu alert add "temperature > 85°C for 2m"
+  dgx gpu alert add "memory > 95%"
+  dgx gpu alert add "utilization == 0 for 30m while vllm" --name idle-vllm
+  dgx gpu alert add "process python3 disappeared"
+  dgx gpu alert notify --webhook https://hooks.slack.com/services/...
+  dgx gpu alert notify --command 'notify-send dgx "$DGX_ALERT_MESSAGE"'
+  dgx gpu alert watch`,
+}
+
+var gpuAlertAddCmd = &cobra.Command{
+	Use:   "add <rule>",
+	Short: "Add an alert rule",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		rule, err := alert.ParseRule(name, strings.Join(args, " "))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(output.ExitUsage)
+		}
+		if err := cfgManager.AddAlertRule(rule); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Alert rule added: %s\n", rule.Name)
+		if alert.Describe(rule) != rule.Name {
+			fmt.Printf("  %s\n", alert.Describe(rule))
+		}
+	},
+}
+
+var gpuAlertListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List alert rules and notifiers",
+	Run: func(cmd *cobra.Command, args []string) {
+		alerts := cfgManager.Get().Alerts
+		if output.Structured(outputFormat) {
+			if alerts.Rules == nil {
+				alerts.Rules = []types.AlertRule{}
+			}
+			if alerts.Notify == nil {
+				alerts.Notify = []types.AlertNotifier{}
+			}
+			writeOutput(alerts)
+			return
+		}
+
+		if len(alerts.Rules) == 0 {
+			fmt.Println("No alert rules configured")
+			fmt.Println("\nAdd one with: dgx gpu alert add \"temperature > 85°C for 2m\"")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tRULE")
+		for _, r := range alerts.Rules {
+			fmt.Fprintf(w, "%s\t%s\n", r.Name, alert.Describe(r))
+		}
+		w.Flush()
+
+		fmt.Println("\nNotifications: stdout" + describeNotifiers(alerts.Notify))
+	},
+}
+
+// describeNotifiers lists configured webhooks and commands after stdout
+func describeNotifiers(notifiers []types.AlertNotifier) string {
+	var sb strings.Builder
+	for _, n := range notifiers {
+		if n.Webhook != "" {
+			sb.WriteString("\n  webhook: " + n.Webhook)
+		}
+		if n.Command != "" {
+			sb.WriteString("\n  command: " + n.Command)
+		}
+	}
+	return sb.String()
+}
+
+var gpuAlertRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove an alert rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cfgManager.RemoveAlertRule(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Alert rule removed: %s\n", args[0])
+	},
+}
+
+var gpuAlertNotifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Configure where alerts are sent",
+	Long: `Add Slack-compatible webhooks or local commands that receive alerts, or
+clear them with --clear. Alerts are always printed to stdout as well.
+
+Webhooks receive a POST with {"text": "<message>", "alert": {...}}.
+Commands run in a local shell with DGX_ALERT_NAME, DGX_ALERT_RULE,
+DGX_ALERT_STATE (firing/resolved), DGX_ALERT_SUBJECT, DGX_ALERT_VALUE,
+DGX_ALERT_MESSAGE and DGX_PROFILE set.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		clearAll, _ := cmd.Flags().GetBool("clear")
+		webhooks, _ := cmd.Flags().GetStringArray("webhook")
+		commands, _ := cmd.Flags().GetStringArray("command")
+
+		notifiers := cfgManager.Get().Alerts.Notify
+		if clearAll {
+			notifiers = nil
+		}
+		for _, url := range webhooks {
+			notifiers = append(notifiers, types.AlertNotifier{Webhook: url})
+		}
+		for _, c := range commands {
+			notifiers = append(notifiers, types.AlertNotifier{Command: c})
+		}
+
+		if clearAll || len(webhooks) > 0 || len(commands) > 0 {
+			if err := cfgManager.SetAlertNotifiers(notifiers); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Println("Notifications: stdout" + describeNotifiers(notifiers))
+	},
+}
+
+var gpuAlertTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test alert to every notifier",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+		a := alert.Alert{
+			Name:    "test",
+			Rule:    "test alert from dgx",
+			Profile: cfg.Name,
+			Metric:  alert.MetricTemperature,
+			Subject: "GPU 0",
+			State:   alert.StateFiring,
+			Value:   "0°C",
+			Time:    time.Now(),
+		}
+		failed := false
+		for _, n := range alert.Notifiers(cfg.Alerts.Notify, os.Stdout) {
+			if err := n.Notify(a); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var gpuAlertWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Evaluate alert rules until Ctrl-C",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+		if len(cfg.Alerts.Rules) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no alert rules configured. Add one with 'dgx gpu alert add'.\n")
+			os.Exit(1)
+		}
+		engine, err := alert.NewEngine(cfg.Name, cfg.Alerts.Rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval < time.Second {
+			fmt.Fprintf(os.Stderr, "Error: --interval must be at least 1s\n")
+			os.Exit(output.ExitUsage)
+		}
+
+		client, err := ssh.NewClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		fmt.Printf("%s Watching %d alert rules on %s every %v\n",
+			time.Now().Format(time.RFC3339), len(cfg.Alerts.Rules), cfg.Name, interval)
+		sampler := alert.NewMonitorSampler(client, cfg.Alerts.Rules)
+		engine.Run(ctx, sampler, alert.Notifiers(cfg.Alerts.Notify, os.Stdout), interval)
+	},
+}
+
+// sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync <source> <destination>",
+	Short: "Sync files between local and DGX",
+	Long: `Sync files using rsync.
+Examples:
+  dgx sync ./code dgx:~/projects/  # Upload to DGX
+  dgx sync dgx:~/results ./        # Download from DGX`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := ssh.NewClient(cfgManager.Get())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		source := args[0]
+		dest := args[1]
+		cfg := cfgManager.Get()
+
+		// Replace "dgx:" with actual SSH path
+		source = strings.ReplaceAll(source, "dgx:", fmt.Sprintf("%s@%s:", cfg.User, cfg.Host))
+		dest = strings.ReplaceAll(dest, "dgx:", fmt.Sprintf("%s@%s:", cfg.User, cfg.Host))
+
+		deleteFlag, _ := cmd.Flags().GetBool("delete")
+
+		fmt.Printf("Syncing %s -> %s\n", args[0], args[1])
+		if err := client.Rsync(source, dest, deleteFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Sync complete")
+	},
+}
+
+// setup-key command
+var setupKeyCmd = &cobra.Command{
+	Use:   "setup-key",
+	Short: "Setup SSH key authentication with DGX",
+	Long:  `Helps you copy your SSH public key to the DGX for passwordless authentication.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+
+		// Check if public key exists
+		pubKeyPath := cfg.IdentityFile + ".pub"
+		pubKeyData, err := os.ReadFile(pubKeyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Cannot read public key at %s\n", pubKeyPath)
+			fmt.Fprintf(os.Stderr, "Make sure your SSH key pair exists.\n")
 			os.Exit(1)
 		}
 
@@ -555,6 +1706,14 @@ var playbookListCmd = &cobra.Command{
 	Aliases: []string{"ls"},
 	Run: func(cmd *cobra.Command, args []string) {
 		playbooks := playbook.GetAvailablePlaybooks()
+		_, errs := playbook.Specs()
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Warning: skipped playbook: %v\n", err)
+		}
+		if output.Structured(outputFormat) {
+			writeOutput(playbooks)
+			return
+		}
 
 		// Group by category
 		categories := make(map[string][]playbook.Playbook)
@@ -582,6 +1741,36 @@ var playbookListCmd = &cobra.Command{
 		fmt.Println("  dgx run ollama pull qwen2.5:32b")
 		fmt.Println("  dgx run vllm serve meta-llama/Llama-2-7b-hf")
 		fmt.Println("  dgx run nvfp4 quantize meta-llama/Llama-2-7b-hf")
+		if dir, err := playbook.UserDir(); err == nil {
+			fmt.Println()
+			fmt.Printf("Add your own YAML playbooks in %s\n", dir)
+		}
+	},
+}
+
+var playbookValidateCmd = &cobra.Command{
+	Use:   "validate <file>...",
+	Short: "Check YAML playbook files",
+	Long: `Parse YAML playbooks and check their parameters, templates, image and port
+references, health checks and tunnel services without running anything.
+
+Examples:
+  dgx playbook validate ~/.config/dgx/playbooks/*.yaml`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, path := range args {
+			s, err := playbook.LoadSpec(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%s: %s playbook with %d commands (%s)\n", path, s.Name, len(s.Commands), strings.Join(s.CommandNames(), ", "))
+		}
+		if failed {
+			os.Exit(1)
+		}
 	},
 }
 
@@ -594,17 +1783,30 @@ var runCmd = &cobra.Command{
 Available playbooks:
   ollama  - Local model runner (install, pull, serve, run)
   vllm    - Optimized LLM inference (pull, serve, status)
+  trt-llm - TensorRT-LLM engines (setup, build, serve, status, stop, logs)
+  nim     - NVIDIA Inference Microservices (login, list, deploy, status, stop, logs)
+  speculative-decoding - Draft/target serving (serve, bench, status, stop, logs)
+  llama-factory - Fine-tuning (init, train, status, logs, webui, stop, export)
+  unsloth - LoRA fine-tuning (setup, train, status, logs, stop, export)
   nvfp4   - 4-bit quantization (setup, quantize)
   dmr     - Docker Model Runner (setup, install, pull, run, status, logs)
 
+YAML playbooks in ~/.config/dgx/playbooks/ run the same way; see
+'dgx playbook list' and 'dgx run <playbook> --help'.
+
+'serve' and 'nim deploy' accept --tunnel to open the service tunnel and wait
+for it to answer.
+
 Examples:
   dgx run ollama install
   dgx run ollama pull qwen2.5:32b
-  dgx run vllm serve meta-llama/Llama-2-7b-hf
+  dgx run vllm serve meta-llama/Llama-2-7b-hf --tunnel
+  dgx run nim deploy llama-3.1-8b-instruct --tunnel
   dgx run nvfp4 quantize meta-llama/Llama-2-7b-hf
   dgx run dmr status`,
 	DisableFlagParsing: true,
 	Run: func(cmd *cobra.Command, args []string) {
+		_, _, args = splitGlobalArgs(args)
 		if len(args) == 0 || isHelpArg(args[0]) {
 			cmd.Help()
 			return
@@ -618,6 +1820,7 @@ Examples:
 		defer client.Close()
 
 		manager := playbook.NewManager(client)
+		manager.OpenTunnel = openServiceTunnel
 		playbookName := args[0]
 		playbookArgs := args[1:]
 		if len(playbookArgs) > 0 && isHelpArg(playbookArgs[0]) {
@@ -625,11 +1828,69 @@ Examples:
 			return
 		}
 
+		if output.Structured(outputFormat) {
+			if len(playbookArgs) == 0 || playbookArgs[0] != "status" {
+				fmt.Fprintf(os.Stderr, "Error: --output %s is only supported by 'dgx run <playbook> status'\n", outputFormat)
+				os.Exit(output.ExitUsage)
+			}
+			status, err := manager.Status(playbookName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			writeOutput(status)
+			return
+		}
+
 		if err := manager.Execute(playbookName, playbookArgs); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-	},
+	},
+}
+
+// splitGlobalArgs pulls leading --profile and --output flags out of args
+// for commands that disable cobra flag parsing. Flags after the first other
+// argument belong to the command, so a playbook's own flags pass through.
+func splitGlobalArgs(args []string) (profileName, format string, rest []string) {
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--profile" && len(args) > 1:
+			profileName = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--profile="):
+			profileName = strings.TrimPrefix(args[0], "--profile=")
+			args = args[1:]
+		case (args[0] == "--output" || args[0] == "-o") && len(args) > 1:
+			format = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--output="):
+			format = strings.TrimPrefix(args[0], "--output=")
+			args = args[1:]
+		default:
+			return profileName, format, args
+		}
+	}
+	return profileName, format, args
+}
+
+// profileInfo is the structured form of a profile for config show/list
+type profileInfo struct {
+	Profile      string `json:"profile"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	User         string `json:"user"`
+	IdentityFile string `json:"identity_file"`
+	Current      bool   `json:"current"`
+	ConfigPath   string `json:"config_path,omitempty"`
+}
+
+// writeOutput prints v in the selected structured output format
+func writeOutput(v interface{}) {
+	if err := output.Write(os.Stdout, outputFormat, v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 func isHelpArg(arg string) bool {
@@ -782,11 +2043,12 @@ func resolveRemotePath(input string, cfg *types.Config) string {
 var envCmd = &cobra.Command{
 	Use:   "env",
 	Short: "Manage environment tokens on your DGX",
-	Long: `Store secrets (HF_TOKEN, WANDB_API_KEY, etc.) in ~/.config/dgx/env.sh so every shell on the DGX picks them up.
+	Long: `Store secrets (HF_TOKEN, WANDB_API_KEY, NGC_API_KEY, etc.) in ~/.config/dgx/env.sh so every shell on the DGX picks them up.
 
 Examples:
   dgx env hf-token
-  dgx env wandb --value your_api_key`,
+  dgx env wandb --value your_api_key
+  dgx env ngc-key`,
 }
 
 var envHFTokenCmd = &cobra.Command{
@@ -809,6 +2071,26 @@ var envHFTokenCmd = &cobra.Command{
 	},
 }
 
+var envNGCKeyCmd = &cobra.Command{
+	Use:   "ngc-key",
+	Short: "Set NGC_API_KEY on the DGX (used by dgx run nim)",
+	Run: func(cmd *cobra.Command, args []string) {
+		value, _ := cmd.Flags().GetString("value")
+		if value == "" {
+			var err error
+			value, err = promptForSecret("NGC API key")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := setRemoteEnvVar("NGC_API_KEY", value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 // codex command
 var codexCmd = &cobra.Command{
 	Use:   "codex",
@@ -994,9 +2276,21 @@ var envWandbCmd = &cobra.Command{
 var execCmd = &cobra.Command{
 	Use:   "exec <command>",
 	Short: "Execute a command on the DGX",
-	Long:  `Run an arbitrary shell command on your DGX Spark.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Run an arbitrary shell command on your DGX Spark, or on several profiles at once.
+
+Examples:
+  dgx exec nvidia-smi
+  dgx exec --all nvidia-smi --query-gpu=driver_version --format=csv,noheader
+  dgx exec --hosts spark-a,spark-b --parallel 2 --fail-fast "docker ps"`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		hostNames, _ := cmd.Flags().GetStringSlice("hosts")
+		if all || len(hostNames) > 0 {
+			runFanOut(cmd, strings.Join(args, " "), all, hostNames)
+			return
+		}
+
 		client, err := ssh.NewClient(cfgManager.Get())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -1015,11 +2309,554 @@ var execCmd = &cobra.Command{
 	},
 }
 
+// runFanOut executes a command on several profiles in parallel and exits
+// non-zero when any host fails
+func runFanOut(cmd *cobra.Command, command string, all bool, hostNames []string) {
+	if all {
+		hostNames = cfgManager.Profiles()
+	}
+	if len(hostNames) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no profiles configured. Add one with 'dgx config add'.\n")
+		os.Exit(1)
+	}
+
+	hosts, err := profileConfigs(hostNames)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	failFast, _ := cmd.Flags().GetBool("fail-fast")
+
+	results := fleet.Execute(hosts, command, fleet.Options{
+		Parallel: parallel,
+		FailFast: failFast,
+	})
+
+	fmt.Println()
+	fmt.Print(fleet.FormatSummary(results))
+
+	for _, r := range results {
+		if r.Failed() {
+			os.Exit(1)
+		}
+	}
+}
+
+// profileConfigs looks up each named profile
+func profileConfigs(names []string) ([]*types.Config, error) {
+	configs := make([]*types.Config, 0, len(names))
+	for _, name := range names {
+		cfg, err := cfgManager.GetProfile(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Serve GPU and tunnel metrics for Prometheus",
+	Long: `Serve Prometheus / OpenMetrics metrics on /metrics for every configured
+profile (or the profiles given with --hosts). Run it on your laptop or a jump
+host and point a Prometheus scrape job at it.
+
+Each scrape reuses data collected within --cache-ttl. Frequent or concurrent
+scrapes share one SSH collection per host instead of opening new sessions.
+
+Metrics are labelled with profile and host:
+  dgx_up                              SSH collection succeeded (1) or failed (0)
+  dgx_ssh_latency_seconds             SSH connect time
+  dgx_gpu_utilization_percent         Per GPU (gpu, name labels)
+  dgx_gpu_memory_used_bytes           Per GPU
+  dgx_gpu_temperature_celsius         Per GPU
+  dgx_gpu_power_draw_watts            Per GPU
+  dgx_gpu_process_memory_used_bytes   Per process (gpu, pid, process labels)
+  dgx_tunnel_up                       Per saved or running tunnel
+
+Examples:
+  dgx exporter                          # Listen on :9835
+  dgx exporter --listen 127.0.0.1:9835 --hosts spark-a,spark-b
+  dgx exporter --cache-ttl 30s`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		listen, _ := cmd.Flags().GetString("listen")
+		hostNames, _ := cmd.Flags().GetStringSlice("hosts")
+		if len(hostNames) == 0 {
+			hostNames = cfgManager.Profiles()
+		}
+		if len(hostNames) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no profiles configured. Add one with 'dgx config add'.\n")
+			os.Exit(1)
+		}
+		hosts, err := profileConfigs(hostNames)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(output.ExitUsage)
+		}
+
+		exp := exporter.New(hosts)
+		exp.CacheTTL, _ = cmd.Flags().GetDuration("cache-ttl")
+		exp.Timeout, _ = cmd.Flags().GetDuration("timeout")
+
+		server := &http.Server{Addr: listen, Handler: exp, ReadHeaderTimeout: 10 * time.Second}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		go func() {
+			<-ctx.Done()
+			server.Close()
+		}()
+
+		fmt.Printf("%s Serving metrics for %s on http://%s/metrics\n",
+			time.Now().Format(time.RFC3339), strings.Join(hostNames, ", "), listen)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var diagnosticsCmd = &cobra.Command{
+	Use:   "diagnostics",
+	Short: "Check driver, CUDA and container runtime alignment",
+	Long: `Run a catalogue of checks on the DGX and report pass, warn or fail with a
+hint for each problem:
+
+  kernel-module      nvidia and nvidia_uvm kernel modules are loaded
+  driver             nvidia-smi works and the driver branch supports the Spark
+  cuda               CUDA version of the driver and of any host toolkit
+  container-toolkit  nvidia-container-toolkit is installed
+  docker-runtime     nvidia-ctk registered the nvidia runtime in daemon.json
+  docker-gpu         docker run --gpus all sees the GPU
+  xid                Xid errors in the kernel log since boot
+
+All checks run in one SSH session. The docker-gpu check may pull --image
+first; skip it with --skip-container. Exits with 1 if any check fails.
+
+Examples:
+  dgx diagnostics
+  dgx diagnostics --skip-container
+  dgx diagnostics --image nvcr.io/nvidia/cuda:13.0.0-base-ubuntu24.04
+  dgx diagnostics -o json`,
+	Aliases: []string{"diag", "doctor"},
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+		client, err := ssh.NewClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		var opts diagnostics.Options
+		opts.Image, _ = cmd.Flags().GetString("image")
+		opts.SkipContainer, _ = cmd.Flags().GetBool("skip-container")
+
+		structured := output.Structured(outputFormat)
+		if !structured {
+			fmt.Printf("Running diagnostics on %s@%s...\n", cfg.User, cfg.Host)
+		}
+		results, err := diagnostics.Run(gpu.NewMonitor(client), diagnostics.Catalogue(opts))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		report := types.DiagnosticsReport{Profile: cfg.Name, Status: diagnostics.Worst(results), Checks: results}
+		if structured {
+			writeOutput(report)
+		} else {
+			fmt.Println()
+			fmt.Print(diagnostics.Format(results))
+		}
+
+		if report.Status == diagnostics.StatusFail {
+			client.Close()
+			os.Exit(1)
+		}
+	},
+}
+
+var supportCmd = &cobra.Command{
+	Use:   "support",
+	Short: "Collect information for support cases",
+}
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Collect a scrubbed diagnostics archive",
+	Long: `Collect everything a support case needs into a tar.gz:
+
+  remote/   nvidia-smi (summary and -q), dmesg, journal (system and docker),
+            docker info, docker ps, daemon.json, container toolkit info,
+            packages, OS release, kernel modules, resources, env.sh and the
+            dgx diagnostics checks
+  local/    dgx config, saved and live tunnels, tunnel events, dgx version
+
+Remote commands share one SSH session. A command that fails is kept with its
+output and listed under failures in manifest.json; if the DGX is unreachable
+the bundle still holds the local files.
+
+Secrets are always redacted: HF_TOKEN, WANDB_API_KEY, CODEX_API_KEY,
+NGC_API_KEY and other *_TOKEN, *_KEY, *_SECRET or *_PASSWORD values from
+env.sh are replaced everywhere in the bundle, as are alert webhook URLs. --redact-ips and
+--redact-users also scrub IPv4 addresses, the DGX host name and usernames.
+Review the archive before sharing it.
+
+Examples:
+  dgx support bundle
+  dgx support bundle --redact-ips --redact-users
+  dgx support bundle -f /tmp/spark-case.tar.gz`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := cfgManager.Get()
+		bundle := support.New(cfg.Name, Version, time.Now())
+
+		fmt.Printf("Collecting from %s@%s...\n", cfg.User, cfg.Host)
+		client, err := ssh.NewClient(cfg)
+		if err == nil {
+			err = bundle.CollectRemote(gpu.NewMonitor(client))
+			client.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: remote collection failed, bundling local files only: %v\n", err)
+		}
+		collectLocalSupport(bundle, cfg)
+
+		redactor := support.NewRedactor()
+		for name, value := range support.EnvSecrets(bundle.Data(support.EnvFile)) {
+			redactor.AddSecret(name, value)
+		}
+		for _, name := range cfgManager.Profiles() {
+			if p, err := cfgManager.GetProfile(name); err == nil {
+				for _, n := range p.Alerts.Notify {
+					redactor.AddSecret("WEBHOOK", n.Webhook)
+				}
+			}
+		}
+		if redactIPs, _ := cmd.Flags().GetBool("redact-ips"); redactIPs {
+			redactor.IPs = true
+			redactor.AddHost(cfg.Host)
+		}
+		if redactUsers, _ := cmd.Flags().GetBool("redact-users"); redactUsers {
+			redactor.AddUser(cfg.User)
+			if u, err := user.Current(); err == nil {
+				// Windows usernames are DOMAIN\name
+				redactor.AddUser(u.Username[strings.LastIndex(u.Username, "\\")+1:])
+			}
+		}
+
+		path, _ := cmd.Flags().GetString("file")
+		if path == "" {
+			path = bundle.Name() + ".tar.gz"
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := bundle.Write(f, redactor); err != nil {
+			f.Close()
+			fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		manifest := bundle.Manifest()
+		fmt.Printf("Support bundle written to %s (%d files, %d failures)\n", path, len(manifest.Files), len(manifest.Failures))
+		for _, failure := range manifest.Failures {
+			fmt.Printf("  - %s\n", failure)
+		}
+		fmt.Println("Review it before sharing; see manifest.json for what was collected and redacted.")
+	},
+}
+
+// collectLocalSupport adds the dgx config and tunnel state to a support bundle
+func collectLocalSupport(bundle *support.Bundle, cfg *types.Config) {
+	configPath := cfgManager.GetConfigPath()
+	if data, err := os.ReadFile(configPath); err == nil {
+		bundle.Add("local/config.yaml", configPath, data)
+	} else {
+		bundle.Fail("local/config.yaml", err)
+	}
+
+	tm := tunnel.NewManager(cfg)
+	live, err := tm.List()
+	if err != nil {
+		bundle.Fail("local/tunnels.json", err)
+	}
+	var tunnels []types.Tunnel
+	for _, s := range tunnel.Reconcile(cfg.Tunnels, live) {
+		tunnels = append(tunnels, s.Tunnel())
+	}
+	bundle.AddJSON("local/tunnels.json", "dgx tunnel list", tunnels)
+
+	if events, err := tm.Events(500); err == nil {
+		bundle.AddJSON("local/tunnel-events.json", "dgx tunnel events -n 500", events)
+	} else {
+		bundle.Fail("local/tunnel-events.json", err)
+	}
+
+	bundle.AddJSON("local/version.json", "dgx version", map[string]string{
+		"version": Version,
+		"os":      runtime.GOOS,
+		"arch":    runtime.GOARCH,
+	})
+}
+
+var tuneCmd = &cobra.Command{
+	Use:   "tune",
+	Short: "Tune the DGX for AI workloads",
+}
+
+var tuneLimitsCmd = &cobra.Command{
+	Use:   "limits",
+	Short: "Inspect and apply kernel, ulimit and shared memory settings",
+	Long: `Compare the DGX's limits with a profile recommended for large model
+serving and training:
+
+  ulimit -n                1048576 open files
+  ulimit -l                unlimited locked memory
+  /dev/shm                 at least half of RAM (reported only)
+  vm.max_map_count         1048576
+  vm.swappiness            10 or lower
+  transparent hugepages    madvise
+  docker default-ulimits   memlock=-1 nofile=1048576 stack=67108864
+  docker default-shm-size  16G
+
+Settings already above the recommendation are never lowered.`,
+}
+
+var tuneLimitsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show current limits against the recommended profile",
+	Long: `Show current limits against the recommended profile. Limits are read in
+one SSH session, as a new login sees them.
+
+Examples:
+  dgx tune limits show
+  dgx tune limits show -o json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		state := readTuneState()
+		settings := state.Settings()
+		if output.Structured(outputFormat) {
+			writeOutput(settings)
+			return
+		}
+		fmt.Print(tune.Format(settings))
+	},
+}
+
+var tuneLimitsApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Write the recommended limits to the DGX with sudo",
+	Long: `Write the settings that differ from the recommended profile and load them:
+
+  /etc/sysctl.d/90-dgx.conf           vm.max_map_count, vm.swappiness
+  /etc/security/limits.d/90-dgx.conf  nofile and memlock for all users
+  /etc/tmpfiles.d/dgx-thp.conf        transparent hugepages
+  /etc/docker/daemon.json             default-ulimits and default-shm-size,
+                                      merged with the existing keys
+
+Every change is shown as a diff first; --dry-run stops there. Files run
+through sudo, which prompts for a password if needed. daemon.json is backed
+up to daemon.json.dgx-backup. Docker only picks up daemon.json after a
+restart, which stops running containers, so it is restarted only with
+--restart-docker. /dev/shm is sized in /etc/fstab and is never changed.
+
+Examples:
+  dgx tune limits apply --dry-run
+  dgx tune limits apply
+  dgx tune limits apply --restart-docker`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		restartDocker, _ := cmd.Flags().GetBool("restart-docker")
+
+		files, err := readTuneState().Plan()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(files) == 0 {
+			fmt.Println("All settings dgx manages already match the recommended profile.")
+			return
+		}
+
+		for _, f := range files {
+			fmt.Print(f.Diff())
+			fmt.Println()
+		}
+		if dryRun {
+			if cmds := tune.FollowUp(files, restartDocker); len(cmds) > 0 {
+				fmt.Println("Then runs:")
+				for _, c := range cmds {
+					fmt.Printf("  %s\n", c)
+				}
+				fmt.Println()
+			}
+			for _, note := range tune.Notes(files, restartDocker) {
+				fmt.Printf("Note: %s\n", note)
+			}
+			fmt.Println("Dry run: nothing was written. Run without --dry-run to apply.")
+			return
+		}
+
+		cfg := cfgManager.Get()
+		client, err := ssh.NewClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := client.RunTTY(tune.ApplyScript(files, restartDocker)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to apply limits: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Limits applied.")
+		for _, note := range tune.Notes(files, restartDocker) {
+			fmt.Printf("  - %s\n", note)
+		}
+	},
+}
+
+// readTuneState reads the DGX's current limits, exiting on failure
+func readTuneState() tune.State {
+	client, err := ssh.NewClient(cfgManager.Get())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	state, err := tune.Read(gpu.NewMonitor(client))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return state
+}
+
+var firmwareCmd = &cobra.Command{
+	Use:   "firmware",
+	Short: "Compare BIOS, driver, DGX OS and images with a compatibility matrix",
+}
+
+var firmwareCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check installed versions against the compatibility matrix",
+	Long: `Read the BIOS version (dmidecode), NVIDIA driver, CUDA version, DGX OS
+release and the container images the playbooks pin, and compare them with a
+compatibility matrix. Components below the recommended version are marked
+upgrade, below the minimum unsupported, each with upgrade advice.
+
+The matrix is built into dgx. To keep your own pinned baseline, write it to
+~/.config/dgx/firmware-matrix.yaml (see 'dgx firmware matrix'), which is
+then used instead, or pass --matrix. Exits with 1 if any component is
+unsupported.
+
+Examples:
+  dgx firmware check
+  dgx firmware check --matrix ./lab-baseline.yaml
+  dgx firmware check -o json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("matrix")
+		matrix, source, err := loadFirmwareMatrix(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg := cfgManager.Get()
+		client, err := ssh.NewClient(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer client.Close()
+
+		installed, err := firmware.Read(gpu.NewMonitor(client))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		results := firmware.Check(matrix, installed)
+		report := types.FirmwareReport{
+			Profile:       cfg.Name,
+			Matrix:        source,
+			MatrixVersion: matrix.Version,
+			Status:        firmware.Worst(results),
+			Components:    results,
+		}
+		if output.Structured(outputFormat) {
+			writeOutput(report)
+		} else {
+			fmt.Printf("Compatibility matrix: %s (version %s)\n\n", source, matrix.Version)
+			fmt.Print(firmware.Format(results))
+		}
+
+		if report.Status == firmware.StatusUnsupported {
+			client.Close()
+			os.Exit(1)
+		}
+	},
+}
+
+var firmwareMatrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "Print the built-in compatibility matrix",
+	Long: `Print the compatibility matrix built into dgx as YAML, as a starting point
+for your own pinned baseline.
+
+Examples:
+  dgx firmware matrix > ~/.config/dgx/firmware-matrix.yaml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Stdout.Write(firmware.EmbeddedMatrix())
+	},
+}
+
+// loadFirmwareMatrix loads the matrix from path, else from the dgx config
+// directory, else the built-in one. It also returns where it came from.
+func loadFirmwareMatrix(path string) (*firmware.Matrix, string, error) {
+	if path == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			return nil, "", err
+		}
+		local := filepath.Join(dir, firmware.MatrixFile)
+		if _, err := os.Stat(local); err == nil {
+			path = local
+		}
+	}
+	if path == "" {
+		return firmware.DefaultMatrix(), "embedded", nil
+	}
+	matrix, err := firmware.LoadMatrix(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return matrix, path, nil
+}
+
 // version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
 	Run: func(cmd *cobra.Command, args []string) {
+		if output.Structured(outputFormat) {
+			writeOutput(map[string]string{"version": Version})
+			return
+		}
 		fmt.Printf("dgx version %s\n", Version)
 	},
 }
@@ -1028,18 +2865,83 @@ func init() {
 	// config subcommands
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configUseCmd)
+	configCmd.AddCommand(configAddCmd)
+	configCmd.AddCommand(configRemoveCmd)
+	configCmd.AddCommand(configImportCmd)
+
+	// config add flags
+	configAddCmd.Flags().String("host", "", "Hostname or IP of the DGX")
+	configAddCmd.Flags().String("user", "", "SSH username")
+	configAddCmd.Flags().Int("port", 22, "SSH port")
+	configAddCmd.Flags().String("identity-file", "", "SSH private key (default ~/.ssh/id_ed25519)")
+	configAddCmd.Flags().Bool("use", false, "Make the new profile current")
+
+	// global flags
+	rootCmd.PersistentFlags().String("profile", "", "DGX profile to use (overrides DGX_PROFILE and the current profile)")
+	rootCmd.PersistentFlags().StringP("output", "o", output.FormatTable, "Output format: table, json or yaml")
 
 	// tunnel subcommands
 	tunnelCmd.AddCommand(tunnelCreateCmd)
 	tunnelCmd.AddCommand(tunnelListCmd)
 	tunnelCmd.AddCommand(tunnelKillCmd)
 	tunnelCmd.AddCommand(tunnelKillAllCmd)
+	tunnelCmd.AddCommand(tunnelDaemonCmd)
+
+	tunnelCmd.AddCommand(tunnelUpCmd)
+	tunnelCmd.AddCommand(tunnelDownCmd)
+	tunnelCmd.AddCommand(tunnelEventsCmd)
+	tunnelCmd.AddCommand(tunnelOpenCmd)
+
+	// tunnel flags
+	tunnelCreateCmd.Flags().Bool("ssh", false, "Use a detached OpenSSH process instead of the tunnel daemon")
+	tunnelCreateCmd.Flags().Bool("autostart", false, "Restore this tunnel whenever the tunnel daemon starts")
+	tunnelUpCmd.Flags().Bool("all", false, "Bring up every saved tunnel")
+	tunnelDownCmd.Flags().Bool("all", false, "Tear down every saved tunnel")
+	tunnelKillCmd.Flags().Bool("forget", false, "Also remove the saved tunnel definition")
+	tunnelCreateCmd.Flags().String("health-path", "", "HTTP path to probe through the tunnel (default: TCP connect)")
+	tunnelOpenCmd.Flags().Duration("wait", 2*time.Minute, "How long to wait for the service to answer (0 to skip)")
+	tunnelCreateCmd.Flags().Bool("reverse", false, "Forward <remote-port> on the DGX back to <local-port> on this machine")
+	tunnelCreateCmd.Flags().Int("socks", 0, "Start a SOCKS5 proxy on this local port that connects from the DGX")
+	tunnelDaemonCmd.Flags().Duration("keepalive", tunnel.DefaultKeepaliveInterval, "SSH keepalive interval")
+	tunnelDaemonCmd.Flags().Duration("probe-interval", tunnel.DefaultProbeInterval, "Health probe interval for forwarded services")
+	tunnelEventsCmd.Flags().IntP("limit", "n", 50, "Number of recent events to show (0 for all)")
 
 	// playbook subcommands
 	playbookCmd.AddCommand(playbookListCmd)
+	playbookCmd.AddCommand(playbookValidateCmd)
 
 	// gpu flags
 	gpuCmd.Flags().BoolP("raw", "r", false, "Show raw nvidia-smi output")
+	gpuCmd.Flags().BoolP("watch", "w", false, "Stream a live dashboard until Ctrl-C")
+	gpuCmd.Flags().Duration("interval", time.Second, "Refresh interval for --watch")
+	gpuRecordCmd.Flags().Duration("interval", 10*time.Second, "Sampling interval")
+	gpuRecordCmd.Flags().Bool("foreground", false, "Record in this process instead of detaching")
+	gpuRecordCmd.Flags().Bool("stop", false, "Stop the running recorder")
+	gpuRecordCmd.Flags().Bool("status", false, "Show whether the recorder is running")
+	gpuRecordCmd.Flags().String("retention", "7d", "Drop samples older than this")
+	gpuRecordCmd.Flags().String("raw-retention", "24h", "Keep full-resolution samples this long before downsampling")
+	gpuRecordCmd.Flags().String("resolution", "1m", "Bucket size for downsampled samples")
+	gpuHistoryCmd.Flags().String("since", "6h", "How far back to look (e.g. 30m, 6h, 7d)")
+	gpuHistoryCmd.Flags().Int("gpu", 0, "Only show this GPU index")
+	gpuHistoryCmd.Flags().String("export", "", "Export recorded samples instead of a summary (csv or json)")
+	gpuCmd.AddCommand(gpuRecordCmd)
+	gpuCmd.AddCommand(gpuHistoryCmd)
+
+	// gpu alert subcommands
+	gpuAlertAddCmd.Flags().String("name", "", "Rule name (defaults to the rule expression)")
+	gpuAlertNotifyCmd.Flags().StringArray("webhook", nil, "Add a Slack-compatible webhook URL")
+	gpuAlertNotifyCmd.Flags().StringArray("command", nil, "Add a local shell command run for each alert")
+	gpuAlertNotifyCmd.Flags().Bool("clear", false, "Remove all webhooks and commands first")
+	gpuAlertWatchCmd.Flags().Duration("interval", 15*time.Second, "How often to sample the GPUs")
+	gpuAlertCmd.AddCommand(gpuAlertAddCmd)
+	gpuAlertCmd.AddCommand(gpuAlertListCmd)
+	gpuAlertCmd.AddCommand(gpuAlertRemoveCmd)
+	gpuAlertCmd.AddCommand(gpuAlertNotifyCmd)
+	gpuAlertCmd.AddCommand(gpuAlertTestCmd)
+	gpuAlertCmd.AddCommand(gpuAlertWatchCmd)
+	gpuCmd.AddCommand(gpuAlertCmd)
 
 	// sync flags
 	syncCmd.Flags().BoolP("delete", "d", false, "Delete extraneous files from destination")
@@ -1047,8 +2949,10 @@ func init() {
 	// env subcommands
 	envHFTokenCmd.Flags().String("value", "", "Token to set (omit to be prompted)")
 	envWandbCmd.Flags().String("value", "", "API key to set (omit to be prompted)")
+	envNGCKeyCmd.Flags().String("value", "", "API key to set (omit to be prompted)")
 	envCmd.AddCommand(envHFTokenCmd)
 	envCmd.AddCommand(envWandbCmd)
+	envCmd.AddCommand(envNGCKeyCmd)
 
 	// codex subcommands
 	codexSetAPIKeyCmd.Flags().String("value", "", "API key to set (omit to be prompted)")
@@ -1068,10 +2972,49 @@ func init() {
 	mutagenCmd.AddCommand(mutagenMonitorCmd)
 	mutagenCmd.AddCommand(mutagenProjectApplyCmd)
 
+	// exec flags
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().Bool("all", false, "Run on every configured profile")
+	execCmd.Flags().StringSlice("hosts", nil, "Comma-separated profiles to run on")
+	execCmd.Flags().Int("parallel", fleet.DefaultParallel, "Maximum hosts to run on at once")
+	execCmd.Flags().Bool("fail-fast", false, "Stop starting new hosts after the first failure")
+
+	// exporter flags
+	exporterCmd.Flags().String("listen", exporter.DefaultListen, "Address to serve /metrics on")
+	exporterCmd.Flags().StringSlice("hosts", nil, "Comma-separated profiles to export (default: all)")
+	exporterCmd.Flags().Duration("cache-ttl", exporter.DefaultCacheTTL, "Reuse collected host data for this long")
+	exporterCmd.Flags().Duration("timeout", exporter.DefaultTimeout, "Give up on a host that takes longer than this")
+
+	// diagnostics flags
+	diagnosticsCmd.Flags().String("image", diagnostics.DefaultImage, "Image to start for the docker-gpu check")
+	diagnosticsCmd.Flags().Bool("skip-container", false, "Skip the docker-gpu check")
+
+	// support subcommands
+	supportBundleCmd.Flags().StringP("file", "f", "", "Archive path (default: ./dgx-support-<profile>-<time>.tar.gz)")
+	supportBundleCmd.Flags().Bool("redact-ips", false, "Also redact IPv4 addresses and the DGX host name")
+	supportBundleCmd.Flags().Bool("redact-users", false, "Also redact the DGX and local usernames")
+	supportCmd.AddCommand(supportBundleCmd)
+
+	// firmware subcommands
+	firmwareCheckCmd.Flags().String("matrix", "", "Compatibility matrix file (default: ~/.config/dgx/firmware-matrix.yaml if present, else built in)")
+	firmwareCmd.AddCommand(firmwareCheckCmd)
+	firmwareCmd.AddCommand(firmwareMatrixCmd)
+
+	// tune subcommands
+	tuneLimitsApplyCmd.Flags().Bool("dry-run", false, "Show the diffs without writing anything")
+	tuneLimitsApplyCmd.Flags().Bool("restart-docker", false, "Restart Docker after changing daemon.json (stops running containers)")
+	tuneLimitsCmd.AddCommand(tuneLimitsShowCmd)
+	tuneLimitsCmd.AddCommand(tuneLimitsApplyCmd)
+	tuneCmd.AddCommand(tuneLimitsCmd)
+
 	// Add all commands to root
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(connectCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(diagnosticsCmd)
+	rootCmd.AddCommand(supportCmd)
+	rootCmd.AddCommand(tuneCmd)
+	rootCmd.AddCommand(firmwareCmd)
 	rootCmd.AddCommand(tunnelCmd)
 	rootCmd.AddCommand(gpuCmd)
 	rootCmd.AddCommand(syncCmd)
@@ -1079,6 +3022,7 @@ func init() {
 	rootCmd.AddCommand(playbookCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(exporterCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(codexCmd)
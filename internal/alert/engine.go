@@ -0,0 +1,278 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Observation is one sample of the DGX as seen by the alert engine
+type Observation struct {
+	Time time.Time
+	GPUs []types.GPUInfo
+	// Containers holds "name image" for each running container. It is only
+	// sampled when a rule has a while condition.
+	Containers []string
+	// HostMemoryTotalBytes is used as the total for GPUs that report none,
+	// such as the GB10 with unified memory
+	HostMemoryTotalBytes *uint64
+}
+
+// Sampler provides observations to the engine
+type Sampler interface {
+	Sample() (Observation, error)
+}
+
+// Alert states
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Alert reports a rule starting or stopping to match
+type Alert struct {
+	Name    string    `json:"name"`
+	Rule    string    `json:"rule"`
+	Profile string    `json:"profile"`
+	Metric  string    `json:"metric"`
+	Subject string    `json:"subject"` // "GPU 0" or the process name
+	State   string    `json:"state"`
+	Value   string    `json:"value,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Message formats the alert as one line for chat and terminals
+func (a Alert) Message() string {
+	var what string
+	switch {
+	case a.Metric != MetricProcess:
+		what = fmt.Sprintf("%s %s is %s", a.Subject, a.Metric, a.Value)
+	case a.State == StateFiring:
+		what = fmt.Sprintf("process %s disappeared", a.Subject)
+	default:
+		what = fmt.Sprintf("process %s is running again", a.Subject)
+	}
+	return fmt.Sprintf("[%s] %s: %s (rule: %s)", strings.ToUpper(a.State), a.Profile, what, a.Rule)
+}
+
+// notAvailable is shown when a resolved alert's metric is no longer reported
+const notAvailable = "N/A"
+
+// Engine evaluates rules against observations and remembers which
+// conditions are pending, firing, or (for process rules) were seen
+type Engine struct {
+	profile string
+	rules   []types.AlertRule
+	holds   map[string]time.Duration
+	pending map[string]time.Time // When the condition started holding
+	firing  map[string]bool
+	seen    map[string]bool
+}
+
+// NewEngine validates rules and creates an engine for a profile
+func NewEngine(profile string, rules []types.AlertRule) (*Engine, error) {
+	e := &Engine{
+		profile: profile,
+		rules:   rules,
+		holds:   make(map[string]time.Duration),
+		pending: make(map[string]time.Time),
+		firing:  make(map[string]bool),
+		seen:    make(map[string]bool),
+	}
+	for _, r := range rules {
+		if err := Validate(r); err != nil {
+			return nil, err
+		}
+		if _, dup := e.holds[r.Name]; dup {
+			return nil, fmt.Errorf("duplicate alert rule name: %s", r.Name)
+		}
+		e.holds[r.Name], _ = holdDuration(r)
+	}
+	return e, nil
+}
+
+// Check takes one sample and evaluates every rule against it
+func (e *Engine) Check(sampler Sampler) ([]Alert, error) {
+	obs, err := sampler.Sample()
+	if err != nil {
+		return nil, err
+	}
+	return e.Evaluate(obs), nil
+}
+
+// Evaluate returns the alerts that fired or resolved with this observation
+func (e *Engine) Evaluate(obs Observation) []Alert {
+	if obs.Time.IsZero() {
+		obs.Time = time.Now()
+	}
+
+	var alerts []Alert
+	for _, r := range e.rules {
+		active := r.WhileContainer == "" || containerRunning(obs.Containers, r.WhileContainer)
+
+		if r.Metric == MetricProcess {
+			key := r.Name + "/" + r.Process
+			present := processRunning(obs.GPUs, r)
+			if present {
+				e.seen[key] = true
+			}
+			match := active && !present && e.seen[key]
+			if a := e.transition(key, match, r, obs.Time); a != nil {
+				a.Subject = r.Process
+				alerts = append(alerts, *a)
+			}
+			continue
+		}
+
+		for _, g := range obs.GPUs {
+			if r.GPU != nil && *r.GPU != g.ID {
+				continue
+			}
+			value := metricValue(r, g, obs.HostMemoryTotalBytes)
+			match := active && value != nil && operators[r.Op](*value, r.Threshold)
+			if a := e.transition(fmt.Sprintf("%s/gpu%d", r.Name, g.ID), match, r, obs.Time); a != nil {
+				a.Subject = fmt.Sprintf("GPU %d", g.ID)
+				a.Value = notAvailable
+				if value != nil {
+					a.Value = formatValue(r, *value)
+				}
+				alerts = append(alerts, *a)
+			}
+		}
+	}
+	return alerts
+}
+
+// transition moves a condition between pending, firing and resolved and
+// returns an alert when it starts firing or stops
+func (e *Engine) transition(key string, match bool, r types.AlertRule, now time.Time) *Alert {
+	alert := &Alert{Name: r.Name, Rule: Describe(r), Profile: e.profile, Metric: r.Metric, Time: now}
+
+	if !match {
+		delete(e.pending, key)
+		if e.firing[key] {
+			delete(e.firing, key)
+			alert.State = StateResolved
+			return alert
+		}
+		return nil
+	}
+
+	start, ok := e.pending[key]
+	if !ok {
+		start = now
+		e.pending[key] = now
+	}
+	if e.firing[key] || now.Sub(start) < e.holds[r.Name] {
+		return nil
+	}
+	e.firing[key] = true
+	alert.State = StateFiring
+	return alert
+}
+
+// metricValue returns a GPU's value for the rule's metric in the rule's unit
+func metricValue(r types.AlertRule, g types.GPUInfo, hostMemoryTotal *uint64) *float64 {
+	switch r.Metric {
+	case MetricTemperature:
+		return g.TemperatureCelsius
+	case MetricUtilization:
+		return g.UtilizationPercent
+	case MetricPower:
+		return g.PowerDrawWatts
+	case MetricMemory:
+		if g.MemoryUsedBytes == nil {
+			return nil
+		}
+		used := float64(*g.MemoryUsedBytes)
+		if r.Unit == "GiB" {
+			v := used / (1 << 30)
+			return &v
+		}
+		total := g.MemoryTotalBytes
+		if total == nil {
+			total = hostMemoryTotal
+		}
+		if total == nil || *total == 0 {
+			return nil
+		}
+		v := used / float64(*total) * 100
+		return &v
+	}
+	return nil
+}
+
+func formatValue(r types.AlertRule, v float64) string {
+	switch r.Metric {
+	case MetricTemperature:
+		return gpu.FormatCelsius(&v)
+	case MetricPower:
+		return gpu.FormatPower(&v, nil)
+	case MetricMemory:
+		if r.Unit == "GiB" {
+			return fmt.Sprintf("%.1f GiB", v)
+		}
+	}
+	return gpu.FormatPercent(&v)
+}
+
+// processRunning reports whether a process matching the rule runs on a
+// GPU the rule covers. Names match case-insensitively as substrings, since
+// nvidia-smi may report full paths.
+func processRunning(gpus []types.GPUInfo, r types.AlertRule) bool {
+	want := strings.ToLower(r.Process)
+	for _, g := range gpus {
+		if r.GPU != nil && *r.GPU != g.ID {
+			continue
+		}
+		for _, p := range g.Processes {
+			if strings.Contains(strings.ToLower(p.Name), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containerRunning(containers []string, match string) bool {
+	match = strings.ToLower(match)
+	for _, c := range containers {
+		if strings.Contains(strings.ToLower(c), match) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run checks the rules every interval and sends alerts to every notifier
+// until ctx is cancelled. Sampling and notification failures are reported
+// on stderr without stopping the loop.
+func (e *Engine) Run(ctx context.Context, sampler Sampler, notifiers []Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		alerts, err := e.Check(sampler)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Sampling failed: %v\n", time.Now().Format(time.RFC3339), err)
+		}
+		for _, a := range alerts {
+			for _, n := range notifiers {
+				if err := n.Notify(a); err != nil {
+					fmt.Fprintf(os.Stderr, "%s Notification failed: %v\n", time.Now().Format(time.RFC3339), err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// fakeSampler replays scripted observations one minute apart
+type fakeSampler struct {
+	observations []Observation
+	start        time.Time
+	next         int
+}
+
+func (f *fakeSampler) Sample() (Observation, error) {
+	if f.next >= len(f.observations) {
+		return Observation{}, errors.New("no more observations")
+	}
+	obs := f.observations[f.next]
+	obs.Time = f.start.Add(time.Duration(f.next) * time.Minute)
+	f.next++
+	return obs, nil
+}
+
+func gb10(temp, util float64, usedGiB uint64, procs ...string) Observation {
+	used, total := usedGiB<<30, uint64(128<<30)
+	g := types.GPUInfo{
+		ID:                 0,
+		Name:               "NVIDIA GB10",
+		TemperatureCelsius: &temp,
+		UtilizationPercent: &util,
+		MemoryUsedBytes:    &used,
+	}
+	for i, name := range procs {
+		g.Processes = append(g.Processes, types.GPUProcess{PID: 100 + i, Name: name})
+	}
+	return Observation{GPUs: []types.GPUInfo{g}, HostMemoryTotalBytes: &total}
+}
+
+func mustRule(t *testing.T, expr string) types.AlertRule {
+	t.Helper()
+	rule, err := ParseRule("", expr)
+	if err != nil {
+		t.Fatalf("parse %q: %v", expr, err)
+	}
+	return rule
+}
+
+// run feeds every observation through the engine and returns alert states per step
+func run(t *testing.T, rules []types.AlertRule, observations ...Observation) [][]Alert {
+	t.Helper()
+	engine, err := NewEngine("spark-a", rules)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	sampler := &fakeSampler{observations: observations, start: time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)}
+
+	var steps [][]Alert
+	for range observations {
+		alerts, err := engine.Check(sampler)
+		if err != nil {
+			t.Fatalf("check: %v", err)
+		}
+		steps = append(steps, alerts)
+	}
+	return steps
+}
+
+func states(alerts []Alert) []string {
+	var s []string
+	for _, a := range alerts {
+		s = append(s, a.State)
+	}
+	return s
+}
+
+func TestTemperatureHold(t *testing.T) {
+	steps := run(t, []types.AlertRule{mustRule(t, "temperature > 85°C for 2m")},
+		gb10(90, 50, 10), // pending
+		gb10(91, 50, 10), // held 1m
+		gb10(92, 50, 10), // held 2m: fires
+		gb10(93, 50, 10), // still firing, no repeat
+		gb10(70, 50, 10), // resolves
+	)
+
+	want := [][]string{nil, nil, {StateFiring}, nil, {StateResolved}}
+	for i := range want {
+		if got := states(steps[i]); len(got) != len(want[i]) || (len(got) > 0 && got[0] != want[i][0]) {
+			t.Fatalf("step %d: expected %v, got %v", i, want[i], got)
+		}
+	}
+	if a := steps[2][0]; a.Value != "92°C" || a.Subject != "GPU 0" {
+		t.Fatalf("unexpected alert %+v", a)
+	}
+}
+
+func TestMemoryPercentUsesHostMemoryOnGB10(t *testing.T) {
+	steps := run(t, []types.AlertRule{mustRule(t, "memory > 95%")},
+		gb10(50, 50, 100),
+		gb10(50, 50, 124), // 124 of 128 GiB = 96.9%
+	)
+	if len(steps[0]) != 0 || len(steps[1]) != 1 || steps[1][0].Value != "97%" {
+		t.Fatalf("unexpected alerts %+v", steps)
+	}
+}
+
+func TestIdleWhileContainer(t *testing.T) {
+	idle := gb10(40, 0, 10)
+	serving := idle
+	serving.Containers = []string{"vllm-server nvcr.io/nvidia/vllm:25.09-py3"}
+
+	steps := run(t, []types.AlertRule{mustRule(t, "utilization == 0 for 2m while vllm")},
+		idle, idle, idle, // idle without vLLM: nothing
+		serving, serving, serving, // idle while vLLM runs for 2m: fires
+		idle, // vLLM stopped: resolves
+	)
+
+	for i := 0; i < 5; i++ {
+		if len(steps[i]) != 0 {
+			t.Fatalf("step %d: unexpected alerts %+v", i, steps[i])
+		}
+	}
+	if got := states(steps[5]); len(got) != 1 || got[0] != StateFiring {
+		t.Fatalf("expected firing at step 5, got %v", got)
+	}
+	if got := states(steps[6]); len(got) != 1 || got[0] != StateResolved {
+		t.Fatalf("expected resolved at step 6, got %v", got)
+	}
+}
+
+func TestProcessDisappeared(t *testing.T) {
+	steps := run(t, []types.AlertRule{mustRule(t, "process python3 disappeared")},
+		gb10(40, 0, 10), // never seen yet: nothing
+		gb10(40, 90, 10, "/usr/bin/python3"),
+		gb10(40, 0, 10), // gone
+		gb10(40, 0, 10),
+		gb10(40, 90, 10, "python3"), // back
+	)
+
+	if len(steps[0]) != 0 || len(steps[1]) != 0 || len(steps[3]) != 0 {
+		t.Fatalf("unexpected alerts %+v", steps)
+	}
+	if got := steps[2]; len(got) != 1 || got[0].State != StateFiring || got[0].Message() !=
+		"[FIRING] spark-a: process python3 disappeared (rule: process python3 disappeared)" {
+		t.Fatalf("expected process alert, got %+v", got)
+	}
+	if got := states(steps[4]); len(got) != 1 || got[0] != StateResolved {
+		t.Fatalf("expected resolved, got %v", got)
+	}
+}
+
+func TestDuplicateRuleNames(t *testing.T) {
+	rule := mustRule(t, "memory > 95%")
+	if _, err := NewEngine("spark-a", []types.AlertRule{rule, rule}); err == nil {
+		t.Fatalf("expected duplicate name error")
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	var payload webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	a := Alert{Name: "hot", Rule: "temperature > 85°C", Profile: "spark-a", Metric: MetricTemperature,
+		Subject: "GPU 0", State: StateFiring, Value: "90°C"}
+	if err := (Webhook{URL: server.URL}).Notify(a); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if payload.Text != "[FIRING] spark-a: GPU 0 temperature is 90°C (rule: temperature > 85°C)" {
+		t.Fatalf("unexpected text %q", payload.Text)
+	}
+	if payload.Alert.Name != "hot" {
+		t.Fatalf("unexpected alert %+v", payload.Alert)
+	}
+}
+
+func TestParseHostOutput(t *testing.T) {
+	output := "vllm-server nvcr.io/nvidia/vllm:25.09-py3\nopen-webui ghcr.io/open-webui/open-webui:main\n" +
+		markerMemInfo + "\nMemTotal:       128544140 kB\n"
+	containers, total := parseHostOutput(output)
+	if len(containers) != 2 || containers[0] != "vllm-server nvcr.io/nvidia/vllm:25.09-py3" {
+		t.Fatalf("unexpected containers %v", containers)
+	}
+	if total == nil || *total != 128544140*1024 {
+		t.Fatalf("unexpected total %v", total)
+	}
+}
@@ -0,0 +1,112 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Notifier delivers alerts
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// Stdout prints alerts as timestamped lines
+type Stdout struct {
+	W io.Writer
+}
+
+// Notify prints the alert message
+func (s Stdout) Notify(a Alert) error {
+	_, err := fmt.Fprintf(s.W, "%s %s\n", a.Time.Format(time.RFC3339), a.Message())
+	return err
+}
+
+// Webhook posts alerts as Slack-compatible JSON: the message in "text",
+// with the structured alert alongside for other receivers
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the body posted by Webhook
+type webhookPayload struct {
+	Text  string `json:"text"`
+	Alert Alert  `json:"alert"`
+}
+
+// Notify posts the alert and fails on a non-2xx response
+func (w Webhook) Notify(a Alert) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false) // Keep > and < readable in rule text
+	if err := enc.Encode(webhookPayload{Text: a.Message(), Alert: a}); err != nil {
+		return err
+	}
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", &body)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Command runs a local shell command for each alert with the alert in
+// DGX_ALERT_* environment variables, e.g. notify-send "$DGX_ALERT_MESSAGE"
+type Command struct {
+	Command string
+}
+
+// Notify runs the command and waits for it to finish
+func (c Command) Notify(a Alert) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", c.Command)
+	}
+	cmd.Env = append(os.Environ(),
+		"DGX_ALERT_NAME="+a.Name,
+		"DGX_ALERT_RULE="+a.Rule,
+		"DGX_ALERT_STATE="+a.State,
+		"DGX_ALERT_SUBJECT="+a.Subject,
+		"DGX_ALERT_VALUE="+a.Value,
+		"DGX_ALERT_MESSAGE="+a.Message(),
+		"DGX_PROFILE="+a.Profile,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("alert command %q: %w", c.Command, err)
+	}
+	return nil
+}
+
+// Notifiers builds the configured notifiers, always starting with stdout
+func Notifiers(configured []types.AlertNotifier, stdout io.Writer) []Notifier {
+	notifiers := []Notifier{Stdout{W: stdout}}
+	for _, n := range configured {
+		if n.Webhook != "" {
+			notifiers = append(notifiers, Webhook{URL: n.Webhook})
+		}
+		if n.Command != "" {
+			notifiers = append(notifiers, Command{Command: n.Command})
+		}
+	}
+	return notifiers
+}
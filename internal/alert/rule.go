@@ -0,0 +1,208 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Metrics a rule can check
+const (
+	MetricTemperature = "temperature"
+	MetricMemory      = "memory"
+	MetricUtilization = "utilization"
+	MetricPower       = "power"
+	MetricProcess     = "process"
+)
+
+// metricAliases maps accepted spellings to metric names
+var metricAliases = map[string]string{
+	"temperature": MetricTemperature,
+	"temp":        MetricTemperature,
+	"memory":      MetricMemory,
+	"mem":         MetricMemory,
+	"utilization": MetricUtilization,
+	"util":        MetricUtilization,
+	"power":       MetricPower,
+	"process":     MetricProcess,
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// ParseRule parses a rule expression such as
+//
+//	temperature > 85°C for 2m
+//	memory > 95%
+//	utilization == 0 for 30m while vllm
+//	process python3 disappeared
+//	gpu 1 power > 200W
+//
+// The rule is named after the expression unless name is set.
+func ParseRule(name, expr string) (types.AlertRule, error) {
+	fields := strings.Fields(expr)
+	var rule types.AlertRule
+
+	// Optional "gpu N" prefix
+	if len(fields) >= 2 && strings.EqualFold(fields[0], "gpu") {
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return rule, fmt.Errorf("invalid GPU index %q", fields[1])
+		}
+		rule.GPU = &id
+		fields = fields[2:]
+	}
+
+	// Optional "for <duration>" and "while <container>" suffixes, in either order
+suffixes:
+	for len(fields) >= 2 {
+		switch strings.ToLower(fields[len(fields)-2]) {
+		case "for":
+			rule.For = fields[len(fields)-1]
+		case "while":
+			rule.WhileContainer = fields[len(fields)-1]
+		default:
+			break suffixes
+		}
+		fields = fields[:len(fields)-2]
+	}
+
+	if len(fields) == 0 {
+		return rule, fmt.Errorf("empty rule")
+	}
+	metric, ok := metricAliases[strings.ToLower(fields[0])]
+	if !ok {
+		return rule, fmt.Errorf("unknown metric %q (use temperature, memory, utilization, power or process)", fields[0])
+	}
+	rule.Metric = metric
+
+	if metric == MetricProcess {
+		if len(fields) != 3 || !strings.EqualFold(fields[2], "disappeared") {
+			return rule, fmt.Errorf("process rules look like: process <name> disappeared")
+		}
+		rule.Process = fields[1]
+	} else {
+		if len(fields) != 3 {
+			return rule, fmt.Errorf("expected <metric> <op> <value>, got %q", strings.Join(fields, " "))
+		}
+		rule.Op = fields[1]
+		value, unit, err := parseThreshold(metric, fields[2])
+		if err != nil {
+			return rule, err
+		}
+		rule.Threshold = value
+		rule.Unit = unit
+	}
+
+	rule.Name = name
+	if rule.Name == "" {
+		rule.Name = strings.Join(strings.Fields(expr), " ")
+	}
+	return rule, Validate(rule)
+}
+
+// parseThreshold splits a value such as 85°C, 95% or 110GiB into a number
+// and, for memory, its unit
+func parseThreshold(metric, s string) (float64, string, error) {
+	unit := ""
+	switch {
+	case strings.HasSuffix(s, "%"):
+		unit = "%"
+	case strings.HasSuffix(strings.ToLower(s), "gib"), strings.HasSuffix(strings.ToLower(s), "gb"):
+		unit = "GiB"
+	}
+	number := strings.TrimRight(s, "%°CcWwGgIiBb")
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid threshold %q", s)
+	}
+
+	if metric == MetricMemory {
+		if unit == "" {
+			return 0, "", fmt.Errorf("memory thresholds need a unit: %% or GiB (e.g. 95%% or 110GiB)")
+		}
+		return value, unit, nil
+	}
+	if unit == "GiB" {
+		return 0, "", fmt.Errorf("GiB thresholds only apply to memory")
+	}
+	return value, "", nil
+}
+
+// Validate checks a rule loaded from config
+func Validate(rule types.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("alert rule has no name")
+	}
+	if _, ok := metricAliases[rule.Metric]; !ok || rule.Metric != metricAliases[rule.Metric] {
+		return fmt.Errorf("rule %s: unknown metric %q", rule.Name, rule.Metric)
+	}
+	if rule.Metric == MetricProcess {
+		if rule.Process == "" {
+			return fmt.Errorf("rule %s: process rules need a process name", rule.Name)
+		}
+	} else if _, ok := operators[rule.Op]; !ok {
+		return fmt.Errorf("rule %s: unknown operator %q (use >, >=, <, <=, == or !=)", rule.Name, rule.Op)
+	}
+	if rule.Metric == MetricMemory && rule.Unit != "%" && rule.Unit != "GiB" {
+		return fmt.Errorf("rule %s: memory rules need unit %% or GiB", rule.Name)
+	}
+	if _, err := holdDuration(rule); err != nil {
+		return fmt.Errorf("rule %s: %w", rule.Name, err)
+	}
+	return nil
+}
+
+// holdDuration returns how long a rule's condition must hold
+func holdDuration(rule types.AlertRule) (time.Duration, error) {
+	if rule.For == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(rule.For)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", rule.For)
+	}
+	return d, nil
+}
+
+// Describe formats a rule as the expression ParseRule accepts
+func Describe(rule types.AlertRule) string {
+	var parts []string
+	if rule.GPU != nil {
+		parts = append(parts, fmt.Sprintf("gpu %d", *rule.GPU))
+	}
+	if rule.Metric == MetricProcess {
+		parts = append(parts, "process", rule.Process, "disappeared")
+	} else {
+		parts = append(parts, rule.Metric, rule.Op, formatThreshold(rule))
+	}
+	if rule.For != "" {
+		parts = append(parts, "for", rule.For)
+	}
+	if rule.WhileContainer != "" {
+		parts = append(parts, "while", rule.WhileContainer)
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatThreshold(rule types.AlertRule) string {
+	value := strconv.FormatFloat(rule.Threshold, 'f', -1, 64)
+	switch rule.Metric {
+	case MetricTemperature:
+		return value + "°C"
+	case MetricPower:
+		return value + "W"
+	case MetricUtilization:
+		return value + "%"
+	}
+	return value + rule.Unit
+}
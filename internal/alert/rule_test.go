@@ -0,0 +1,47 @@
+package alert
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"temperature > 85°C for 2m", "temperature > 85°C for 2m"},
+		{"temp > 85 for 2m", "temperature > 85°C for 2m"},
+		{"memory > 95%", "memory > 95%"},
+		{"mem >= 110GiB", "memory >= 110GiB"},
+		{"utilization == 0 for 30m while vllm", "utilization == 0% for 30m while vllm"},
+		{"util == 0 while vllm for 30m", "utilization == 0% for 30m while vllm"},
+		{"process python3 disappeared", "process python3 disappeared"},
+		{"gpu 1 power > 200W", "gpu 1 power > 200W"},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseRule("", tt.expr)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", tt.expr, err)
+		}
+		if got := Describe(rule); got != tt.want {
+			t.Fatalf("ParseRule(%q) described as %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"fan > 50",
+		"temperature 85",
+		"temperature ~ 85",
+		"memory > 95",
+		"power > 10GiB",
+		"temperature > 85 for soon",
+		"process python3",
+		"gpu x temperature > 85",
+	} {
+		if _, err := ParseRule("", expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package alert
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// markerMemInfo separates docker ps output from /proc/meminfo in hostCommand
+const markerMemInfo = "@@meminfo"
+
+// hostCommand lists running containers and the host memory total in one round trip
+const hostCommand = `docker ps --format '{{.Names}} {{.Image}}' 2>/dev/null; echo ` + markerMemInfo + `; grep MemTotal /proc/meminfo`
+
+// MonitorSampler observes the DGX over SSH with gpu.Monitor. Container and
+// host memory data are only fetched when a rule needs them.
+type MonitorSampler struct {
+	client  *ssh.Client
+	monitor *gpu.Monitor
+	host    bool
+}
+
+// NewMonitorSampler creates a sampler for the given rules
+func NewMonitorSampler(client *ssh.Client, rules []types.AlertRule) *MonitorSampler {
+	s := &MonitorSampler{client: client, monitor: gpu.NewMonitor(client)}
+	for _, r := range rules {
+		if r.WhileContainer != "" || (r.Metric == MetricMemory && r.Unit == "%") {
+			s.host = true
+		}
+	}
+	return s
+}
+
+// Sample reads GPU status and, if needed, containers and host memory
+func (s *MonitorSampler) Sample() (Observation, error) {
+	obs := Observation{Time: time.Now()}
+	gpus, err := s.monitor.GetStatus()
+	if err != nil {
+		return obs, err
+	}
+	obs.GPUs = gpus
+
+	if s.host {
+		output, err := s.client.Execute(hostCommand)
+		if err != nil {
+			return obs, err
+		}
+		obs.Containers, obs.HostMemoryTotalBytes = parseHostOutput(output)
+	}
+	return obs, nil
+}
+
+// parseHostOutput splits hostCommand output into container lines and the
+// MemTotal value in bytes
+func parseHostOutput(output string) ([]string, *uint64) {
+	var containers []string
+	var memTotal *uint64
+
+	inMemInfo := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == markerMemInfo:
+			inMemInfo = true
+		case line == "":
+		case !inMemInfo:
+			containers = append(containers, line)
+		case strings.HasPrefix(line, "MemTotal:"):
+			// MemTotal:       128544140 kB
+			fields := strings.Fields(line)
+			if len(fields) >= 2 {
+				if kb, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+					bytes := kb * 1024
+					memTotal = &bytes
+				}
+			}
+		}
+	}
+	return containers, memTotal
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/weatherman/dgx-manager/pkg/types"
 	"gopkg.in/yaml.v3"
@@ -12,29 +14,45 @@ import (
 const (
 	DefaultConfigDir  = ".config/dgx"
 	DefaultConfigFile = "config.yaml"
+	DefaultProfile    = "default"
+
+	// ProfileEnvVar selects a profile for a single invocation without
+	// changing the current profile stored in the config file
+	ProfileEnvVar = "DGX_PROFILE"
 )
 
 // Manager handles configuration persistence
 type Manager struct {
 	configPath string
-	config     *types.Config
+	file       *types.ConfigFile
+	active     string
 }
 
-// NewManager creates a new configuration manager
-func NewManager() (*Manager, error) {
+// Dir returns the local dgx state directory (~/.config/dgx)
+func Dir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
+	return filepath.Join(home, DefaultConfigDir), nil
+}
 
-	configDir := filepath.Join(home, DefaultConfigDir)
-	configPath := filepath.Join(configDir, DefaultConfigFile)
+// NewManager creates a new configuration manager
+func NewManager() (*Manager, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	return newManager(filepath.Join(configDir, DefaultConfigFile))
+}
+
+func newManager(configPath string) (*Manager, error) {
 	m := &Manager{
 		configPath: configPath,
 	}
@@ -42,7 +60,10 @@ func NewManager() (*Manager, error) {
 	// Load existing config or create default
 	if err := m.Load(); err != nil {
 		if os.IsNotExist(err) {
-			m.config = m.defaultConfig()
+			m.file = &types.ConfigFile{Profiles: map[string]*types.Config{}}
+			if _, err := m.ImportNVSyncProfiles(); err != nil {
+				m.file.Profiles = map[string]*types.Config{}
+			}
 			if err := m.Save(); err != nil {
 				return nil, fmt.Errorf("failed to save default config: %w", err)
 			}
@@ -61,18 +82,68 @@ func (m *Manager) Load() error {
 		return err
 	}
 
-	var cfg types.Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var file types.ConfigFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	m.config = &cfg
+	migrateLegacyConfig(&file)
+	m.file = &file
+	if _, ok := m.file.Profiles[m.active]; !ok {
+		m.active = m.file.CurrentProfile
+	}
 	return nil
 }
 
+// migrateLegacyConfig moves the pre-profile single-host layout into a
+// "default" profile and fills in profile names
+func migrateLegacyConfig(file *types.ConfigFile) {
+	if file.Profiles == nil {
+		file.Profiles = map[string]*types.Config{}
+	}
+
+	if file.Host != "" || file.User != "" || len(file.Tunnels) > 0 {
+		if _, exists := file.Profiles[DefaultProfile]; !exists {
+			file.Profiles[DefaultProfile] = &types.Config{
+				Host:         file.Host,
+				Port:         file.Port,
+				User:         file.User,
+				IdentityFile: file.IdentityFile,
+				Tunnels:      file.Tunnels,
+			}
+			if file.CurrentProfile == "" {
+				file.CurrentProfile = DefaultProfile
+			}
+		}
+		file.Host = ""
+		file.Port = 0
+		file.User = ""
+		file.IdentityFile = ""
+		file.Tunnels = nil
+	}
+
+	for name, cfg := range file.Profiles {
+		if cfg == nil {
+			cfg = &types.Config{}
+			file.Profiles[name] = cfg
+		}
+		cfg.Name = name
+		if cfg.Port == 0 {
+			cfg.Port = 22
+		}
+	}
+
+	if _, ok := file.Profiles[file.CurrentProfile]; !ok {
+		file.CurrentProfile = ""
+		if names := sortedProfileNames(file.Profiles); len(names) > 0 {
+			file.CurrentProfile = names[0]
+		}
+	}
+}
+
 // Save writes the configuration to disk
 func (m *Manager) Save() error {
-	data, err := yaml.Marshal(m.config)
+	data, err := yaml.Marshal(m.file)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -84,44 +155,57 @@ func (m *Manager) Save() error {
 	return nil
 }
 
-// Get returns the current configuration
+// Get returns the configuration of the active profile. When no profile
+// exists yet it returns an unsaved default that Set will persist.
 func (m *Manager) Get() *types.Config {
-	return m.config
+	if cfg, ok := m.file.Profiles[m.active]; ok {
+		return cfg
+	}
+	return m.defaultConfig(m.activeName())
 }
 
-// Set updates the configuration
+// Set updates the configuration of the active profile
 func (m *Manager) Set(cfg *types.Config) error {
-	m.config = cfg
+	name := m.activeName()
+	cfg.Name = name
+	m.file.Profiles[name] = cfg
+	if m.file.CurrentProfile == "" {
+		m.file.CurrentProfile = name
+	}
+	m.active = name
 	return m.Save()
 }
 
 // Update updates specific fields and saves
 func (m *Manager) Update(updateFn func(*types.Config)) error {
-	updateFn(m.config)
-	return m.Save()
+	cfg := m.Get()
+	updateFn(cfg)
+	return m.Set(cfg)
 }
 
 // AddTunnel adds a tunnel to the configuration
 func (m *Manager) AddTunnel(tunnel types.Tunnel) error {
-	m.config.Tunnels = append(m.config.Tunnels, tunnel)
-	return m.Save()
+	return m.Update(func(cfg *types.Config) {
+		cfg.Tunnels = append(cfg.Tunnels, tunnel)
+	})
 }
 
 // RemoveTunnel removes a tunnel from the configuration by ID
 func (m *Manager) RemoveTunnel(id string) error {
-	tunnels := make([]types.Tunnel, 0)
-	for _, t := range m.config.Tunnels {
-		if t.ID != id {
-			tunnels = append(tunnels, t)
+	return m.Update(func(cfg *types.Config) {
+		tunnels := make([]types.Tunnel, 0)
+		for _, t := range cfg.Tunnels {
+			if t.ID != id {
+				tunnels = append(tunnels, t)
+			}
 		}
-	}
-	m.config.Tunnels = tunnels
-	return m.Save()
+		cfg.Tunnels = tunnels
+	})
 }
 
 // GetTunnel retrieves a tunnel by ID
 func (m *Manager) GetTunnel(id string) (*types.Tunnel, error) {
-	for _, t := range m.config.Tunnels {
+	for _, t := range m.Get().Tunnels {
 		if t.ID == id {
 			return &t, nil
 		}
@@ -129,26 +213,240 @@ func (m *Manager) GetTunnel(id string) (*types.Tunnel, error) {
 	return nil, fmt.Errorf("tunnel not found: %s", id)
 }
 
+// AddAlertRule adds a GPU alert rule to the active profile
+func (m *Manager) AddAlertRule(rule types.AlertRule) error {
+	for _, r := range m.Get().Alerts.Rules {
+		if r.Name == rule.Name {
+			return fmt.Errorf("alert rule already exists: %s", rule.Name)
+		}
+	}
+	return m.Update(func(cfg *types.Config) {
+		cfg.Alerts.Rules = append(cfg.Alerts.Rules, rule)
+	})
+}
+
+// RemoveAlertRule removes a GPU alert rule from the active profile by name
+func (m *Manager) RemoveAlertRule(name string) error {
+	rules := make([]types.AlertRule, 0)
+	for _, r := range m.Get().Alerts.Rules {
+		if r.Name != name {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == len(m.Get().Alerts.Rules) {
+		return fmt.Errorf("alert rule not found: %s", name)
+	}
+	return m.Update(func(cfg *types.Config) {
+		cfg.Alerts.Rules = rules
+	})
+}
+
+// SetAlertNotifiers replaces where the active profile's alerts are sent
+func (m *Manager) SetAlertNotifiers(notifiers []types.AlertNotifier) error {
+	return m.Update(func(cfg *types.Config) {
+		cfg.Alerts.Notify = notifiers
+	})
+}
+
+// Select switches the active profile for this process only
+func (m *Manager) Select(name string) error {
+	if _, ok := m.file.Profiles[name]; !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	m.active = name
+	return nil
+}
+
+// Use makes a profile the current one and persists the choice
+func (m *Manager) Use(name string) error {
+	if err := m.Select(name); err != nil {
+		return err
+	}
+	m.file.CurrentProfile = name
+	return m.Save()
+}
+
+// ActiveProfile returns the name of the profile commands run against
+func (m *Manager) ActiveProfile() string {
+	return m.active
+}
+
+// CurrentProfile returns the profile name stored in the config file
+func (m *Manager) CurrentProfile() string {
+	return m.file.CurrentProfile
+}
+
+// Profiles returns all profile names in sorted order
+func (m *Manager) Profiles() []string {
+	return sortedProfileNames(m.file.Profiles)
+}
+
+// GetProfile retrieves a profile by name
+func (m *Manager) GetProfile(name string) (*types.Config, error) {
+	cfg, ok := m.file.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile not found: %s", name)
+	}
+	return cfg, nil
+}
+
+// AddProfile stores a new named profile. The first profile added becomes current.
+func (m *Manager) AddProfile(name string, cfg *types.Config) error {
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if _, exists := m.file.Profiles[name]; exists {
+		return fmt.Errorf("profile already exists: %s", name)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 22
+	}
+	cfg.Name = name
+	m.file.Profiles[name] = cfg
+	if m.file.CurrentProfile == "" {
+		m.file.CurrentProfile = name
+		if m.active == "" {
+			m.active = name
+		}
+	}
+	return m.Save()
+}
+
+// RemoveProfile deletes a profile. Removing the current profile moves the
+// pointer to the first remaining profile.
+func (m *Manager) RemoveProfile(name string) error {
+	if _, ok := m.file.Profiles[name]; !ok {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	delete(m.file.Profiles, name)
+
+	if m.file.CurrentProfile == name {
+		m.file.CurrentProfile = ""
+		if names := m.Profiles(); len(names) > 0 {
+			m.file.CurrentProfile = names[0]
+		}
+	}
+	if m.active == name {
+		m.active = m.file.CurrentProfile
+	}
+	return m.Save()
+}
+
+// ImportNVSyncProfiles creates a profile for every NVIDIA Sync host that
+// is not already configured, saves, and returns the names of the new profiles
+func (m *Manager) ImportNVSyncProfiles() ([]string, error) {
+	profiles, err := detectNVSyncProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, profile := range profiles {
+		if m.hasProfileFor(profile.Host, profile.User, profile.Port) {
+			continue
+		}
+
+		name := m.uniqueProfileName(nvSyncProfileName(profile))
+		cfg := &types.Config{
+			Name:         name,
+			Host:         profile.Host,
+			Port:         profile.Port,
+			User:         profile.User,
+			IdentityFile: profile.IdentityFile,
+			Tunnels:      []types.Tunnel{},
+		}
+		m.file.Profiles[name] = cfg
+		added = append(added, name)
+	}
+
+	if m.file.CurrentProfile == "" && len(added) > 0 {
+		m.file.CurrentProfile = added[0]
+	}
+	if _, ok := m.file.Profiles[m.active]; !ok {
+		m.active = m.file.CurrentProfile
+	}
+
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, m.Save()
+}
+
+func (m *Manager) hasProfileFor(host, user string, port int) bool {
+	for _, cfg := range m.file.Profiles {
+		if cfg.Host == host && cfg.User == user && cfg.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) uniqueProfileName(base string) string {
+	name := base
+	for i := 2; ; i++ {
+		if _, exists := m.file.Profiles[name]; !exists {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func nvSyncProfileName(profile *NVSyncProfile) string {
+	name := profile.Alias
+	if name == "" {
+		name = profile.Host
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '/' || r == '\\' {
+			return '-'
+		}
+		return r
+	}, name)
+	if name == "" {
+		return DefaultProfile
+	}
+	return name
+}
+
+// ValidateProfileName checks that a profile name is usable on the command line
+func ValidateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if strings.ContainsAny(name, " \t/\\") {
+		return fmt.Errorf("invalid profile name %q: must not contain whitespace or slashes", name)
+	}
+	return nil
+}
+
+func sortedProfileNames(profiles map[string]*types.Config) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Manager) activeName() string {
+	if m.active != "" {
+		return m.active
+	}
+	return DefaultProfile
+}
+
 // defaultConfig returns a default configuration
-func (m *Manager) defaultConfig() *types.Config {
+func (m *Manager) defaultConfig(name string) *types.Config {
 	home, _ := os.UserHomeDir()
 
-	cfg := &types.Config{
+	return &types.Config{
+		Name:         name,
 		Host:         "", // User must configure
 		Port:         22,
 		User:         "",
 		IdentityFile: filepath.Join(home, ".ssh", "id_ed25519"),
 		Tunnels:      []types.Tunnel{},
 	}
-
-	if profile, err := DetectNVSyncProfile(); err == nil && profile != nil {
-		cfg.Host = profile.Host
-		cfg.Port = profile.Port
-		cfg.User = profile.User
-		cfg.IdentityFile = profile.IdentityFile
-	}
-
-	return cfg
 }
 
 // GetConfigPath returns the path to the config file
@@ -158,5 +456,6 @@ func (m *Manager) GetConfigPath() string {
 
 // IsConfigured checks if the essential configuration is set
 func (m *Manager) IsConfigured() bool {
-	return m.config.Host != "" && m.config.User != ""
+	cfg := m.Get()
+	return cfg.Host != "" && cfg.User != ""
 }
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func TestLoadMigratesLegacyConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	legacy := `host: 192.168.0.10
+port: 2222
+user: alice
+identity_file: /tmp/id_ed25519
+tunnels:
+  - id: tunnel-1
+    local_port: 8888
+    remote_port: 8888
+    remote_host: localhost
+`
+	if err := os.WriteFile(configPath, []byte(legacy), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	m, err := newManager(configPath)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+
+	cfg := m.Get()
+	if cfg.Name != DefaultProfile {
+		t.Fatalf("unexpected profile %q", cfg.Name)
+	}
+	if cfg.Host != "192.168.0.10" || cfg.Port != 2222 || cfg.User != "alice" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+	if len(cfg.Tunnels) != 1 {
+		t.Fatalf("expected 1 tunnel, got %d", len(cfg.Tunnels))
+	}
+	if !m.IsConfigured() {
+		t.Fatalf("expected migrated profile to be configured")
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("{}\n"), 0600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	m, err := newManager(configPath)
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	if m.IsConfigured() {
+		t.Fatalf("empty config should not be configured")
+	}
+
+	if err := m.AddProfile("spark-a", &types.Config{Host: "10.0.0.1", User: "alice"}); err != nil {
+		t.Fatalf("add spark-a: %v", err)
+	}
+	if err := m.AddProfile("spark-b", &types.Config{Host: "10.0.0.2", User: "bob"}); err != nil {
+		t.Fatalf("add spark-b: %v", err)
+	}
+	if err := m.AddProfile("spark-a", &types.Config{Host: "10.0.0.3", User: "carol"}); err == nil {
+		t.Fatalf("expected duplicate profile error")
+	}
+
+	if got := m.Get().Name; got != "spark-a" {
+		t.Fatalf("first profile should be current, got %q", got)
+	}
+
+	if err := m.Select("spark-b"); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if got := m.Get().Host; got != "10.0.0.2" {
+		t.Fatalf("unexpected host after select %q", got)
+	}
+	if got := m.CurrentProfile(); got != "spark-a" {
+		t.Fatalf("select must not change current profile, got %q", got)
+	}
+	if err := m.Select("missing"); err == nil {
+		t.Fatalf("expected error selecting unknown profile")
+	}
+
+	if err := m.Use("spark-b"); err != nil {
+		t.Fatalf("use: %v", err)
+	}
+	reloaded, err := newManager(configPath)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.Get().Name; got != "spark-b" {
+		t.Fatalf("current profile not persisted, got %q", got)
+	}
+
+	if err := reloaded.RemoveProfile("spark-b"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := reloaded.Get().Name; got != "spark-a" {
+		t.Fatalf("expected fallback to spark-a, got %q", got)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 // NVSyncProfile captures connection details exported by the NVIDIA Sync app.
 type NVSyncProfile struct {
+	Alias        string
 	Host         string
 	User         string
 	Port         int
@@ -156,7 +157,7 @@ func parseNVSyncProfileReader(r io.Reader) ([]*NVSyncProfile, error) {
 				current = nil
 				continue
 			}
-			current = &NVSyncProfile{Host: alias[0], Port: 22}
+			current = &NVSyncProfile{Alias: alias[0], Host: alias[0], Port: 22}
 		case "hostname":
 			if current != nil {
 				current.Host = value
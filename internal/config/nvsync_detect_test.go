@@ -45,4 +45,38 @@ Host 192.168.0.10
 			t.Fatalf("unexpected identity %q", profile.IdentityFile)
 		}
 	})
+
+	t.Run("parses multiple hosts", func(t *testing.T) {
+		dir := t.TempDir()
+		keyPath := filepath.Join(dir, "nvsync.key")
+		if err := os.WriteFile(keyPath, []byte("test"), 0600); err != nil {
+			t.Fatalf("write key: %v", err)
+		}
+
+		config := fmt.Sprintf(`
+Host spark-a
+    Hostname 192.168.0.10
+    User alice
+    IdentityFile "%[1]s"
+
+Host spark-b
+    Hostname 192.168.0.11
+    User alice
+    IdentityFile "%[1]s"
+`, keyPath)
+
+		profiles, err := parseNVSyncProfileReader(strings.NewReader(config))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if len(profiles) != 2 {
+			t.Fatalf("expected 2 profiles, got %d", len(profiles))
+		}
+		if profiles[0].Alias != "spark-a" || profiles[1].Alias != "spark-b" {
+			t.Fatalf("unexpected aliases %q, %q", profiles[0].Alias, profiles[1].Alias)
+		}
+		if profiles[1].Host != "192.168.0.11" {
+			t.Fatalf("unexpected host %q", profiles[1].Host)
+		}
+	})
 }
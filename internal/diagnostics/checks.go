@@ -0,0 +1,323 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/weatherman/dgx-manager/internal/version"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// DefaultImage is the image started to check that containers see the GPU.
+// nvidia-container-toolkit mounts nvidia-smi into it.
+const DefaultImage = "ubuntu:24.04"
+
+// minDriverMajor is the oldest driver branch that supports the DGX Spark
+const minDriverMajor = 580
+
+// containerTimeout bounds the docker run check, including the image pull
+const containerTimeout = 300
+
+// Options select what the catalogue checks
+type Options struct {
+	Image         string // Image for the container check; DefaultImage if empty
+	SkipContainer bool   // Skip starting a container
+}
+
+// Remediation hints shared by several checks
+const (
+	hintInstallToolkit  = "install the toolkit and restart Docker: sudo apt install -y nvidia-container-toolkit && sudo systemctl restart docker"
+	hintConfigureDocker = "register the runtime and restart Docker: sudo nvidia-ctk runtime configure --runtime=docker && sudo systemctl restart docker"
+	hintFixDriver       = "fix the driver and kernel-module checks first"
+)
+
+// Catalogue returns the diagnostics checks in report order
+func Catalogue(opts Options) []Check {
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	checks := []Check{
+		{
+			Name:    "kernel-module",
+			Command: "lsmod | awk '$1 ~ /^nvidia/ {print $1}'",
+			Parse:   parseKernelModules,
+		},
+		{
+			Name:    "driver",
+			Command: "nvidia-smi --query-gpu=driver_version --format=csv,noheader",
+			Parse:   parseDriver,
+		},
+		{
+			Name:    "cuda",
+			Command: "nvidia-smi | grep -o 'CUDA Version: [0-9.]*'; (nvcc --version || /usr/local/cuda/bin/nvcc --version) 2>/dev/null | grep -o 'release [0-9.]*'",
+			Parse:   parseCUDA,
+		},
+		{
+			Name:    "container-toolkit",
+			Command: "dpkg-query -W -f='${Version}\\n' nvidia-container-toolkit 2>/dev/null || nvidia-ctk --version",
+			Parse:   parseContainerToolkit,
+		},
+		{
+			Name:    "docker-runtime",
+			Command: "cat /etc/docker/daemon.json",
+			Parse:   parseDaemonJSON,
+		},
+	}
+	if !opts.SkipContainer {
+		checks = append(checks, Check{
+			Name:    "docker-gpu",
+			Command: fmt.Sprintf("timeout %d docker run --rm --gpus all %s nvidia-smi -L", containerTimeout, shellQuote(image)),
+			Parse:   parseContainerRun,
+		})
+	}
+	checks = append(checks, Check{
+		Name: "xid",
+		// dmesg may be restricted to root; fall back to sudo and the journal
+		Command: `if log=$(dmesg 2>/dev/null || sudo -n dmesg 2>/dev/null || journalctl -k -b --no-pager 2>/dev/null); then printf '%s\n' "$log" | grep 'NVRM: Xid' | tail -n 50; true; else echo 'kernel log unreadable'; false; fi`,
+		Parse:   parseXid,
+	})
+	return checks
+}
+
+func parseKernelModules(output string, exit int) types.DiagnosticResult {
+	modules := strings.Fields(output)
+	loaded := make(map[string]bool)
+	for _, m := range modules {
+		loaded[m] = true
+	}
+
+	switch {
+	case !loaded["nvidia"]:
+		return fail("nvidia module not loaded",
+			"load it with sudo modprobe nvidia; if that fails, check Secure Boot (mokutil --sb-state) and dmesg")
+	case !loaded["nvidia_uvm"]:
+		return warn("nvidia_uvm not loaded", "CUDA needs it: sudo modprobe nvidia-uvm")
+	}
+	return pass(strings.Join(modules, ", "))
+}
+
+func parseDriver(output string, exit int) types.DiagnosticResult {
+	line := version.FirstLine(output)
+	if exit != 0 {
+		switch {
+		case strings.Contains(output, "command not found"):
+			return fail("nvidia-smi not found", "install the NVIDIA driver for DGX OS: sudo apt update && sudo apt install -y nvidia-driver-580-open, then reboot")
+		case strings.Contains(output, "version mismatch"):
+			return fail(line, "the loaded kernel module is older than the driver libraries, usually after an upgrade; reboot the DGX")
+		}
+		return fail(line, "check the kernel-module check and dmesg for NVRM errors")
+	}
+
+	major, err := strconv.Atoi(strings.SplitN(line, ".", 2)[0])
+	if err != nil {
+		return warn(fmt.Sprintf("unrecognised driver version %q", line), "")
+	}
+	if major < minDriverMajor {
+		return warn(fmt.Sprintf("%s (older than %d)", line, minDriverMajor),
+			"upgrade the driver: sudo apt update && sudo apt full-upgrade, then reboot")
+	}
+	return pass(line)
+}
+
+func parseCUDA(output string, exit int) types.DiagnosticResult {
+	var driverCUDA, toolkit string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "CUDA Version:"):
+			driverCUDA = strings.TrimSpace(strings.TrimPrefix(line, "CUDA Version:"))
+		case strings.HasPrefix(line, "release "):
+			toolkit = strings.TrimPrefix(line, "release ")
+		}
+	}
+
+	if driverCUDA == "" {
+		return fail("driver reports no CUDA version", hintFixDriver)
+	}
+	if toolkit == "" {
+		return pass(fmt.Sprintf("driver supports CUDA %s; no host toolkit (containers bring their own)", driverCUDA))
+	}
+	if version.Compare(toolkit, driverCUDA) > 0 {
+		return warn(fmt.Sprintf("host toolkit %s is newer than the driver's CUDA %s", toolkit, driverCUDA),
+			fmt.Sprintf("upgrade the driver or install a CUDA toolkit no newer than %s", driverCUDA))
+	}
+	return pass(fmt.Sprintf("driver supports CUDA %s; host toolkit %s", driverCUDA, toolkit))
+}
+
+func parseContainerToolkit(output string, exit int) types.DiagnosticResult {
+	if exit != 0 || strings.TrimSpace(output) == "" {
+		return fail("nvidia-container-toolkit is not installed", hintInstallToolkit)
+	}
+	// 1.17.8-1 from dpkg, or "NVIDIA Container Toolkit CLI version 1.17.8"
+	v := strings.TrimPrefix(version.FirstLine(output), "NVIDIA Container Toolkit CLI version ")
+	return pass(v)
+}
+
+// daemonConfig is the part of Docker's daemon.json the runtime check reads
+type daemonConfig struct {
+	DefaultRuntime string `json:"default-runtime"`
+	Runtimes       map[string]struct {
+		Path string `json:"path"`
+	} `json:"runtimes"`
+}
+
+func parseDaemonJSON(output string, exit int) types.DiagnosticResult {
+	if exit != 0 {
+		return warn("no /etc/docker/daemon.json; --gpus works but --runtime=nvidia and Compose runtimes do not", hintConfigureDocker)
+	}
+
+	var cfg daemonConfig
+	if err := json.Unmarshal([]byte(output), &cfg); err != nil {
+		return fail(fmt.Sprintf("daemon.json is not valid JSON: %v", err),
+			"fix the syntax in /etc/docker/daemon.json, then sudo systemctl restart docker; Docker will not start with it")
+	}
+
+	runtime, ok := cfg.Runtimes["nvidia"]
+	if !ok {
+		return warn("no nvidia runtime in daemon.json", hintConfigureDocker)
+	}
+	if !strings.Contains(runtime.Path, "nvidia-container-runtime") {
+		return warn(fmt.Sprintf("nvidia runtime points at %q", runtime.Path), hintConfigureDocker)
+	}
+
+	detail := "nvidia runtime registered"
+	if cfg.DefaultRuntime == "nvidia" {
+		detail += " (default)"
+	}
+	return pass(detail)
+}
+
+func parseContainerRun(output string, exit int) types.DiagnosticResult {
+	if exit == 0 {
+		var gpus []string
+		for _, line := range strings.Split(output, "\n") {
+			// GPU 0: NVIDIA GB10 (UUID: GPU-...)
+			if name, _, _ := strings.Cut(strings.TrimSpace(line), " (UUID"); strings.HasPrefix(name, "GPU ") {
+				gpus = append(gpus, name)
+			}
+		}
+		if len(gpus) > 0 {
+			return pass(strings.Join(gpus, ", "))
+		}
+		return fail("container started but saw no GPUs", hintInstallToolkit)
+	}
+
+	line := lastLine(output)
+	switch {
+	case exit == 124:
+		return warn(fmt.Sprintf("timed out after %ds pulling or starting the image", containerTimeout),
+			"pull the image first (docker pull) or use --image with an image already on the DGX")
+	case strings.Contains(output, "docker: command not found"):
+		return fail("docker not found", "install Docker: see https://docs.docker.com/engine/install/ubuntu/")
+	case strings.Contains(output, "permission denied") && strings.Contains(output, "docker.sock"):
+		return fail("permission denied on the Docker socket", "add your user to the docker group: sudo usermod -aG docker $USER, then log in again")
+	case strings.Contains(output, "Cannot connect to the Docker daemon"):
+		return fail("Docker daemon not running", "start it: sudo systemctl enable --now docker")
+	case strings.Contains(output, "could not select device driver"):
+		return fail("Docker has no GPU device driver", hintInstallToolkit)
+	case strings.Contains(output, "nvidia-container-cli"):
+		return fail(line, hintFixDriver)
+	}
+	return fail(line, "run docker run --rm --gpus all <image> nvidia-smi on the DGX to see the full error")
+}
+
+// xid describes an NVIDIA Xid error code
+type xid struct {
+	description string
+	severe      bool // Hardware or driver faults rather than application errors
+	hint        string
+}
+
+// Xid hints shared by several codes
+const (
+	hintXidApp   = "usually a bug or out-of-memory in the CUDA application named in the log line; check its logs"
+	hintXidECC   = "reboot the DGX; contact NVIDIA support if the errors repeat"
+	hintXidReset = "reboot the DGX to reset the GPU; upgrade the driver if it repeats"
+)
+
+var xidCodes = map[int]xid{
+	13:  {"graphics engine exception", false, hintXidApp},
+	31:  {"GPU memory page fault", false, hintXidApp},
+	43:  {"GPU stopped processing", false, hintXidApp},
+	45:  {"preemptive cleanup", false, "follows a killed process or another Xid; harmless on its own"},
+	48:  {"double-bit ECC error", true, hintXidECC},
+	62:  {"internal micro-controller halt", true, hintXidReset},
+	63:  {"ECC page retirement or row remapping", false, "reboot when convenient to apply the remapping"},
+	64:  {"ECC page retirement or row remapping failure", true, hintXidECC},
+	74:  {"NVLink error", true, "reboot the DGX; contact NVIDIA support if the errors repeat"},
+	79:  {"GPU has fallen off the bus", true, "check power and cooling, then reboot; contact NVIDIA support if it repeats"},
+	92:  {"high single-bit ECC error rate", false, hintXidECC},
+	94:  {"contained ECC error", false, "the affected application was stopped; restart it"},
+	95:  {"uncontained ECC error", true, hintXidReset},
+	119: {"GSP RPC timeout", true, hintXidReset},
+	120: {"GSP error", true, hintXidReset},
+}
+
+// xidPattern matches "NVRM: Xid (PCI:000f:01:00): 79, pid=..., GPU has fallen off the bus."
+var xidPattern = regexp.MustCompile(`NVRM: Xid \([^)]*\): (\d+),`)
+
+func parseXid(output string, exit int) types.DiagnosticResult {
+	if exit != 0 {
+		return warn("cannot read the kernel log",
+			"let your user read it (sudo usermod -aG adm $USER) or allow passwordless sudo for dmesg")
+	}
+
+	counts := make(map[int]int)
+	for _, m := range xidPattern.FindAllStringSubmatch(output, -1) {
+		code, _ := strconv.Atoi(m[1])
+		counts[code]++
+	}
+	if len(counts) == 0 {
+		return pass("no Xid errors since boot")
+	}
+
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	var parts []string
+	var worst int
+	severe := false
+	for _, code := range codes {
+		info, ok := xidCodes[code]
+		if !ok {
+			info = xid{description: "unknown", hint: "look the code up in NVIDIA's Xid catalogue"}
+		}
+		parts = append(parts, fmt.Sprintf("%d× Xid %d (%s)", counts[code], code, info.description))
+
+		// The hint follows the most severe, then most frequent, code
+		if worst == 0 || (info.severe && !severe) || (info.severe == severe && counts[code] > counts[worst]) {
+			worst = code
+			severe = info.severe
+		}
+	}
+
+	hint := xidCodes[worst].hint
+	if hint == "" {
+		hint = "look the code up in NVIDIA's Xid catalogue"
+	}
+	if severe {
+		return fail(strings.Join(parts, ", "), hint)
+	}
+	return warn(strings.Join(parts, ", "), hint)
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
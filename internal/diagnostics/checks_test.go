@@ -0,0 +1,99 @@
+package diagnostics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string, int) types.DiagnosticResult
+		output  string // Inline output, or a fixture name ending in .txt or .json
+		exit    int
+		status  string
+		detail  string
+		hasHint bool
+	}{
+		{"modules loaded", parseKernelModules, "nvidia_uvm\nnvidia_drm\nnvidia_modeset\nnvidia\n", 0, StatusPass, "nvidia_uvm, nvidia_drm", false},
+		{"uvm missing", parseKernelModules, "nvidia_drm\nnvidia_modeset\nnvidia\n", 0, StatusWarn, "nvidia_uvm not loaded", true},
+		{"no modules", parseKernelModules, "", 1, StatusFail, "nvidia module not loaded", true},
+
+		{"driver ok", parseDriver, "580.95.05\n", 0, StatusPass, "580.95.05", false},
+		{"driver old", parseDriver, "570.172.08\n", 0, StatusWarn, "older than 580", true},
+		{"driver missing", parseDriver, "bash: line 2: nvidia-smi: command not found\n", 127, StatusFail, "nvidia-smi not found", true},
+		{"driver mismatch", parseDriver, "driver-mismatch.txt", 18, StatusFail, "Driver/library version mismatch", true},
+
+		{"cuda driver only", parseCUDA, "CUDA Version: 13.0\n", 1, StatusPass, "no host toolkit", false},
+		{"cuda toolkit ok", parseCUDA, "CUDA Version: 13.0\nrelease 12.8\n", 0, StatusPass, "host toolkit 12.8", false},
+		{"cuda toolkit newer", parseCUDA, "CUDA Version: 12.8\nrelease 13.0\n", 0, StatusWarn, "newer than the driver's CUDA 12.8", true},
+		{"cuda no driver", parseCUDA, "", 1, StatusFail, "no CUDA version", true},
+
+		{"toolkit dpkg", parseContainerToolkit, "1.17.8-1\n", 0, StatusPass, "1.17.8-1", false},
+		{"toolkit ctk", parseContainerToolkit, "NVIDIA Container Toolkit CLI version 1.17.8\ncommit: f202b80a9b9d0db00d9b1d73c0128c8962c55f4d\n", 0, StatusPass, "1.17.8", false},
+		{"toolkit missing", parseContainerToolkit, "bash: line 4: nvidia-ctk: command not found\n", 127, StatusFail, "not installed", true},
+
+		{"daemon configured", parseDaemonJSON, "daemon-configured.json", 0, StatusPass, "nvidia runtime registered (default)", false},
+		{"daemon no runtime", parseDaemonJSON, "daemon-no-runtime.json", 0, StatusWarn, "no nvidia runtime", true},
+		{"daemon invalid", parseDaemonJSON, "daemon-invalid.json", 0, StatusFail, "not valid JSON", true},
+		{"daemon missing", parseDaemonJSON, "cat: /etc/docker/daemon.json: No such file or directory\n", 1, StatusWarn, "no /etc/docker/daemon.json", true},
+
+		{"container sees gpu", parseContainerRun, "docker-run-gb10.txt", 0, StatusPass, "GPU 0: NVIDIA GB10", false},
+		{"container no runtime", parseContainerRun, "docker-run-no-runtime.txt", 125, StatusFail, "no GPU device driver", true},
+		{"container timeout", parseContainerRun, "", 124, StatusWarn, "timed out", true},
+		{"docker socket", parseContainerRun, "docker: permission denied while trying to connect to the Docker daemon socket at unix:///var/run/docker.sock\n", 126, StatusFail, "permission denied", true},
+
+		{"no xid", parseXid, "", 0, StatusPass, "no Xid errors", false},
+		{"xid errors", parseXid, "dmesg-xid.txt", 0, StatusFail, "2× Xid 13 (graphics engine exception), 1× Xid 31 (GPU memory page fault), 1× Xid 79 (GPU has fallen off the bus)", true},
+		{"xid app only", parseXid, "[ 81.0] NVRM: Xid (PCI:000f:01:00): 31, pid=1, name=python3, MMU Fault\n", 0, StatusWarn, "1× Xid 31", true},
+		{"kernel log unreadable", parseXid, "kernel log unreadable\n", 1, StatusWarn, "cannot read the kernel log", true},
+	}
+
+	for _, tt := range tests {
+		output := tt.output
+		if strings.HasSuffix(output, ".txt") || strings.HasSuffix(output, ".json") {
+			data, err := os.ReadFile(filepath.Join("testdata", output))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			output = string(data)
+		}
+		got := tt.parse(output, tt.exit)
+		if got.Status != tt.status || !strings.Contains(got.Detail, tt.detail) {
+			t.Fatalf("%s: expected %s %q, got %s %q", tt.name, tt.status, tt.detail, got.Status, got.Detail)
+		}
+		if (got.Hint != "") != tt.hasHint {
+			t.Fatalf("%s: unexpected hint %q", tt.name, got.Hint)
+		}
+	}
+}
+
+func TestXidHintFollowsSevereCode(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dmesg-xid.txt"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	got := parseXid(string(data), 0)
+	if got.Hint != xidCodes[79].hint {
+		t.Fatalf("expected the Xid 79 hint, got %q", got.Hint)
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	var names []string
+	for _, c := range Catalogue(Options{SkipContainer: true}) {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "kernel-module,driver,cuda,container-toolkit,docker-runtime,xid" {
+		t.Fatalf("unexpected catalogue %v", names)
+	}
+
+	checks := Catalogue(Options{Image: "nvcr.io/nvidia/cuda:13.0.0-base-ubuntu24.04"})
+	if c := checks[5]; c.Name != "docker-gpu" || !strings.Contains(c.Command, "--gpus all 'nvcr.io/nvidia/cuda:13.0.0-base-ubuntu24.04' nvidia-smi -L") {
+		t.Fatalf("unexpected container check %+v", c)
+	}
+}
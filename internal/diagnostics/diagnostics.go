@@ -0,0 +1,112 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Check statuses, from best to worst
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+var severity = map[string]int{StatusPass: 0, StatusWarn: 1, StatusFail: 2}
+
+// Check is one entry in the diagnostics catalogue: a remote command and a
+// parser that turns its output and exit status into a result
+type Check struct {
+	Name    string
+	Command string
+	Parse   func(output string, exit int) types.DiagnosticResult
+}
+
+func pass(detail string) types.DiagnosticResult {
+	return types.DiagnosticResult{Status: StatusPass, Detail: detail}
+}
+
+func warn(detail, hint string) types.DiagnosticResult {
+	return types.DiagnosticResult{Status: StatusWarn, Detail: detail, Hint: hint}
+}
+
+func fail(detail, hint string) types.DiagnosticResult {
+	return types.DiagnosticResult{Status: StatusFail, Detail: detail, Hint: hint}
+}
+
+// Run executes every check in one SSH session and parses the results in
+// catalogue order
+func Run(monitor *gpu.Monitor, checks []Check) ([]types.DiagnosticResult, error) {
+	outputs, err := monitor.RunSections(Sections(checks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run diagnostics: %w", err)
+	}
+	return Results(checks, outputs), nil
+}
+
+// Sections returns the checks as collect sections, so they can share a
+// session with other commands
+func Sections(checks []Check) []gpu.Section {
+	sections := make([]gpu.Section, 0, len(checks))
+	for _, c := range checks {
+		sections = append(sections, gpu.Section{Name: c.Name, Command: c.Command})
+	}
+	return sections
+}
+
+// Results parses collected sections in catalogue order
+func Results(checks []Check, outputs map[string]gpu.SectionResult) []types.DiagnosticResult {
+	results := make([]types.DiagnosticResult, 0, len(checks))
+	for _, c := range checks {
+		r, ok := outputs[c.Name]
+		var result types.DiagnosticResult
+		if ok {
+			result = c.Parse(r.Output, r.Exit)
+		} else {
+			result = fail("no output", "the remote shell stopped before this check; run it again")
+		}
+		result.Name = c.Name
+		results = append(results, result)
+	}
+	return results
+}
+
+// Worst returns the most severe status among results, or pass if there are none
+func Worst(results []types.DiagnosticResult) string {
+	worst := StatusPass
+	for _, r := range results {
+		if severity[r.Status] > severity[worst] {
+			worst = r.Status
+		}
+	}
+	return worst
+}
+
+// Format formats results as a table followed by hints for the checks that
+// did not pass
+func Format(results []types.DiagnosticResult) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Status, r.Detail)
+	}
+	w.Flush()
+
+	first := true
+	for _, r := range results {
+		if r.Hint == "" {
+			continue
+		}
+		if first {
+			sb.WriteString("\nHints:\n")
+			first = false
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", r.Name, r.Hint)
+	}
+	return sb.String()
+}
@@ -0,0 +1,250 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Defaults for 'dgx exporter'
+const (
+	DefaultListen   = ":9835"
+	DefaultCacheTTL = 15 * time.Second
+	DefaultTimeout  = 20 * time.Second
+)
+
+// Content types served on /metrics
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Snapshot is the data collected from one host over SSH
+type Snapshot struct {
+	Time     time.Time
+	Latency  time.Duration // SSH connect time from CheckConnection
+	Duration time.Duration // Time spent collecting
+	GPUs     []types.GPUInfo
+	Err      error // Non-nil when the host could not be reached
+}
+
+// Exporter serves GPU and tunnel metrics for several profiles. Collected
+// host data is cached for CacheTTL so frequent scrapes share SSH sessions.
+type Exporter struct {
+	// CacheTTL is how long a host's collected data is reused
+	CacheTTL time.Duration
+	// Timeout bounds how long a scrape waits for a host
+	Timeout time.Duration
+
+	hosts []*host
+}
+
+// host caches collections for one profile and ensures only one runs at a time
+type host struct {
+	config  *types.Config
+	collect func() Snapshot
+	tunnels func() ([]types.Tunnel, error)
+
+	mu       sync.Mutex
+	last     *Snapshot
+	inflight chan struct{}
+}
+
+// New creates an exporter for the given profiles
+func New(configs []*types.Config) *Exporter {
+	e := &Exporter{CacheTTL: DefaultCacheTTL, Timeout: DefaultTimeout}
+	for _, cfg := range configs {
+		client, _ := ssh.NewClient(cfg)
+		client.SetBatchMode(true)
+		manager := tunnel.NewManager(cfg)
+		cfg := cfg
+		e.hosts = append(e.hosts, &host{
+			config:  cfg,
+			collect: func() Snapshot { return collectHost(client) },
+			tunnels: func() ([]types.Tunnel, error) { return hostTunnels(cfg, manager) },
+		})
+	}
+	return e
+}
+
+// collectHost measures SSH latency and reads GPU status from one host
+func collectHost(client *ssh.Client) Snapshot {
+	start := time.Now()
+	s := Snapshot{Time: start}
+
+	// GetStatus reuses the connection whose handshake gives the latency
+	if err := client.Connect(); err != nil {
+		s.Err = err
+		s.Duration = time.Since(start)
+		return s
+	}
+	s.Latency = time.Since(start)
+
+	gpus, err := gpu.NewMonitor(client).GetStatus()
+	client.Close()
+	if err != nil {
+		s.Err = err
+	}
+	s.GPUs = gpus
+	s.Duration = time.Since(start)
+	return s
+}
+
+// hostTunnels joins saved and live tunnels for a profile. This is local
+// state, so it is read on every scrape rather than cached.
+func hostTunnels(cfg *types.Config, manager *tunnel.Manager) ([]types.Tunnel, error) {
+	live, err := manager.List()
+	var tunnels []types.Tunnel
+	for _, s := range tunnel.Reconcile(cfg.Tunnels, live) {
+		tunnels = append(tunnels, s.Tunnel())
+	}
+	return tunnels, err
+}
+
+// snapshot returns cached data if it is fresh, otherwise collects it,
+// joining a collection already in flight. A scrape gives up after timeout
+// but the collection keeps running and refreshes the cache for the next one.
+func (h *host) snapshot(ttl, timeout time.Duration) Snapshot {
+	h.mu.Lock()
+	if h.last != nil && time.Since(h.last.Time) < ttl {
+		s := *h.last
+		h.mu.Unlock()
+		return s
+	}
+	if h.inflight == nil {
+		done := make(chan struct{})
+		h.inflight = done
+		go func() {
+			s := h.collect()
+			h.mu.Lock()
+			h.last = &s
+			h.inflight = nil
+			h.mu.Unlock()
+			close(done)
+		}()
+	}
+	done := h.inflight
+	h.mu.Unlock()
+
+	select {
+	case <-done:
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return *h.last
+	case <-time.After(timeout):
+		return Snapshot{Time: time.Now(), Duration: timeout, Err: fmt.Errorf("collection timed out after %v", timeout)}
+	}
+}
+
+// gather collects every host in parallel and builds the metric registry
+func (e *Exporter) gather() *registry {
+	snapshots := make([]Snapshot, len(e.hosts))
+	var wg sync.WaitGroup
+	for i, h := range e.hosts {
+		wg.Add(1)
+		go func(i int, h *host) {
+			defer wg.Done()
+			snapshots[i] = h.snapshot(e.CacheTTL, e.Timeout)
+		}(i, h)
+	}
+	wg.Wait()
+
+	r := newRegistry()
+	for i, h := range e.hosts {
+		addHost(r, h.config, snapshots[i])
+	}
+	for _, h := range e.hosts {
+		tunnels, _ := h.tunnels()
+		addTunnels(r, h.config, tunnels)
+	}
+	return r
+}
+
+// addHost records the connection and GPU metrics of one snapshot
+func addHost(r *registry, cfg *types.Config, s Snapshot) {
+	hostLabels := []label{{"profile", cfg.Name}, {"host", cfg.Host}}
+
+	up := 0.0
+	if s.Err == nil {
+		up = 1
+	}
+	r.gauge("dgx_up", "Whether the last SSH collection from the host succeeded.", up, hostLabels...)
+	r.gauge("dgx_collect_duration_seconds", "Time spent collecting from the host over SSH.", s.Duration.Seconds(), hostLabels...)
+	r.gauge("dgx_collect_timestamp_seconds", "Unix time of the cached collection.", float64(s.Time.UnixNano())/1e9, hostLabels...)
+	if s.Err == nil || s.Latency > 0 {
+		r.gauge("dgx_ssh_latency_seconds", "Time to establish an SSH connection to the host.", s.Latency.Seconds(), hostLabels...)
+	}
+
+	for _, g := range s.GPUs {
+		gpuLabels := append(append([]label{}, hostLabels...), label{"gpu", strconv.Itoa(g.ID)}, label{"name", g.Name})
+		r.optional("dgx_gpu_utilization_percent", "GPU utilization.", g.UtilizationPercent, gpuLabels...)
+		r.optional("dgx_gpu_memory_used_bytes", "GPU memory in use.", bytesValue(g.MemoryUsedBytes), gpuLabels...)
+		r.optional("dgx_gpu_memory_total_bytes", "Total GPU memory (absent on unified-memory GPUs such as GB10).", bytesValue(g.MemoryTotalBytes), gpuLabels...)
+		r.optional("dgx_gpu_temperature_celsius", "GPU temperature.", g.TemperatureCelsius, gpuLabels...)
+		r.optional("dgx_gpu_power_draw_watts", "GPU power draw.", g.PowerDrawWatts, gpuLabels...)
+		r.optional("dgx_gpu_power_limit_watts", "GPU power limit.", g.PowerLimitWatts, gpuLabels...)
+		r.optional("dgx_gpu_graphics_clock_mhz", "GPU graphics clock.", g.GraphicsClockMHz, gpuLabels...)
+		r.optional("dgx_gpu_memory_clock_mhz", "GPU memory clock.", g.MemoryClockMHz, gpuLabels...)
+
+		for _, p := range g.Processes {
+			procLabels := append(append([]label{}, gpuLabels[:3]...), label{"pid", strconv.Itoa(p.PID)}, label{"process", p.Name})
+			r.optional("dgx_gpu_process_memory_used_bytes", "GPU memory used by a process.", bytesValue(p.MemoryUsedBytes), procLabels...)
+		}
+	}
+}
+
+// addTunnels records whether each saved or running tunnel is up
+func addTunnels(r *registry, cfg *types.Config, tunnels []types.Tunnel) {
+	for _, t := range tunnels {
+		up := 0.0
+		if t.State == tunnel.StateUp || t.State == tunnel.StateUnsaved {
+			up = 1
+		}
+		r.gauge("dgx_tunnel_up", "Whether a saved or running tunnel is listening.", up,
+			label{"profile", cfg.Name},
+			label{"host", cfg.Host},
+			label{"tunnel", t.ID},
+			label{"kind", tunnel.KindOf(t)},
+			label{"local_port", strconv.Itoa(t.LocalPort)},
+			label{"remote_port", strconv.Itoa(t.RemotePort)},
+		)
+	}
+}
+
+func bytesValue(b *uint64) *float64 {
+	if b == nil {
+		return nil
+	}
+	v := float64(*b)
+	return &v
+}
+
+// ServeHTTP serves /metrics, negotiating OpenMetrics when the scraper asks for it
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "/metrics":
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>dgx exporter</title></head><body><h1>dgx exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+		return
+	default:
+		http.NotFound(w, req)
+		return
+	}
+
+	openMetrics := strings.Contains(req.Header.Get("Accept"), "application/openmetrics-text")
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+	e.gather().write(w, openMetrics)
+}
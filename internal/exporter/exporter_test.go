@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	spark := &types.Config{Name: "spark-a", Host: "10.0.0.5"}
+	down := &types.Config{Name: "spark-b", Host: "10.0.0.6"}
+
+	used, util, temp, procUsed := uint64(1<<30), 45.0, 61.0, uint64(512)
+	r := newRegistry()
+	addHost(r, spark, Snapshot{
+		Time:    time.Unix(1700000000, 0),
+		Latency: 12 * time.Millisecond,
+		GPUs: []types.GPUInfo{{
+			ID:                 0,
+			Name:               "NVIDIA GB10",
+			MemoryUsedBytes:    &used,
+			UtilizationPercent: &util,
+			TemperatureCelsius: &temp,
+			Processes:          []types.GPUProcess{{PID: 4242, Name: `py"thon`, MemoryUsedBytes: &procUsed}},
+		}},
+	})
+	addHost(r, down, Snapshot{Time: time.Unix(1700000000, 0), Err: errors.New("connection refused")})
+	addTunnels(r, spark, []types.Tunnel{
+		{ID: "vllm", LocalPort: 8000, RemotePort: 8000, State: "up"},
+		{ID: "jupyter", LocalPort: 8888, RemotePort: 8888, State: "down"},
+	})
+
+	var sb strings.Builder
+	if err := r.write(&sb, false); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"# TYPE dgx_up gauge\n",
+		`dgx_up{profile="spark-a",host="10.0.0.5"} 1`,
+		`dgx_up{profile="spark-b",host="10.0.0.6"} 0`,
+		`dgx_ssh_latency_seconds{profile="spark-a",host="10.0.0.5"} 0.012`,
+		`dgx_gpu_utilization_percent{profile="spark-a",host="10.0.0.5",gpu="0",name="NVIDIA GB10"} 45`,
+		`dgx_gpu_memory_used_bytes{profile="spark-a",host="10.0.0.5",gpu="0",name="NVIDIA GB10"} 1.073741824e+09`,
+		`dgx_gpu_process_memory_used_bytes{profile="spark-a",host="10.0.0.5",gpu="0",pid="4242",process="py\"thon"} 512`,
+		`dgx_tunnel_up{profile="spark-a",host="10.0.0.5",tunnel="vllm",kind="local",local_port="8000",remote_port="8000"} 1`,
+		`dgx_tunnel_up{profile="spark-a",host="10.0.0.5",tunnel="jupyter",kind="local",local_port="8888",remote_port="8888"} 0`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	// Unreported metrics are omitted rather than exported as zero
+	for _, absent := range []string{"dgx_gpu_memory_total_bytes", "dgx_gpu_power_draw_watts", `dgx_ssh_latency_seconds{profile="spark-b"`} {
+		if strings.Contains(out, absent) {
+			t.Fatalf("unexpected %q in:\n%s", absent, out)
+		}
+	}
+	if strings.Count(out, "# HELP dgx_up ") != 1 {
+		t.Fatalf("expected one HELP line per family:\n%s", out)
+	}
+}
+
+func TestSnapshotCaching(t *testing.T) {
+	var calls int32
+	h := &host{
+		config: &types.Config{Name: "spark-a"},
+		collect: func() Snapshot {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(20 * time.Millisecond)
+			return Snapshot{Time: time.Now()}
+		},
+	}
+
+	// Concurrent scrapes share one collection
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.snapshot(time.Minute, time.Second)
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("expected 1 collection for concurrent scrapes, got %d", calls)
+	}
+
+	// Fresh data is served from the cache
+	h.snapshot(time.Minute, time.Second)
+	if calls != 1 {
+		t.Fatalf("expected cached snapshot, got %d collections", calls)
+	}
+
+	// Stale data is collected again
+	h.snapshot(0, time.Second)
+	if calls != 2 {
+		t.Fatalf("expected a new collection after the TTL, got %d", calls)
+	}
+}
+
+func TestSnapshotTimeout(t *testing.T) {
+	release := make(chan struct{})
+	h := &host{
+		config: &types.Config{Name: "spark-a"},
+		collect: func() Snapshot {
+			<-release
+			return Snapshot{Time: time.Now()}
+		},
+	}
+	defer close(release)
+
+	if s := h.snapshot(time.Minute, 10*time.Millisecond); s.Err == nil {
+		t.Fatalf("expected a timeout error")
+	}
+}
+
+func TestServeOpenMetrics(t *testing.T) {
+	e := &Exporter{CacheTTL: time.Minute, Timeout: time.Second}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/openmetrics-text") {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.HasSuffix(rec.Body.String(), "# EOF\n") {
+		t.Fatalf("expected # EOF terminator, got %q", rec.Body.String())
+	}
+}
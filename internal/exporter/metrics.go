@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// label is one name="value" pair on a sample
+type label struct {
+	name  string
+	value string
+}
+
+// sample is one value of a metric family
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// family is a metric with its HELP text and samples
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+// registry collects families in the order they are first used, so the
+// exposition output is stable between scrapes
+type registry struct {
+	families []*family
+	byName   map[string]*family
+}
+
+func newRegistry() *registry {
+	return &registry{byName: make(map[string]*family)}
+}
+
+// gauge adds a sample to the named gauge family
+func (r *registry) gauge(name, help string, value float64, labels ...label) {
+	f, ok := r.byName[name]
+	if !ok {
+		f = &family{name: name, help: help}
+		r.byName[name] = f
+		r.families = append(r.families, f)
+	}
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// optional adds a sample only when the value was reported
+func (r *registry) optional(name, help string, value *float64, labels ...label) {
+	if value != nil {
+		r.gauge(name, help, *value, labels...)
+	}
+}
+
+// write renders the registry in the Prometheus text format, or in
+// OpenMetrics when openMetrics is set (which adds the # EOF terminator)
+func (r *registry) write(w io.Writer, openMetrics bool) error {
+	var sb strings.Builder
+	for _, f := range r.families {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", f.name)
+		for _, s := range f.samples {
+			sb.WriteString(f.name)
+			if len(s.labels) > 0 {
+				sb.WriteString("{")
+				for i, l := range s.labels {
+					if i > 0 {
+						sb.WriteString(",")
+					}
+					fmt.Fprintf(&sb, "%s=\"%s\"", l.name, escapeLabel(l.value))
+				}
+				sb.WriteString("}")
+			}
+			sb.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+	if openMetrics {
+		sb.WriteString("# EOF\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,260 @@
+package firmware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/version"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Component statuses, from best to worst
+const (
+	StatusOK          = "ok"
+	StatusMissing     = "missing"     // Not installed, or the version could not be read
+	StatusUpgrade     = "upgrade"     // Below the recommended version
+	StatusUnsupported = "unsupported" // Below the minimum version
+)
+
+var severity = map[string]int{StatusOK: 0, StatusMissing: 1, StatusUpgrade: 2, StatusUnsupported: 3}
+
+// sections read every version in one SSH session. The BIOS version needs
+// root for dmidecode; sysfs has the same string readable by anyone.
+var sections = []gpu.Section{
+	{Name: "bios", Command: "sudo -n dmidecode -s bios-version 2>/dev/null || cat /sys/class/dmi/id/bios_version"},
+	{Name: "driver", Command: "nvidia-smi --query-gpu=driver_version --format=csv,noheader"},
+	{Name: "cuda", Command: "nvidia-smi | grep -o 'CUDA Version: [0-9.]*'"},
+	{Name: "dgx-os", Command: "cat /etc/dgx-release"},
+	{Name: "images", Command: "docker image ls --format '{{.Repository}}:{{.Tag}}'"},
+}
+
+// missingAdvice explains a component whose version could not be read
+var missingAdvice = map[string]string{
+	"bios":   "could not read the BIOS version from dmidecode or /sys/class/dmi/id",
+	"driver": "nvidia-smi failed; run dgx diagnostics",
+	"cuda":   "nvidia-smi did not report a CUDA version; run dgx diagnostics",
+	"dgx-os": "no /etc/dgx-release; this host does not run DGX OS",
+}
+
+// Installed is the software found on the DGX
+type Installed struct {
+	Versions map[string]string // Component name -> version, missing if unknown
+	Images   []string          // Pulled images as repository:tag
+}
+
+// Read collects the installed versions in one SSH session
+func Read(monitor *gpu.Monitor) (Installed, error) {
+	results, err := monitor.RunSections(sections)
+	if err != nil {
+		return Installed{}, fmt.Errorf("failed to read versions: %w", err)
+	}
+	return Parse(results), nil
+}
+
+// Parse builds Installed from collected sections
+func Parse(results map[string]gpu.SectionResult) Installed {
+	in := Installed{Versions: make(map[string]string)}
+	output := func(name string) string {
+		r, ok := results[name]
+		if !ok || r.Exit != 0 {
+			return ""
+		}
+		return strings.TrimSpace(r.Output)
+	}
+
+	if v := version.FirstLine(output("bios")); v != "" {
+		in.Versions["bios"] = v
+	}
+	if v := version.FirstLine(output("driver")); v != "" {
+		in.Versions["driver"] = v
+	}
+	if _, v, ok := strings.Cut(output("cuda"), "CUDA Version:"); ok && strings.TrimSpace(v) != "" {
+		in.Versions["cuda"] = version.FirstLine(v)
+	}
+	if v := parseDGXRelease(output("dgx-os")); v != "" {
+		in.Versions["dgx-os"] = v
+	}
+	for _, line := range strings.Split(output("images"), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasSuffix(line, ":<none>") {
+			in.Images = append(in.Images, line)
+		}
+	}
+	return in
+}
+
+// parseDGXRelease returns the DGX OS version from /etc/dgx-release. OTA
+// updates append a DGX_OTA_VERSION line each; the last one is current.
+func parseDGXRelease(release string) string {
+	current := ""
+	for _, line := range strings.Split(release, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "DGX_SWBUILD_VERSION":
+			if current == "" {
+				current = value
+			}
+		case "DGX_OTA_VERSION":
+			current = value
+		}
+	}
+	return current
+}
+
+// Check compares the installed software with a matrix, components first
+// and then images, in matrix order
+func Check(m *Matrix, in Installed) []types.FirmwareComponent {
+	var results []types.FirmwareComponent
+	for _, c := range m.Components {
+		current := in.Versions[c.Name]
+		r := types.FirmwareComponent{Name: c.Name, Current: current, Minimum: c.Minimum, Recommended: c.Recommended}
+		switch {
+		case current == "":
+			r.Current, r.Status, r.Advice = "unknown", StatusMissing, missingAdvice[c.Name]
+		default:
+			r.Status = compareStatus(current, c.Minimum, c.Recommended, version.Compare)
+			if r.Status != StatusOK {
+				r.Advice = c.Advice
+			}
+		}
+		results = append(results, r)
+	}
+
+	for _, img := range m.Images {
+		results = append(results, checkImage(img, in.Images))
+	}
+	return results
+}
+
+// checkImage compares the newest pulled tag of an image with the matrix.
+// Tags with a different suffix, such as -py2 for -py3, are ignored.
+func checkImage(img Image, pulled []string) types.FirmwareComponent {
+	r := types.FirmwareComponent{Name: img.Image, Minimum: img.Minimum, Recommended: img.Recommended}
+	pull := fmt.Sprintf("docker pull %s:%s", img.Image, img.Recommended)
+	_, suffix, _ := strings.Cut(img.Recommended, "-")
+
+	newest := ""
+	for _, ref := range pulled {
+		// Registry hosts may carry a port, so the tag follows the last colon
+		i := strings.LastIndex(ref, ":")
+		if i < 0 || ref[:i] != img.Image {
+			continue
+		}
+		tag := ref[i+1:]
+		if _, s, _ := strings.Cut(tag, "-"); s != suffix {
+			continue
+		}
+		if newest == "" || compareTags(tag, newest) > 0 {
+			newest = tag
+		}
+	}
+
+	if newest == "" {
+		r.Current, r.Status = "not pulled", StatusMissing
+		r.Advice = pull
+		if img.Playbook != "" {
+			r.Advice += fmt.Sprintf(" (dgx run %s pulls it when needed)", img.Playbook)
+		}
+		return r
+	}
+	r.Current = newest
+	r.Status = compareStatus(newest, img.Minimum, img.Recommended, compareTags)
+	if r.Status != StatusOK {
+		r.Advice = pull
+	}
+	return r
+}
+
+// compareStatus places a version against the minimum and recommended
+// versions; either may be empty. Versions that are not numeric only
+// match when equal.
+func compareStatus(current, minimum, recommended string, compare func(a, b string) int) string {
+	if minimum != "" && bothNumeric(current, minimum) && compare(current, minimum) < 0 {
+		return StatusUnsupported
+	}
+	if recommended == "" {
+		return StatusOK
+	}
+	if bothNumeric(current, recommended) {
+		if compare(current, recommended) < 0 {
+			return StatusUpgrade
+		}
+		return StatusOK
+	}
+	if current != recommended {
+		return StatusUpgrade
+	}
+	return StatusOK
+}
+
+// Worst returns the most severe status among results, or ok if there are none
+func Worst(results []types.FirmwareComponent) string {
+	worst := StatusOK
+	for _, r := range results {
+		if severity[r.Status] > severity[worst] {
+			worst = r.Status
+		}
+	}
+	return worst
+}
+
+// Format formats results as a table followed by advice for the components
+// that are not ok
+func Format(results []types.FirmwareComponent) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tCURRENT\tRECOMMENDED\tMINIMUM\tSTATUS")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Name, r.Current, orDash(r.Recommended), orDash(r.Minimum), r.Status)
+	}
+	w.Flush()
+
+	first := true
+	for _, r := range results {
+		if r.Advice == "" {
+			continue
+		}
+		if first {
+			sb.WriteString("\nAdvice:\n")
+			first = false
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", r.Name, r.Advice)
+	}
+	return sb.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// bothNumeric reports whether both versions are dotted numbers, ignoring a
+// tag suffix after "-"
+func bothNumeric(a, b string) bool {
+	return numeric(a) && numeric(b)
+}
+
+func numeric(v string) bool {
+	v, _, _ = strings.Cut(v, "-")
+	for _, part := range strings.Split(v, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// compareTags compares image tags such as 25.09-py3 by their version
+func compareTags(a, b string) int {
+	a, _, _ = strings.Cut(a, "-")
+	b, _, _ = strings.Cut(b, "-")
+	return version.Compare(a, b)
+}
@@ -0,0 +1,151 @@
+package firmware
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/playbook"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func find(t *testing.T, results []types.FirmwareComponent, name string) types.FirmwareComponent {
+	t.Helper()
+	for _, r := range results {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no result for %s", name)
+	return types.FirmwareComponent{}
+}
+
+func TestEmbeddedMatrixPinsPlaybookImages(t *testing.T) {
+	m := DefaultMatrix()
+	for _, ref := range playbook.PinnedImages() {
+		image, tag, _ := strings.Cut(ref, ":")
+		found := false
+		for _, img := range m.Images {
+			if img.Image == image {
+				found = true
+				if img.Recommended != tag {
+					t.Fatalf("%s: matrix recommends %s but the playbook pins %s", image, img.Recommended, tag)
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("matrix does not cover %s", ref)
+		}
+	}
+}
+
+func TestParseMatrixRejectsMistakes(t *testing.T) {
+	tests := map[string]string{
+		"missing version":   "components: []\n",
+		"unknown component": "version: x\ncomponents:\n  - name: firmware\n",
+		"duplicate":         "version: x\ncomponents:\n  - name: cuda\n  - name: cuda\n",
+		"unknown field":     "version: x\ncomponents:\n  - name: cuda\n    recomended: \"13.0\"\n",
+		"tagged image":      "version: x\nimages:\n  - image: nvcr.io/nvidia/vllm:25.09-py3\n    recommended: 25.09-py3\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseMatrix([]byte(data)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	release, err := os.ReadFile(filepath.Join("testdata", "dgx-release"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	images, err := os.ReadFile(filepath.Join("testdata", "images.txt"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	in := Parse(map[string]gpu.SectionResult{
+		"bios":   {Output: "5.36_0ACUM018\n"},
+		"driver": {Output: "580.95.05\n"},
+		"cuda":   {Output: "CUDA Version: 13.0\n"},
+		"dgx-os": {Output: string(release)},
+		"images": {Output: string(images)},
+	})
+	want := map[string]string{"bios": "5.36_0ACUM018", "driver": "580.95.05", "cuda": "13.0", "dgx-os": "7.2.3"}
+	for name, v := range want {
+		if in.Versions[name] != v {
+			t.Fatalf("%s: expected %s, got %q", name, v, in.Versions[name])
+		}
+	}
+	if len(in.Images) != 6 {
+		t.Fatalf("expected 6 tagged images, got %v", in.Images)
+	}
+
+	missing := Parse(map[string]gpu.SectionResult{
+		"dgx-os": {Output: "cat: /etc/dgx-release: No such file or directory\n", Exit: 1},
+	})
+	if _, ok := missing.Versions["dgx-os"]; ok {
+		t.Fatalf("expected no DGX OS version without /etc/dgx-release")
+	}
+}
+
+func TestCheckEmbeddedMatrix(t *testing.T) {
+	images, err := os.ReadFile(filepath.Join("testdata", "images.txt"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	in := Installed{
+		Versions: map[string]string{"driver": "580.95.05", "cuda": "12.8", "dgx-os": "7.2.1", "bios": "5.36_0ACUM018"},
+		Images:   strings.Fields(string(images)),
+	}
+	results := Check(DefaultMatrix(), in)
+
+	want := map[string]string{
+		"bios":                    StatusOK, // No recommended BIOS; reported only
+		"driver":                  StatusOK,
+		"cuda":                    StatusUnsupported,
+		"dgx-os":                  StatusUpgrade,
+		"nvcr.io/nvidia/vllm":     StatusUpgrade, // 25.11-py2 has another suffix
+		"nvcr.io/nvidia/tensorrt": StatusOK,
+	}
+	for name, status := range want {
+		if got := find(t, results, name).Status; got != status {
+			t.Fatalf("%s: expected %s, got %s", name, status, got)
+		}
+	}
+	if got := find(t, results, "nvcr.io/nvidia/vllm"); got.Current != "25.06-py3" || got.Advice != "docker pull nvcr.io/nvidia/vllm:25.09-py3" {
+		t.Fatalf("unexpected vllm result: %+v", got)
+	}
+	if Worst(results) != StatusUnsupported {
+		t.Fatalf("expected unsupported overall, got %s", Worst(results))
+	}
+}
+
+func TestCheckLocalMatrix(t *testing.T) {
+	m, err := LoadMatrix(filepath.Join("testdata", "matrix-lab.yaml"))
+	if err != nil {
+		t.Fatalf("failed to load matrix: %v", err)
+	}
+	images, err := os.ReadFile(filepath.Join("testdata", "images.txt"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	in := Installed{
+		Versions: map[string]string{"driver": "580.95.05", "bios": "5.30_0ACUM011"},
+		Images:   strings.Fields(string(images)),
+	}
+	results := Check(m, in)
+	if len(results) != 3 {
+		t.Fatalf("expected only the pinned components, got %d", len(results))
+	}
+	if got := find(t, results, "driver").Status; got != StatusOK {
+		t.Fatalf("a newer driver than the baseline should be ok, got %s", got)
+	}
+	if got := find(t, results, "bios"); got.Status != StatusUpgrade || got.Advice != "" {
+		t.Fatalf("a different BIOS string should need an upgrade: %+v", got)
+	}
+	if got := find(t, results, "registry.lab:5000/team/trainer"); got.Status != StatusUpgrade || got.Current != "1.4" {
+		t.Fatalf("unexpected trainer result: %+v", got)
+	}
+}
@@ -0,0 +1,115 @@
+package firmware
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MatrixFile is the name of a local matrix in the dgx config directory,
+// used instead of the embedded one when present
+const MatrixFile = "firmware-matrix.yaml"
+
+//go:embed matrix.yaml
+var embeddedMatrix []byte
+
+// Components a matrix can pin, in report order
+var componentNames = []string{"bios", "driver", "cuda", "dgx-os"}
+
+// Matrix is a compatibility baseline: the versions a DGX should run
+type Matrix struct {
+	Version    string      `yaml:"version"`
+	Platform   string      `yaml:"platform"`
+	Components []Component `yaml:"components"`
+	Images     []Image     `yaml:"images"`
+}
+
+// Component pins the version of one part of the software stack
+type Component struct {
+	Name        string `yaml:"name"`
+	Minimum     string `yaml:"minimum"`
+	Recommended string `yaml:"recommended"`
+	Advice      string `yaml:"advice"` // How to upgrade
+}
+
+// Image pins the tag of a container image
+type Image struct {
+	Image       string `yaml:"image"` // Repository, without a tag
+	Minimum     string `yaml:"minimum"`
+	Recommended string `yaml:"recommended"`
+	Playbook    string `yaml:"playbook"` // Playbook that uses it, if any
+}
+
+// EmbeddedMatrix returns the matrix built into dgx, as YAML
+func EmbeddedMatrix() []byte {
+	return embeddedMatrix
+}
+
+// DefaultMatrix returns the matrix built into dgx
+func DefaultMatrix() *Matrix {
+	m, err := ParseMatrix(embeddedMatrix)
+	if err != nil {
+		panic(fmt.Sprintf("embedded firmware matrix: %v", err))
+	}
+	return m
+}
+
+// LoadMatrix reads a matrix from a YAML file
+func LoadMatrix(path string) (*Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseMatrix(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseMatrix parses and validates a matrix. Unknown fields are rejected
+// so a typo does not silently drop a pin.
+func ParseMatrix(data []byte) (*Matrix, error) {
+	var m Matrix
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid matrix: %w", err)
+	}
+	if m.Version == "" {
+		return nil, fmt.Errorf("invalid matrix: version is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range m.Components {
+		if !isComponent(c.Name) {
+			return nil, fmt.Errorf("invalid matrix: unknown component %q (valid: bios, driver, cuda, dgx-os)", c.Name)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("invalid matrix: component %q listed twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	for _, img := range m.Images {
+		if img.Image == "" || img.Recommended == "" {
+			return nil, fmt.Errorf("invalid matrix: images need an image and a recommended tag")
+		}
+		if strings.Contains(img.Image[strings.LastIndex(img.Image, "/")+1:], ":") {
+			return nil, fmt.Errorf("invalid matrix: image %q must not include a tag; put it in recommended", img.Image)
+		}
+	}
+	return &m, nil
+}
+
+func isComponent(name string) bool {
+	for _, n := range componentNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
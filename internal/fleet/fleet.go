@@ -0,0 +1,212 @@
+package fleet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// DefaultParallel is the number of hosts contacted at once when unset
+const DefaultParallel = 4
+
+// Options controls how a command fans out across hosts
+type Options struct {
+	Parallel int       // Maximum concurrent SSH sessions
+	FailFast bool      // Cancel remaining hosts after the first failure
+	Stdout   io.Writer // Defaults to os.Stdout
+	Stderr   io.Writer // Defaults to os.Stderr
+}
+
+// Result captures the outcome of running a command on one host
+type Result struct {
+	Profile  string
+	Host     string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+	Skipped  bool // Never started because of --fail-fast
+}
+
+// Failed reports whether the host did not complete successfully
+func (r Result) Failed() bool {
+	return r.Skipped || r.Err != nil
+}
+
+// Execute runs command on every host with bounded concurrency, streaming
+// output prefixed with the profile name. Results are returned in host order.
+func Execute(hosts []*types.Config, command string, opts Options) []Result {
+	if opts.Parallel <= 0 {
+		opts.Parallel = DefaultParallel
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+
+	width := 0
+	for _, h := range hosts {
+		if len(h.Name) > width {
+			width = len(h.Name)
+		}
+	}
+
+	var (
+		outMu      sync.Mutex
+		wg         sync.WaitGroup
+		cancelOnce sync.Once
+		sem        = make(chan struct{}, opts.Parallel)
+		cancel     = make(chan struct{})
+		results    = make([]Result, len(hosts))
+	)
+
+	for i, host := range hosts {
+		results[i] = Result{Profile: host.Name, Host: host.Host, ExitCode: -1}
+
+		wg.Add(1)
+		go func(i int, host *types.Config) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-cancel:
+				results[i].Skipped = true
+				return
+			}
+			defer func() { <-sem }()
+
+			select {
+			case <-cancel:
+				results[i].Skipped = true
+				return
+			default:
+			}
+
+			prefix := fmt.Sprintf("[%-*s] ", width, host.Name)
+			stdout := newPrefixWriter(opts.Stdout, prefix, &outMu)
+			stderr := newPrefixWriter(opts.Stderr, prefix, &outMu)
+
+			start := time.Now()
+			err := runOne(host, command, stdout, stderr, cancel)
+			stdout.Flush()
+			stderr.Flush()
+
+			results[i].Duration = time.Since(start)
+			results[i].ExitCode = ssh.ExitStatus(err)
+			results[i].Err = err
+
+			if err != nil && opts.FailFast {
+				cancelOnce.Do(func() { close(cancel) })
+			}
+		}(i, host)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// runOne executes command on a single host, closing the connection early
+// if the fan-out is cancelled
+func runOne(host *types.Config, command string, stdout, stderr io.Writer, cancel <-chan struct{}) error {
+	client, err := ssh.NewClient(host)
+	if err != nil {
+		return err
+	}
+	// Hosts connect concurrently, so host key prompts would race for stdin
+	client.SetBatchMode(true)
+	defer client.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cancel:
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	return client.ExecuteStream(command, stdout, stderr)
+}
+
+// FormatSummary renders a table of exit codes and durations per host
+func FormatSummary(results []Result) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tHOST\tEXIT\tDURATION\tSTATUS")
+	for _, r := range results {
+		exit := "-"
+		duration := "-"
+		status := "ok"
+		switch {
+		case r.Skipped:
+			status = "skipped"
+		case r.Err != nil:
+			status = "failed"
+			if r.ExitCode < 0 {
+				status = "error: " + r.Err.Error()
+			}
+		}
+		if !r.Skipped {
+			duration = r.Duration.Round(time.Millisecond).String()
+			if r.ExitCode >= 0 {
+				exit = fmt.Sprintf("%d", r.ExitCode)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Profile, r.Host, exit, duration, status)
+	}
+	tw.Flush()
+	return buf.String()
+}
+
+// prefixWriter prefixes every complete line written to it and serialises
+// writes to the shared destination so hosts never interleave mid-line
+type prefixWriter struct {
+	dest   io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func newPrefixWriter(dest io.Writer, prefix string, mu *sync.Mutex) *prefixWriter {
+	return &prefixWriter{dest: dest, prefix: prefix, mu: mu}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if err := w.writeLine(string(w.buf[:idx])); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.dest, "%s%s\n", w.prefix, line)
+	return err
+}
@@ -0,0 +1,49 @@
+package fleet
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var (
+		out bytes.Buffer
+		mu  sync.Mutex
+	)
+	w := newPrefixWriter(&out, "[spark-a] ", &mu)
+
+	w.Write([]byte("GPU 0: NVIDIA GB10\nDriver"))
+	w.Write([]byte(" 580.95\npartial"))
+	w.Flush()
+
+	want := "[spark-a] GPU 0: NVIDIA GB10\n[spark-a] Driver 580.95\n[spark-a] partial\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%q\nwant:\n%q", out.String(), want)
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	summary := FormatSummary([]Result{
+		{Profile: "spark-a", Host: "10.0.0.1", ExitCode: 0, Duration: 1200 * time.Millisecond},
+		{Profile: "spark-b", Host: "10.0.0.2", ExitCode: 2, Duration: time.Second, Err: errors.New("command failed")},
+		{Profile: "spark-c", Host: "10.0.0.3", ExitCode: -1, Skipped: true},
+	})
+
+	lines := strings.Split(strings.TrimSpace(summary), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header + 3 rows, got %d:\n%s", len(lines), summary)
+	}
+	if !strings.Contains(lines[1], "1.2s") || !strings.HasSuffix(lines[1], "ok") {
+		t.Fatalf("unexpected row %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[2] != "2" || fields[4] != "failed" {
+		t.Fatalf("unexpected row %q", lines[2])
+	}
+	if !strings.HasSuffix(lines[3], "skipped") {
+		t.Fatalf("unexpected row %q", lines[3])
+	}
+}
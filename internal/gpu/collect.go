@@ -0,0 +1,298 @@
+package gpu
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Payload markers. Every section starts with "@@section <name>" and ends
+// with "@@exit <status>" so one failing command does not hide the others.
+const (
+	markerSection = "@@section"
+	markerExit    = "@@exit"
+)
+
+// Section is one named command in a collect script
+type Section struct {
+	Name    string
+	Command string
+}
+
+// Sections gathered by Collect
+var (
+	gpuSections = []Section{
+		{"gpus", "nvidia-smi --query-gpu=uuid," + gpuQueryFields + " --format=csv,noheader,nounits"},
+		{"apps", "nvidia-smi --query-compute-apps=gpu_uuid,pid,process_name,used_memory --format=csv,noheader,nounits"},
+	}
+	hostSections = []Section{
+		{"os", "uname -r; grep -m1 '^PRETTY_NAME=' /etc/os-release"},
+		{"uptime", "cat /proc/uptime"},
+		{"loadavg", "cat /proc/loadavg"},
+		{"cpus", "nproc"},
+		{"meminfo", "grep -E '^(MemTotal|MemAvailable|SwapTotal|SwapFree):' /proc/meminfo"},
+		{"pressure", "cat /proc/pressure/memory"},
+		{"thermal", "cat /sys/class/thermal/thermal_zone*/temp"},
+		{"disk", "df -P -B1 /"},
+		{"dockerdisk", `d=$(docker info --format '{{.DockerRootDir}}' 2>/dev/null); d=${d:-/var/lib/docker}; echo "$d"; df -P -B1 "$d"`},
+	}
+)
+
+// SectionResult is the output and exit status of one section
+type SectionResult struct {
+	Output string
+	Exit   int
+}
+
+// Err returns an error describing a section that exited non-zero
+func (r SectionResult) Err(name string) error {
+	if r.Exit == 0 {
+		return nil
+	}
+	msg := strings.TrimSpace(r.Output)
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", r.Exit)
+	}
+	return fmt.Errorf("%s: %s", name, msg)
+}
+
+// Snapshot is GPU and host state collected in one SSH round trip
+type Snapshot struct {
+	GPUs []types.GPUInfo
+	Host types.HostInfo
+	// GPUErr is set when nvidia-smi failed; host data is still filled in
+	GPUErr error
+	// Sections holds the raw results of extra sections passed to Collect
+	Sections map[string]SectionResult
+}
+
+// collectScript builds one shell invocation that runs every section
+func collectScript(sections []Section) string {
+	var sb strings.Builder
+	for _, s := range sections {
+		fmt.Fprintf(&sb, "echo '%s %s'; { %s; } 2>&1; echo \"%s $?\"\n", markerSection, s.Name, s.Command, markerExit)
+	}
+	return sb.String()
+}
+
+// splitSections parses collectScript output into results by section name
+func splitSections(output string) map[string]SectionResult {
+	results := make(map[string]SectionResult)
+	var name string
+	var body strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, markerSection+" "):
+			name = strings.TrimSpace(strings.TrimPrefix(line, markerSection))
+			body.Reset()
+		case strings.HasPrefix(line, markerExit+" ") && name != "":
+			exit, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, markerExit)))
+			if err != nil {
+				exit = -1
+			}
+			results[name] = SectionResult{Output: body.String(), Exit: exit}
+			name = ""
+		case name != "":
+			body.WriteString(line + "\n")
+		}
+	}
+	return results
+}
+
+// Collect gathers GPUs, their processes and host resources in one SSH
+// session. Extra sections run in the same session and are returned raw.
+func (m *Monitor) Collect(extra ...Section) (Snapshot, error) {
+	sections := append(append(append([]Section{}, gpuSections...), hostSections...), extra...)
+	results, err := m.RunSections(sections)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to collect status: %w", err)
+	}
+	return parseSnapshot(results), nil
+}
+
+// RunSections runs commands in one SSH session and returns their output
+// and exit status by section name
+func (m *Monitor) RunSections(sections []Section) (map[string]SectionResult, error) {
+	output, err := m.sshClient.Execute(collectScript(sections))
+	if err != nil {
+		return nil, err
+	}
+	return splitSections(output), nil
+}
+
+// parseSnapshot builds a Snapshot from collected sections
+func parseSnapshot(sections map[string]SectionResult) Snapshot {
+	s := Snapshot{Host: parseHostInfo(sections), Sections: sections}
+	s.GPUs, s.GPUErr = parseGPUSections(sections)
+	return s
+}
+
+// parseGPUSections joins the gpus and apps sections on GPU UUID
+func parseGPUSections(sections map[string]SectionResult) ([]types.GPUInfo, error) {
+	gpuResult, ok := sections["gpus"]
+	if !ok {
+		return nil, fmt.Errorf("failed to query GPU: no nvidia-smi output")
+	}
+	if err := gpuResult.Err("nvidia-smi"); err != nil {
+		return nil, fmt.Errorf("failed to query GPU: %w", err)
+	}
+	return joinGPUs(gpuResult.Output, sections["apps"].Output), nil
+}
+
+// joinGPUs parses uuid-prefixed --query-gpu and --query-compute-apps output
+// and attaches each process to its GPU
+func joinGPUs(gpuOutput, appsOutput string) []types.GPUInfo {
+	gpus := make([]types.GPUInfo, 0)
+	byUUID := make(map[string]int)
+	for _, line := range strings.Split(gpuOutput, "\n") {
+		uuid, rest, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		parsed, _ := parseNvidiaSMI(rest)
+		if len(parsed) == 1 {
+			byUUID[strings.TrimSpace(uuid)] = len(gpus)
+			gpus = append(gpus, parsed[0])
+		}
+	}
+
+	for _, line := range strings.Split(appsOutput, "\n") {
+		uuid, rest, ok := strings.Cut(line, ",")
+		if !ok {
+			continue
+		}
+		if i, ok := byUUID[strings.TrimSpace(uuid)]; ok {
+			gpus[i].Processes = append(gpus[i].Processes, parseComputeApps(rest)...)
+		}
+	}
+	return gpus
+}
+
+// parseHostInfo reads host sections; sections that failed leave their fields nil
+func parseHostInfo(sections map[string]SectionResult) types.HostInfo {
+	var h types.HostInfo
+
+	if r := sections["os"]; r.Exit == 0 {
+		// 6.11.0-1016-nvidia
+		// PRETTY_NAME="Ubuntu 24.04.3 LTS"
+		lines := strings.Split(strings.TrimSpace(r.Output), "\n")
+		h.Kernel = strings.TrimSpace(lines[0])
+		if len(lines) > 1 {
+			h.OS = strings.Trim(strings.TrimPrefix(strings.TrimSpace(lines[1]), "PRETTY_NAME="), `"`)
+		}
+	}
+
+	if r := sections["uptime"]; r.Exit == 0 {
+		// 350735.47 234388.90
+		if fields := strings.Fields(r.Output); len(fields) >= 1 {
+			h.UptimeSeconds = parseMetric(fields[0])
+		}
+	}
+
+	if r := sections["loadavg"]; r.Exit == 0 {
+		// 0.52 0.40 0.33 1/1075 12345
+		if fields := strings.Fields(r.Output); len(fields) >= 3 {
+			h.Load1 = parseMetric(fields[0])
+			h.Load5 = parseMetric(fields[1])
+			h.Load15 = parseMetric(fields[2])
+		}
+	}
+
+	if r := sections["cpus"]; r.Exit == 0 {
+		h.CPUs, _ = strconv.Atoi(strings.TrimSpace(r.Output))
+	}
+
+	if r := sections["meminfo"]; r.Exit == 0 {
+		for _, line := range strings.Split(r.Output, "\n") {
+			// MemTotal:       128544140 kB
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			bytes := kb * 1024
+			switch fields[0] {
+			case "MemTotal:":
+				h.MemoryTotalBytes = &bytes
+			case "MemAvailable:":
+				h.MemoryAvailableBytes = &bytes
+			case "SwapTotal:":
+				h.SwapTotalBytes = &bytes
+			case "SwapFree:":
+				h.SwapFreeBytes = &bytes
+			}
+		}
+	}
+
+	if r := sections["pressure"]; r.Exit == 0 {
+		// some avg10=0.00 avg60=0.12 avg300=0.05 total=123456
+		for _, line := range strings.Split(r.Output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && fields[0] == "some" {
+				h.MemoryPressurePercent = parseMetric(strings.TrimPrefix(fields[2], "avg60="))
+			}
+		}
+	}
+
+	if r := sections["thermal"]; r.Exit == 0 {
+		// Millidegrees per thermal zone; keep the hottest
+		for _, line := range strings.Fields(r.Output) {
+			milli, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				continue
+			}
+			if c := milli / 1000; h.TemperatureCelsius == nil || c > *h.TemperatureCelsius {
+				h.TemperatureCelsius = &c
+			}
+		}
+	}
+
+	if r := sections["disk"]; r.Exit == 0 {
+		h.Disks = parseDF(r.Output)
+	}
+	if r := sections["dockerdisk"]; r.Exit == 0 {
+		// The Docker root dir, then df for it
+		path, df, _ := strings.Cut(r.Output, "\n")
+		for _, d := range parseDF(df) {
+			d.Path = strings.TrimSpace(path)
+			h.Disks = append(h.Disks, d)
+		}
+	}
+	return h
+}
+
+// parseDF parses POSIX df -P -B1 output, naming each disk by its mount point
+func parseDF(output string) []types.DiskUsage {
+	var disks []types.DiskUsage
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		// Filesystem 1-blocks Used Available Capacity Mounted-on
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		total, err1 := strconv.ParseUint(fields[1], 10, 64)
+		used, err2 := strconv.ParseUint(fields[2], 10, 64)
+		avail, err3 := strconv.ParseUint(fields[3], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		mount := strings.Join(fields[5:], " ")
+		disks = append(disks, types.DiskUsage{
+			Path:           mount,
+			Mount:          mount,
+			TotalBytes:     total,
+			UsedBytes:      used,
+			AvailableBytes: avail,
+		})
+	}
+	return disks
+}
@@ -0,0 +1,90 @@
+package gpu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectScript(t *testing.T) {
+	script := collectScript(append(append([]Section{}, gpuSections...), hostSections...))
+	for _, s := range append(append([]Section{}, gpuSections...), hostSections...) {
+		if !strings.Contains(script, "echo '@@section "+s.Name+"'; { "+s.Command+"; } 2>&1; echo \"@@exit $?\"") {
+			t.Fatalf("script missing section %s:\n%s", s.Name, script)
+		}
+	}
+}
+
+func TestParseSnapshotGB10(t *testing.T) {
+	s := parseSnapshot(splitSections(readFixture(t, "collect-gb10.txt")))
+	if s.GPUErr != nil {
+		t.Fatalf("unexpected GPU error: %v", s.GPUErr)
+	}
+
+	if len(s.GPUs) != 1 {
+		t.Fatalf("expected 1 GPU, got %d", len(s.GPUs))
+	}
+	g := s.GPUs[0]
+	if g.Name != "NVIDIA GB10" || g.UtilizationPercent == nil || *g.UtilizationPercent != 96 {
+		t.Fatalf("unexpected GPU %+v", g)
+	}
+	if len(g.Processes) != 2 || g.Processes[1].Name != "VLLM::EngineCore" || g.Processes[1].MemoryUsedBytes != nil {
+		t.Fatalf("unexpected processes %+v", g.Processes)
+	}
+
+	h := s.Host
+	if h.UptimeSeconds == nil || *h.UptimeSeconds != 350735.47 {
+		t.Fatalf("unexpected uptime %v", h.UptimeSeconds)
+	}
+	if h.Load1 == nil || *h.Load1 != 3.52 || h.Load15 == nil || *h.Load15 != 1.33 || h.CPUs != 20 {
+		t.Fatalf("unexpected load %+v", h)
+	}
+	if h.MemoryTotalBytes == nil || *h.MemoryTotalBytes != 128544140*1024 {
+		t.Fatalf("unexpected memory total %v", h.MemoryTotalBytes)
+	}
+	if h.MemoryAvailableBytes == nil || *h.MemoryAvailableBytes != 80114236*1024 {
+		t.Fatalf("unexpected memory available %v", h.MemoryAvailableBytes)
+	}
+	if h.OS != "Ubuntu 24.04.3 LTS" || h.Kernel != "6.11.0-1016-nvidia" {
+		t.Fatalf("unexpected OS %q kernel %q", h.OS, h.Kernel)
+	}
+	if h.MemoryPressurePercent == nil || *h.MemoryPressurePercent != 1.25 {
+		t.Fatalf("unexpected memory pressure %v", h.MemoryPressurePercent)
+	}
+	if h.TemperatureCelsius == nil || *h.TemperatureCelsius != 47.25 {
+		t.Fatalf("unexpected temperature %v", h.TemperatureCelsius)
+	}
+	if len(h.Disks) != 2 || h.Disks[0].Path != "/" || h.Disks[0].UsedBytes != 1207960305664 {
+		t.Fatalf("unexpected disks %+v", h.Disks)
+	}
+	if h.Disks[1].Path != "/var/lib/docker" || h.Disks[1].Mount != "/" {
+		t.Fatalf("unexpected Docker root disk %+v", h.Disks[1])
+	}
+}
+
+func TestParseSnapshotWithoutDriver(t *testing.T) {
+	s := parseSnapshot(splitSections(readFixture(t, "collect-no-driver.txt")))
+	if s.GPUErr == nil || !strings.Contains(s.GPUErr.Error(), "command not found") {
+		t.Fatalf("expected nvidia-smi error, got %v", s.GPUErr)
+	}
+	if len(s.GPUs) != 0 {
+		t.Fatalf("expected no GPUs, got %+v", s.GPUs)
+	}
+
+	// Host sections still parse; the failed df leaves disks empty
+	if s.Host.Load1 == nil || *s.Host.Load1 != 0.10 || s.Host.MemoryTotalBytes == nil {
+		t.Fatalf("expected host data despite missing driver: %+v", s.Host)
+	}
+	if len(s.Host.Disks) != 0 {
+		t.Fatalf("expected no disks after df failure, got %+v", s.Host.Disks)
+	}
+}
+
+func TestParseGPUCount(t *testing.T) {
+	count, err := parseGPUCount(readFixture(t, "query-gpu-count-h100.txt"))
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 GPUs, got %d (%v)", count, err)
+	}
+	if _, err := parseGPUCount("No devices were found\n"); err == nil {
+		t.Fatalf("expected error for unparseable count")
+	}
+}
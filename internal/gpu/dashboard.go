@@ -0,0 +1,196 @@
+package gpu
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// sparkTicks are the block characters used by Sparkline, lowest first
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// Terminal control sequences used to redraw the dashboard in place
+const (
+	ansiClear      = "\033[H\033[2J"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+)
+
+// Sparkline renders values scaled between 0 and max as block characters.
+// A non-positive max scales to the largest value.
+func Sparkline(values []float64, max float64) string {
+	if max <= 0 {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > 0 && v > 0 {
+			i = int(v / max * float64(len(sparkTicks)-1))
+			if i >= len(sparkTicks) {
+				i = len(sparkTicks) - 1
+			}
+		}
+		sb.WriteRune(sparkTicks[i])
+	}
+	return sb.String()
+}
+
+// history keeps the most recent values of one metric
+type history struct {
+	values []float64
+	size   int
+}
+
+func (h *history) add(v *float64) {
+	value := 0.0
+	if v != nil {
+		value = *v
+	}
+	h.values = append(h.values, value)
+	if len(h.values) > h.size {
+		h.values = h.values[len(h.values)-h.size:]
+	}
+}
+
+// gpuHistory tracks sparkline data for one GPU
+type gpuHistory struct {
+	util, mem, temp, power history
+}
+
+// Dashboard renders a compact live view of GPU samples
+type Dashboard struct {
+	// Title is shown on the first line, e.g. the profile and host
+	Title string
+	// Width is the number of samples kept for each sparkline
+	Width int
+
+	gpus map[int]*gpuHistory
+}
+
+// NewDashboard creates a dashboard with sparklines of the given width
+func NewDashboard(title string, width int) *Dashboard {
+	return &Dashboard{
+		Title: title,
+		Width: width,
+		gpus:  make(map[int]*gpuHistory),
+	}
+}
+
+// Start hides the cursor; call Stop to restore the terminal
+func (d *Dashboard) Start(w io.Writer) {
+	fmt.Fprint(w, ansiHideCursor)
+}
+
+// Stop restores the cursor
+func (d *Dashboard) Stop(w io.Writer) {
+	fmt.Fprint(w, ansiShowCursor)
+}
+
+// Add records a sample in the sparkline history
+func (d *Dashboard) Add(sample Sample) {
+	for _, g := range sample.GPUs {
+		h, ok := d.gpus[g.ID]
+		if !ok {
+			h = &gpuHistory{
+				util:  history{size: d.Width},
+				mem:   history{size: d.Width},
+				temp:  history{size: d.Width},
+				power: history{size: d.Width},
+			}
+			d.gpus[g.ID] = h
+		}
+		h.util.add(g.UtilizationPercent)
+		h.mem.add(memoryGiB(g.MemoryUsedBytes))
+		h.temp.add(g.TemperatureCelsius)
+		h.power.add(g.PowerDrawWatts)
+	}
+}
+
+// Render redraws the whole dashboard for the latest sample
+func (d *Dashboard) Render(w io.Writer, sample Sample, interval time.Duration) {
+	var sb strings.Builder
+	sb.WriteString(ansiClear)
+	fmt.Fprintf(&sb, "%s  %s  (every %v, Ctrl-C to quit)\n\n", d.Title, sample.Time.Format("15:04:05"), interval)
+
+	var procs []procRow
+	for _, g := range sample.GPUs {
+		h := d.gpus[g.ID]
+		fmt.Fprintf(&sb, "GPU %d  %s\n", g.ID, g.Name)
+		if h != nil {
+			powerMax := 0.0
+			if g.PowerLimitWatts != nil {
+				powerMax = *g.PowerLimitWatts
+			}
+			fmt.Fprintf(&sb, "  util  %-6s %s\n", FormatPercent(g.UtilizationPercent), Sparkline(h.util.values, 100))
+			// Unified memory reports no total; scale to the largest value seen instead
+			memMax := 0.0
+			if total := memoryGiB(g.MemoryTotalBytes); total != nil {
+				memMax = *total
+			}
+			fmt.Fprintf(&sb, "  mem   %-6s %s  %s\n", FormatPercent(memoryPercent(g)), Sparkline(h.mem.values, memMax),
+				FormatMemory(g.MemoryUsedBytes, g.MemoryTotalBytes))
+			fmt.Fprintf(&sb, "  temp  %-6s %s\n", FormatCelsius(g.TemperatureCelsius), Sparkline(h.temp.values, 100))
+			fmt.Fprintf(&sb, "  power %-6s %s  %s\n", formatWatts(g.PowerDrawWatts), Sparkline(h.power.values, powerMax),
+				FormatPower(g.PowerDrawWatts, g.PowerLimitWatts))
+		}
+		sb.WriteString("\n")
+
+		for _, p := range g.Processes {
+			procs = append(procs, procRow{gpu: g.ID, proc: p})
+		}
+	}
+
+	if len(procs) == 0 {
+		sb.WriteString("No running GPU processes\n")
+	} else {
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "GPU\tPID\tMEMORY\tPROCESS")
+		for _, r := range procs {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", r.gpu, r.proc.PID, FormatBytes(r.proc.MemoryUsedBytes), r.proc.Name)
+		}
+		tw.Flush()
+	}
+
+	io.WriteString(w, sb.String())
+}
+
+type procRow struct {
+	gpu  int
+	proc types.GPUProcess
+}
+
+// memoryPercent returns used memory as a percentage of total, or nil when
+// the total is not reported (GB10 unified memory)
+func memoryPercent(g types.GPUInfo) *float64 {
+	if g.MemoryUsedBytes == nil || g.MemoryTotalBytes == nil || *g.MemoryTotalBytes == 0 {
+		return nil
+	}
+	pct := float64(*g.MemoryUsedBytes) / float64(*g.MemoryTotalBytes) * 100
+	return &pct
+}
+
+// memoryGiB converts a byte count to GiB for sparklines
+func memoryGiB(b *uint64) *float64 {
+	if b == nil {
+		return nil
+	}
+	gib := float64(*b) / (1 << 30)
+	return &gib
+}
+
+func formatWatts(v *float64) string {
+	if v == nil {
+		return notAvailable
+	}
+	return fmt.Sprintf("%.0fW", *v)
+}
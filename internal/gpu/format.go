@@ -0,0 +1,132 @@
+package gpu
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// notAvailable is shown for metrics the driver does not report
+const notAvailable = "N/A"
+
+// boxWidth is the inner width of the FormatGPUStatus box
+const boxWidth = 69
+
+// FormatGPUStatus formats GPU information for display
+func FormatGPUStatus(gpus []types.GPUInfo) string {
+	var sb strings.Builder
+
+	sb.WriteString("┌" + strings.Repeat("─", boxWidth) + "┐\n")
+	boxLine(&sb, "                         DGX GPU Status")
+	sb.WriteString("├" + strings.Repeat("─", boxWidth) + "┤\n")
+
+	for _, gpu := range gpus {
+		boxLine(&sb, fmt.Sprintf(" GPU %d: %s", gpu.ID, gpu.Name))
+		boxLine(&sb, fmt.Sprintf("   Memory: %-25s Util: %-5s Temp: %s",
+			FormatMemory(gpu.MemoryUsedBytes, gpu.MemoryTotalBytes),
+			FormatPercent(gpu.UtilizationPercent), FormatCelsius(gpu.TemperatureCelsius)))
+		boxLine(&sb, fmt.Sprintf("   Power:  %-25s Clocks: %s",
+			FormatPower(gpu.PowerDrawWatts, gpu.PowerLimitWatts),
+			FormatClocks(gpu.GraphicsClockMHz, gpu.MemoryClockMHz)))
+
+		if len(gpu.Processes) > 0 {
+			boxLine(&sb, "   Processes:")
+			for _, proc := range gpu.Processes {
+				procName := proc.Name
+				if len(procName) > 30 {
+					procName = procName[:27] + "..."
+				}
+				boxLine(&sb, fmt.Sprintf("     - PID %-8d %-30s %12s",
+					proc.PID, procName, FormatBytes(proc.MemoryUsedBytes)))
+			}
+		}
+		sb.WriteString("├" + strings.Repeat("─", boxWidth) + "┤\n")
+	}
+
+	sb.WriteString("└" + strings.Repeat("─", boxWidth) + "┘\n")
+
+	return sb.String()
+}
+
+// boxLine writes content padded (or truncated) to the box width
+func boxLine(sb *strings.Builder, content string) {
+	runes := []rune(content)
+	if len(runes) > boxWidth-1 {
+		runes = runes[:boxWidth-1]
+	}
+	sb.WriteString("│" + string(runes) + strings.Repeat(" ", boxWidth-len(runes)) + "│\n")
+}
+
+// FormatBytes formats a byte count using binary units
+func FormatBytes(b *uint64) string {
+	if b == nil {
+		return notAvailable
+	}
+	const unit = 1024
+	if *b < unit {
+		return fmt.Sprintf("%d B", *b)
+	}
+	div, exp := uint64(unit), 0
+	for n := *b / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(*b)/float64(div), "KMGTP"[exp])
+}
+
+// FormatMemory formats used / total memory
+func FormatMemory(used, total *uint64) string {
+	return FormatBytes(used) + " / " + FormatBytes(total)
+}
+
+// FormatPercent formats a percentage
+func FormatPercent(v *float64) string {
+	if v == nil {
+		return notAvailable
+	}
+	return fmt.Sprintf("%.0f%%", *v)
+}
+
+// FormatCelsius formats a temperature
+func FormatCelsius(v *float64) string {
+	if v == nil {
+		return notAvailable
+	}
+	return fmt.Sprintf("%.0f°C", *v)
+}
+
+// FormatPower formats power draw against the power limit
+func FormatPower(draw, limit *float64) string {
+	if draw == nil {
+		return notAvailable
+	}
+	if limit == nil {
+		return fmt.Sprintf("%.1f W", *draw)
+	}
+	return fmt.Sprintf("%.1f W / %.0f W", *draw, *limit)
+}
+
+// FormatClocks formats graphics / memory clocks
+func FormatClocks(graphics, memory *float64) string {
+	format := func(v *float64) string {
+		if v == nil {
+			return notAvailable
+		}
+		return fmt.Sprintf("%.0f", *v)
+	}
+	return fmt.Sprintf("%s / %s MHz", format(graphics), format(memory))
+}
+
+// FormatUptime formats seconds of uptime as days, hours and minutes
+func FormatUptime(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
@@ -0,0 +1,323 @@
+package gpu
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/config"
+)
+
+// Defaults for the local GPU history store
+const (
+	DefaultRetention    = 7 * 24 * time.Hour
+	DefaultRawRetention = 24 * time.Hour
+	DefaultResolution   = time.Minute
+)
+
+// Point is one recorded measurement of one GPU. Downsampled points average
+// Samples raw samples taken within the same bucket.
+type Point struct {
+	Time               time.Time `json:"time"`
+	GPU                int       `json:"gpu"`
+	Samples            int       `json:"samples"`
+	UtilizationPercent *float64  `json:"utilization_percent"`
+	MemoryUsedBytes    *uint64   `json:"memory_used_bytes"`
+	TemperatureCelsius *float64  `json:"temperature_celsius"`
+	PowerDrawWatts     *float64  `json:"power_draw_watts"`
+}
+
+// record is the on-disk form of a Point: one short JSON line per GPU per
+// sample, with memory in MiB and the time in Unix seconds
+type record struct {
+	T int64    `json:"t"`
+	G int      `json:"g"`
+	N int      `json:"n,omitempty"` // Omitted for raw samples
+	U *float64 `json:"u,omitempty"`
+	M *float64 `json:"m,omitempty"`
+	C *float64 `json:"c,omitempty"`
+	P *float64 `json:"p,omitempty"`
+}
+
+func (r record) point() Point {
+	p := Point{
+		Time:               time.Unix(r.T, 0),
+		GPU:                r.G,
+		Samples:            r.N,
+		UtilizationPercent: r.U,
+		TemperatureCelsius: r.C,
+		PowerDrawWatts:     r.P,
+	}
+	if p.Samples == 0 {
+		p.Samples = 1
+	}
+	if r.M != nil {
+		bytes := uint64(*r.M * bytesPerMiB)
+		p.MemoryUsedBytes = &bytes
+	}
+	return p
+}
+
+func newRecord(p Point) record {
+	r := record{
+		T: p.Time.Unix(),
+		G: p.GPU,
+		U: round2(p.UtilizationPercent),
+		C: round2(p.TemperatureCelsius),
+		P: round2(p.PowerDrawWatts),
+	}
+	if p.Samples > 1 {
+		r.N = p.Samples
+	}
+	if p.MemoryUsedBytes != nil {
+		mib := float64(*p.MemoryUsedBytes) / bytesPerMiB
+		r.M = round2(&mib)
+	}
+	return r
+}
+
+// round2 keeps averaged values short on disk
+func round2(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	r := math.Round(*v*100) / 100
+	return &r
+}
+
+// Store is an append-only JSON Lines file of GPU points for one profile.
+// Compact applies retention and averages old points into coarser buckets.
+type Store struct {
+	// Retention is how long points are kept at all
+	Retention time.Duration
+	// RawRetention is how long points are kept at full resolution
+	RawRetention time.Duration
+	// Resolution is the bucket size points are averaged into after RawRetention
+	Resolution time.Duration
+
+	path string
+}
+
+// StorePath returns the history file for a profile
+// (~/.config/dgx/metrics/gpu-<profile>.jsonl)
+func StorePath(profile string) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "metrics", "gpu-"+profile+".jsonl"), nil
+}
+
+// OpenStore returns the history store for a profile with default retention
+func OpenStore(profile string) (*Store, error) {
+	path, err := StorePath(profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	return NewStore(path), nil
+}
+
+// NewStore returns a store backed by path with default retention
+func NewStore(path string) *Store {
+	return &Store{
+		Retention:    DefaultRetention,
+		RawRetention: DefaultRawRetention,
+		Resolution:   DefaultResolution,
+		path:         path,
+	}
+}
+
+// Path returns the file backing the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append records one point per GPU in the sample
+func (s *Store) Append(sample Sample) error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open GPU history: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, g := range sample.GPUs {
+		p := Point{
+			Time:               sample.Time,
+			GPU:                g.ID,
+			Samples:            1,
+			UtilizationPercent: g.UtilizationPercent,
+			MemoryUsedBytes:    g.MemoryUsedBytes,
+			TemperatureCelsius: g.TemperatureCelsius,
+			PowerDrawWatts:     g.PowerDrawWatts,
+		}
+		if err := enc.Encode(newRecord(p)); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write GPU history: %w", err)
+	}
+	return nil
+}
+
+// Query returns the points recorded at or after since, oldest first.
+// Unreadable lines, such as a write cut short by a crash, are skipped.
+func (s *Store) Query(since time.Time) ([]Point, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var points []Point
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.T < since.Unix() {
+			continue
+		}
+		points = append(points, r.point())
+	}
+	return points, scanner.Err()
+}
+
+// Compact drops points older than Retention and averages points older than
+// RawRetention into Resolution buckets, rewriting the file in place
+func (s *Store) Compact(now time.Time) error {
+	points, err := s.Query(now.Add(-s.Retention))
+	if err != nil {
+		return err
+	}
+	points = downsample(points, now.Add(-s.RawRetention), s.Resolution)
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to compact GPU history: %w", err)
+	}
+	if err := writeRecords(f, points); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact GPU history: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// writeRecords encodes points to f and closes it, returning the first error
+func writeRecords(f *os.File, points []Point) error {
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, p := range points {
+		if err := enc.Encode(newRecord(p)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// downsample merges points older than cutoff that share a GPU and a
+// resolution bucket into one point weighted by sample count
+func downsample(points []Point, cutoff time.Time, resolution time.Duration) []Point {
+	type bucketKey struct {
+		gpu  int
+		time int64
+	}
+
+	var result []Point
+	buckets := make(map[bucketKey]int) // -> index into result
+	for _, p := range points {
+		if !p.Time.Before(cutoff) || resolution <= 0 {
+			result = append(result, p)
+			continue
+		}
+		bucket := p.Time.Truncate(resolution)
+		key := bucketKey{gpu: p.GPU, time: bucket.Unix()}
+		i, ok := buckets[key]
+		if !ok {
+			p.Time = bucket
+			buckets[key] = len(result)
+			result = append(result, p)
+			continue
+		}
+		result[i] = mergePoints(result[i], p)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].Time.Equal(result[j].Time) {
+			return result[i].Time.Before(result[j].Time)
+		}
+		return result[i].GPU < result[j].GPU
+	})
+	return result
+}
+
+// mergePoints averages two points, weighting each by its sample count
+func mergePoints(a, b Point) Point {
+	merged := Point{Time: a.Time, GPU: a.GPU, Samples: a.Samples + b.Samples}
+	merged.UtilizationPercent = mergeValue(a.UtilizationPercent, a.Samples, b.UtilizationPercent, b.Samples)
+	merged.TemperatureCelsius = mergeValue(a.TemperatureCelsius, a.Samples, b.TemperatureCelsius, b.Samples)
+	merged.PowerDrawWatts = mergeValue(a.PowerDrawWatts, a.Samples, b.PowerDrawWatts, b.Samples)
+	if mem := mergeValue(bytesValue(a.MemoryUsedBytes), a.Samples, bytesValue(b.MemoryUsedBytes), b.Samples); mem != nil {
+		bytes := uint64(*mem)
+		merged.MemoryUsedBytes = &bytes
+	}
+	return merged
+}
+
+func mergeValue(a *float64, na int, b *float64, nb int) *float64 {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	v := (*a*float64(na) + *b*float64(nb)) / float64(na+nb)
+	return &v
+}
+
+func bytesValue(b *uint64) *float64 {
+	if b == nil {
+		return nil
+	}
+	v := float64(*b)
+	return &v
+}
+
+// ParseAge parses a duration such as "6h", "90m" or "7d"
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 30m, 6h or 7d)", s)
+	}
+	return d, nil
+}
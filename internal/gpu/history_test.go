@@ -0,0 +1,159 @@
+package gpu
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordFixture replays recorded watch output into a store, one sample a minute
+func recordFixture(t *testing.T, store *Store, name string, start time.Time) {
+	t.Helper()
+	parser := &frameParser{}
+	n := 0
+	for _, line := range strings.Split(readFixture(t, name), "\n") {
+		sample, ok := parser.feed(line)
+		if !ok {
+			continue
+		}
+		sample.Time = start.Add(time.Duration(n) * time.Minute)
+		if err := store.Append(sample); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+		n++
+	}
+}
+
+func findMetric(t *testing.T, s GPUSummary, name string) MetricSummary {
+	t.Helper()
+	for _, m := range s.Metrics {
+		if m.Metric == name {
+			return m
+		}
+	}
+	t.Fatalf("metric %s missing from %+v", name, s.Metrics)
+	return MetricSummary{}
+}
+
+func TestHistorySummary(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "gpu.jsonl"))
+	start := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	recordFixture(t, store, "record-gb10.txt", start)
+
+	points, err := store.Query(start)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(points) != 5 {
+		t.Fatalf("expected 5 points, got %d", len(points))
+	}
+
+	summaries := Summarize(points)
+	if len(summaries) != 1 || summaries[0].Samples != 5 {
+		t.Fatalf("unexpected summaries %+v", summaries)
+	}
+
+	util := findMetric(t, summaries[0], "utilization_percent")
+	if util.Min != 10 || util.Max != 100 || util.Avg != 40 || util.P95 != 100 {
+		t.Fatalf("unexpected utilization summary %+v", util)
+	}
+	mem := findMetric(t, summaries[0], "memory_used_bytes")
+	if mem.Min != 40960*bytesPerMiB || mem.Max != 61440*bytesPerMiB {
+		t.Fatalf("unexpected memory summary %+v", mem)
+	}
+
+	// Queries start at since
+	points, _ = store.Query(start.Add(3 * time.Minute))
+	if len(points) != 2 {
+		t.Fatalf("expected 2 points since 22:03, got %d", len(points))
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "gpu.jsonl"))
+	store.Retention = 48 * time.Hour
+	store.RawRetention = time.Hour
+	store.Resolution = 10 * time.Minute
+
+	now := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	recordFixture(t, store, "record-gb10.txt", now.Add(-72*time.Hour))   // expired
+	recordFixture(t, store, "record-gb10.txt", now.Add(-5*time.Hour))    // downsampled
+	recordFixture(t, store, "record-gb10.txt", now.Add(-10*time.Minute)) // kept raw
+
+	if err := store.Compact(now); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	points, err := store.Query(time.Time{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(points) != 6 {
+		t.Fatalf("expected 1 downsampled + 5 raw points, got %d", len(points))
+	}
+
+	merged := points[0]
+	if merged.Samples != 5 || !merged.Time.Equal(now.Add(-5*time.Hour)) {
+		t.Fatalf("unexpected downsampled point %+v", merged)
+	}
+	if merged.UtilizationPercent == nil || *merged.UtilizationPercent != 40 {
+		t.Fatalf("expected averaged utilization 40, got %v", merged.UtilizationPercent)
+	}
+
+	// Averages are weighted by sample count
+	util := findMetric(t, Summarize(points)[0], "utilization_percent")
+	if util.Avg != 40 || util.P95 != 100 {
+		t.Fatalf("unexpected weighted summary %+v", util)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	util, temp := 45.0, 61.0
+	points := []Point{{
+		Time:               time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC),
+		GPU:                0,
+		Samples:            1,
+		UtilizationPercent: &util,
+		TemperatureCelsius: &temp,
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, points); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := "time,gpu,samples,utilization_percent,memory_used_bytes,temperature_celsius,power_draw_watts\n" +
+		"2026-03-01T22:00:00Z,0,1,45,,61,\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{"6h": 6 * time.Hour, "90m": 90 * time.Minute, "7d": 7 * 24 * time.Hour} {
+		got, err := ParseAge(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseAge("soon"); err == nil {
+		t.Fatalf("expected error for invalid duration")
+	}
+}
+
+func TestWriteRecordsReportsEncodeErrors(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "gpu.jsonl.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nan := math.NaN()
+	points := []Point{{Time: time.Unix(0, 0), UtilizationPercent: &nan}}
+	if err := writeRecords(f, points); err == nil {
+		t.Fatal("expected an error encoding NaN")
+	}
+	if err := f.Close(); err == nil {
+		t.Fatal("expected writeRecords to close the file on error")
+	}
+}
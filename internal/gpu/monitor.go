@@ -2,7 +2,6 @@ package gpu
 
 import (
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -22,30 +21,19 @@ func NewMonitor(sshClient *ssh.Client) *Monitor {
 	}
 }
 
-// GetStatus retrieves GPU status information
+// gpuQueryFields are the nvidia-smi --query-gpu fields parsed by parseNvidiaSMI, in order
+const gpuQueryFields = "index,name,memory.used,memory.total,utilization.gpu,temperature.gpu,power.draw,power.limit,clocks.gr,clocks.mem"
+
+// bytesPerMiB converts nvidia-smi memory values (MiB with nounits) to bytes
+const bytesPerMiB = 1024 * 1024
+
+// GetStatus retrieves GPU status and processes in one SSH round trip
 func (m *Monitor) GetStatus() ([]types.GPUInfo, error) {
-	// Run nvidia-smi command
-	output, err := m.sshClient.Execute("nvidia-smi --query-gpu=index,name,memory.used,memory.total,utilization.gpu,temperature.gpu --format=csv,noheader,nounits")
+	output, err := m.sshClient.Execute(collectScript(gpuSections))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query GPU: %w", err)
 	}
-
-	gpus, err := m.parseNvidiaSMI(output)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get processes for each GPU
-	for i := range gpus {
-		processes, err := m.getGPUProcesses(gpus[i].ID)
-		if err != nil {
-			fmt.Printf("Warning: Failed to get processes for GPU %d: %v\n", gpus[i].ID, err)
-		} else {
-			gpus[i].Processes = processes
-		}
-	}
-
-	return gpus, nil
+	return parseGPUSections(splitSections(output))
 }
 
 // GetStatusText retrieves formatted GPU status as plain text
@@ -57,8 +45,8 @@ func (m *Monitor) GetStatusText() (string, error) {
 	return output, nil
 }
 
-// parseNvidiaSMI parses nvidia-smi CSV output
-func (m *Monitor) parseNvidiaSMI(output string) ([]types.GPUInfo, error) {
+// parseNvidiaSMI parses nvidia-smi CSV output for gpuQueryFields
+func parseNvidiaSMI(output string) ([]types.GPUInfo, error) {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
 	gpus := make([]types.GPUInfo, 0, len(lines))
 
@@ -72,19 +60,29 @@ func (m *Monitor) parseNvidiaSMI(output string) ([]types.GPUInfo, error) {
 		if len(fields) < 6 {
 			continue
 		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
 
-		id, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		id, err := strconv.Atoi(fields[0])
 		if err != nil {
 			continue
 		}
 
 		gpu := types.GPUInfo{
-			ID:          id,
-			Name:        strings.TrimSpace(fields[1]),
-			MemoryUsed:  strings.TrimSpace(fields[2]) + " MiB",
-			MemoryTotal: strings.TrimSpace(fields[3]) + " MiB",
-			Utilization: strings.TrimSpace(fields[4]) + "%",
-			Temperature: strings.TrimSpace(fields[5]) + "°C",
+			ID:                 id,
+			Name:               fields[1],
+			MemoryUsedBytes:    parseMiB(fields[2]),
+			MemoryTotalBytes:   parseMiB(fields[3]),
+			UtilizationPercent: parseMetric(fields[4]),
+			TemperatureCelsius: parseMetric(fields[5]),
+		}
+		// Power and clock fields are optional so older query strings still parse
+		if len(fields) >= 10 {
+			gpu.PowerDrawWatts = parseMetric(fields[6])
+			gpu.PowerLimitWatts = parseMetric(fields[7])
+			gpu.GraphicsClockMHz = parseMetric(fields[8])
+			gpu.MemoryClockMHz = parseMetric(fields[9])
 		}
 
 		gpus = append(gpus, gpu)
@@ -93,14 +91,8 @@ func (m *Monitor) parseNvidiaSMI(output string) ([]types.GPUInfo, error) {
 	return gpus, nil
 }
 
-// getGPUProcesses retrieves processes running on a specific GPU
-func (m *Monitor) getGPUProcesses(gpuID int) ([]types.GPUProcess, error) {
-	cmd := fmt.Sprintf("nvidia-smi --query-compute-apps=pid,process_name,used_memory --format=csv,noheader,nounits --id=%d", gpuID)
-	output, err := m.sshClient.Execute(cmd)
-	if err != nil {
-		return nil, err
-	}
-
+// parseComputeApps parses nvidia-smi --query-compute-apps=pid,process_name,used_memory output
+func parseComputeApps(output string) []types.GPUProcess {
 	lines := strings.Split(strings.TrimSpace(output), "\n")
 	processes := make([]types.GPUProcess, 0)
 
@@ -121,89 +113,63 @@ func (m *Monitor) getGPUProcesses(gpuID int) ([]types.GPUProcess, error) {
 		}
 
 		process := types.GPUProcess{
-			PID:         pid,
-			Name:        strings.TrimSpace(fields[1]),
-			MemoryUsage: strings.TrimSpace(fields[2]) + " MiB",
+			PID:             pid,
+			Name:            strings.TrimSpace(fields[1]),
+			MemoryUsedBytes: parseMiB(strings.TrimSpace(fields[2])),
 		}
 
 		processes = append(processes, process)
 	}
 
-	return processes, nil
+	return processes
 }
 
-// GetGPUCount returns the number of GPUs
-func (m *Monitor) GetGPUCount() (int, error) {
-	output, err := m.sshClient.Execute("nvidia-smi --query-gpu=count --format=csv,noheader")
-	if err != nil {
-		return 0, err
+// parseMetric parses a numeric nvidia-smi value, returning nil for values
+// the driver does not report ([N/A], [Not Supported], [Unknown Error], ...)
+func parseMetric(value string) *float64 {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "[") || strings.EqualFold(value, "N/A") {
+		return nil
+	}
+	// Tolerate units when nounits was not passed (e.g. "61 C", "45 %")
+	if fields := strings.Fields(value); len(fields) > 1 {
+		value = fields[0]
 	}
+	value = strings.TrimSuffix(value, "%")
 
-	count, err := strconv.Atoi(strings.TrimSpace(output))
+	v, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		// If that fails, try counting the number of GPUs
-		gpus, err := m.GetStatus()
-		if err != nil {
-			return 0, err
-		}
-		return len(gpus), nil
+		return nil
 	}
-
-	return count, nil
+	return &v
 }
 
-// WatchGPU monitors GPU usage in real-time
-func (m *Monitor) WatchGPU(interval int) error {
-	// Run nvidia-smi in watch mode (dmon for device monitoring)
-	cmd := fmt.Sprintf("watch -n %d nvidia-smi", interval)
-	output, err := m.sshClient.Execute(cmd)
-	if err != nil {
-		return fmt.Errorf("failed to watch GPU: %w", err)
+// parseMiB parses an nvidia-smi memory value in MiB into bytes
+func parseMiB(value string) *uint64 {
+	v := parseMetric(value)
+	if v == nil || *v < 0 {
+		return nil
 	}
-
-	fmt.Println(output)
-	return nil
+	b := uint64(*v * bytesPerMiB)
+	return &b
 }
 
-// FormatGPUStatus formats GPU information for display
-func FormatGPUStatus(gpus []types.GPUInfo) string {
-	var sb strings.Builder
-
-	sb.WriteString("┌─────────────────────────────────────────────────────────────────────┐\n")
-	sb.WriteString("│                         DGX GPU Status                              │\n")
-	sb.WriteString("├─────────────────────────────────────────────────────────────────────┤\n")
-
-	for _, gpu := range gpus {
-		sb.WriteString(fmt.Sprintf("│ GPU %d: %-55s │\n", gpu.ID, gpu.Name))
-		sb.WriteString(fmt.Sprintf("│   Memory: %s / %s (Util: %s)     Temp: %s       │\n",
-			gpu.MemoryUsed, gpu.MemoryTotal, gpu.Utilization, gpu.Temperature))
-
-		if len(gpu.Processes) > 0 {
-			sb.WriteString("│   Processes:                                                       │\n")
-			for _, proc := range gpu.Processes {
-				procName := proc.Name
-				if len(procName) > 30 {
-					procName = procName[:27] + "..."
-				}
-				sb.WriteString(fmt.Sprintf("│     - PID %-6d %-30s %10s │\n",
-					proc.PID, procName, proc.MemoryUsage))
-			}
-		}
-		sb.WriteString("├─────────────────────────────────────────────────────────────────────┤\n")
+// GetGPUCount returns the number of GPUs
+func (m *Monitor) GetGPUCount() (int, error) {
+	output, err := m.sshClient.Execute("nvidia-smi --query-gpu=count --format=csv,noheader")
+	if err != nil {
+		return 0, err
 	}
-
-	sb.WriteString("└─────────────────────────────────────────────────────────────────────┘\n")
-
-	return sb.String()
+	return parseGPUCount(output)
 }
 
-// ParseMemoryMiB extracts memory value in MiB from string like "1024 MiB"
-func ParseMemoryMiB(memStr string) int {
-	re := regexp.MustCompile(`(\d+)`)
-	matches := re.FindStringSubmatch(memStr)
-	if len(matches) > 1 {
-		val, _ := strconv.Atoi(matches[1])
-		return val
+// parseGPUCount parses --query-gpu=count output, which repeats the count
+// once per GPU
+func parseGPUCount(output string) (int, error) {
+	first, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	count, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected nvidia-smi count output: %q", strings.TrimSpace(output))
 	}
-	return 0
+	return count, nil
 }
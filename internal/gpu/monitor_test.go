@@ -0,0 +1,90 @@
+package gpu
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readFixture returns a recorded nvidia-smi or watch output from testdata
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return string(data)
+}
+
+func TestParseNvidiaSMIGB10(t *testing.T) {
+	gpus, err := parseNvidiaSMI(readFixture(t, "query-gpu-gb10.csv"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(gpus) != 1 {
+		t.Fatalf("expected 1 GPU, got %d", len(gpus))
+	}
+
+	g := gpus[0]
+	if g.Name != "NVIDIA GB10" {
+		t.Fatalf("unexpected name %q", g.Name)
+	}
+	if g.MemoryUsedBytes == nil || *g.MemoryUsedBytes != 10240*bytesPerMiB {
+		t.Fatalf("unexpected memory used %v", g.MemoryUsedBytes)
+	}
+	if g.MemoryTotalBytes != nil || g.PowerLimitWatts != nil || g.MemoryClockMHz != nil {
+		t.Fatalf("expected [N/A]/[Not Supported] fields to be nil: %+v", g)
+	}
+	if g.UtilizationPercent == nil || *g.UtilizationPercent != 45 {
+		t.Fatalf("unexpected utilization %v", g.UtilizationPercent)
+	}
+	if g.TemperatureCelsius == nil || *g.TemperatureCelsius != 61 {
+		t.Fatalf("unexpected temperature %v", g.TemperatureCelsius)
+	}
+	if g.PowerDrawWatts == nil || *g.PowerDrawWatts != 12.34 {
+		t.Fatalf("unexpected power draw %v", g.PowerDrawWatts)
+	}
+
+	status := FormatGPUStatus(gpus)
+	if !strings.Contains(status, "10.0 GiB / N/A") {
+		t.Fatalf("expected N/A memory total in:\n%s", status)
+	}
+}
+
+func TestParseNvidiaSMIMultiGPU(t *testing.T) {
+	gpus, err := parseNvidiaSMI(readFixture(t, "query-gpu-h100.csv"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(gpus) != 2 || gpus[1].ID != 1 {
+		t.Fatalf("unexpected GPUs %+v", gpus)
+	}
+	if *gpus[1].MemoryTotalBytes != 81559*bytesPerMiB || *gpus[1].GraphicsClockMHz != 1980 {
+		t.Fatalf("unexpected GPU 1 metrics %+v", gpus[1])
+	}
+}
+
+func TestParseComputeApps(t *testing.T) {
+	procs := parseComputeApps(readFixture(t, "compute-apps.csv"))
+	if len(procs) != 2 {
+		t.Fatalf("expected 2 processes, got %d", len(procs))
+	}
+	if procs[0].PID != 4242 || *procs[0].MemoryUsedBytes != 10240*bytesPerMiB {
+		t.Fatalf("unexpected process %+v", procs[0])
+	}
+	if procs[1].MemoryUsedBytes != nil {
+		t.Fatalf("expected [N/A] memory to be nil")
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	for _, in := range []string{"[N/A]", "[Not Supported]", "N/A", "", "[Unknown Error]"} {
+		if v := parseMetric(in); v != nil {
+			t.Fatalf("parseMetric(%q) = %v, want nil", in, *v)
+		}
+	}
+	if v := parseMetric("61 C"); v == nil || *v != 61 {
+		t.Fatalf("expected units to be tolerated")
+	}
+}
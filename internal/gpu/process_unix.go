@@ -0,0 +1,28 @@
+//go:build !windows
+
+package gpu
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachedProcAttr puts the recorder in its own session so closing the
+// terminal does not stop it
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether pid exists, using the null signal
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// terminate asks the recorder to flush and exit
+func terminate(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
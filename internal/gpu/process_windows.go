@@ -0,0 +1,32 @@
+//go:build windows
+
+package gpu
+
+import (
+	"os"
+	"syscall"
+)
+
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the recorder without a console window
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
+
+// processAlive reports whether pid exists; FindProcess opens a handle
+// and fails for exited processes on Windows
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// terminate kills the recorder; Windows has no SIGTERM. Each sample is
+// appended as it arrives, so nothing buffered is lost.
+func terminate(process *os.Process) error {
+	return process.Kill()
+}
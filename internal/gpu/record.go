@@ -0,0 +1,221 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/config"
+)
+
+// Recorder timing
+const (
+	// compactInterval is how often the recorder applies retention
+	compactInterval = time.Hour
+	// minStallTimeout bounds how long the recorder waits for a sample
+	// before assuming the SSH connection is dead
+	minStallTimeout = 30 * time.Second
+	maxRetryDelay   = time.Minute
+)
+
+// Record samples GPUs every interval and appends them to store until ctx
+// is cancelled. Dropped or stalled SSH sessions are retried with backoff,
+// so a recorder left running overnight survives network blips.
+func (m *Monitor) Record(ctx context.Context, store *Store, interval time.Duration) error {
+	if err := store.Compact(time.Now()); err != nil {
+		return err
+	}
+	lastCompact := time.Now()
+
+	delay := time.Duration(0)
+	for ctx.Err() == nil {
+		recorded, err := m.recordSession(ctx, store, interval, &lastCompact)
+		if ctx.Err() != nil {
+			break
+		}
+		if recorded > 0 {
+			delay = 0
+		}
+		delay = nextRetryDelay(delay)
+		fmt.Printf("%s Sampling stopped after %d samples: %v; retrying in %v\n",
+			time.Now().Format(time.RFC3339), recorded, err, delay)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+// recordSession records samples from one watch session until it ends
+func (m *Monitor) recordSession(ctx context.Context, store *Store, interval time.Duration, lastCompact *time.Time) (int, error) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stallTimeout := 3 * interval
+	if stallTimeout < minStallTimeout {
+		stallTimeout = minStallTimeout
+	}
+	stall := time.NewTimer(stallTimeout)
+	defer stall.Stop()
+
+	samples, errs := m.Watch(watchCtx, interval)
+	recorded := 0
+	for {
+		select {
+		case sample, ok := <-samples:
+			if !ok {
+				select {
+				case err := <-errs:
+					return recorded, err
+				default:
+					return recorded, fmt.Errorf("GPU watch session ended")
+				}
+			}
+			if err := store.Append(sample); err != nil {
+				return recorded, err
+			}
+			recorded++
+
+			if time.Since(*lastCompact) >= compactInterval {
+				if err := store.Compact(time.Now()); err != nil {
+					fmt.Printf("%s Compaction failed: %v\n", time.Now().Format(time.RFC3339), err)
+				}
+				*lastCompact = time.Now()
+			}
+			stall.Reset(stallTimeout)
+
+		case <-stall.C:
+			// A dead TCP connection never delivers an error; drop it so the
+			// next session reconnects
+			cancel()
+			m.sshClient.Close()
+			return recorded, fmt.Errorf("no samples for %v", stallTimeout)
+		}
+	}
+}
+
+func nextRetryDelay(current time.Duration) time.Duration {
+	if current <= 0 {
+		return time.Second
+	}
+	if current*2 > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return current * 2
+}
+
+// RecorderPaths holds the PID file and log of a profile's background recorder
+type RecorderPaths struct {
+	PID string
+	Log string
+}
+
+// PathsForRecorder returns the recorder files for a profile, kept next to
+// the tunnel daemon's in ~/.config/dgx/run
+func PathsForRecorder(profile string) (RecorderPaths, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return RecorderPaths{}, err
+	}
+	dir = filepath.Join(dir, "run")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return RecorderPaths{}, fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	base := filepath.Join(dir, "gpu-record-"+profile)
+	return RecorderPaths{PID: base + ".pid", Log: base + ".log"}, nil
+}
+
+// RecorderPID returns the PID of the profile's running recorder, or 0
+func RecorderPID(profile string) int {
+	paths, err := PathsForRecorder(profile)
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(paths.PID)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
+
+// WritePIDFile marks the current process as the profile's recorder and
+// returns a function that removes the mark
+func WritePIDFile(profile string) (func(), error) {
+	paths, err := PathsForRecorder(profile)
+	if err != nil {
+		return nil, err
+	}
+	if pid := RecorderPID(profile); pid != 0 && pid != os.Getpid() {
+		return nil, fmt.Errorf("GPU recorder for profile %s is already running (PID %d)", profile, pid)
+	}
+	if err := os.WriteFile(paths.PID, []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return func() { os.Remove(paths.PID) }, nil
+}
+
+// StartRecorder re-runs dgx with args in a detached process that logs to
+// the recorder log, and returns its PID once it has survived startup
+func StartRecorder(profile string, args []string) (int, error) {
+	if pid := RecorderPID(profile); pid != 0 {
+		return 0, fmt.Errorf("GPU recorder for profile %s is already running (PID %d)", profile, pid)
+	}
+	paths, err := PathsForRecorder(profile)
+	if err != nil {
+		return 0, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate dgx binary: %w", err)
+	}
+	logFile, err := os.OpenFile(paths.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open recorder log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, append([]string{"--profile", profile}, args...)...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start GPU recorder: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case <-exited:
+		return 0, fmt.Errorf("GPU recorder exited during startup; see %s", paths.Log)
+	case <-time.After(2 * time.Second):
+	}
+	return cmd.Process.Pid, nil
+}
+
+// StopRecorder terminates the profile's running recorder
+func StopRecorder(profile string) (int, error) {
+	pid := RecorderPID(profile)
+	if pid == 0 {
+		return 0, fmt.Errorf("no GPU recorder is running for profile %s", profile)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find process %d: %w", pid, err)
+	}
+	if err := terminate(process); err != nil {
+		return 0, fmt.Errorf("failed to stop GPU recorder (PID %d): %w", pid, err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,204 @@
+package gpu
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyMetric describes one metric summarized by 'dgx gpu history'
+type historyMetric struct {
+	name   string
+	label  string
+	value  func(Point) *float64
+	format func(float64) string
+}
+
+var historyMetrics = []historyMetric{
+	{
+		name:   "utilization_percent",
+		label:  "utilization",
+		value:  func(p Point) *float64 { return p.UtilizationPercent },
+		format: func(v float64) string { return FormatPercent(&v) },
+	},
+	{
+		name:   "memory_used_bytes",
+		label:  "memory",
+		value:  func(p Point) *float64 { return bytesValue(p.MemoryUsedBytes) },
+		format: func(v float64) string { b := uint64(v); return FormatBytes(&b) },
+	},
+	{
+		name:   "temperature_celsius",
+		label:  "temperature",
+		value:  func(p Point) *float64 { return p.TemperatureCelsius },
+		format: func(v float64) string { return FormatCelsius(&v) },
+	},
+	{
+		name:   "power_draw_watts",
+		label:  "power",
+		value:  func(p Point) *float64 { return p.PowerDrawWatts },
+		format: func(v float64) string { return FormatPower(&v, nil) },
+	},
+}
+
+// MetricSummary holds the distribution of one metric over a time range
+type MetricSummary struct {
+	Metric string  `json:"metric"`
+	Min    float64 `json:"min"`
+	Avg    float64 `json:"avg"`
+	Max    float64 `json:"max"`
+	P95    float64 `json:"p95"`
+}
+
+// GPUSummary summarizes the recorded history of one GPU
+type GPUSummary struct {
+	GPU     int             `json:"gpu"`
+	Samples int             `json:"samples"`
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Metrics []MetricSummary `json:"metrics"`
+}
+
+// weighted is a value standing in for count raw samples
+type weighted struct {
+	value float64
+	count int
+}
+
+// Summarize computes min/avg/max/p95 per metric for each GPU. Downsampled
+// points count as many samples as they average; min and max of downsampled
+// ranges are therefore bucket averages.
+func Summarize(points []Point) []GPUSummary {
+	byGPU := make(map[int][]Point)
+	var ids []int
+	for _, p := range points {
+		if _, ok := byGPU[p.GPU]; !ok {
+			ids = append(ids, p.GPU)
+		}
+		byGPU[p.GPU] = append(byGPU[p.GPU], p)
+	}
+	sort.Ints(ids)
+
+	summaries := make([]GPUSummary, 0, len(ids))
+	for _, id := range ids {
+		gpuPoints := byGPU[id]
+		s := GPUSummary{GPU: id, From: gpuPoints[0].Time, To: gpuPoints[0].Time}
+		for _, p := range gpuPoints {
+			s.Samples += p.Samples
+			if p.Time.Before(s.From) {
+				s.From = p.Time
+			}
+			if p.Time.After(s.To) {
+				s.To = p.Time
+			}
+		}
+
+		for _, m := range historyMetrics {
+			var values []weighted
+			for _, p := range gpuPoints {
+				if v := m.value(p); v != nil {
+					values = append(values, weighted{value: *v, count: p.Samples})
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			s.Metrics = append(s.Metrics, summarizeValues(m.name, values))
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+func summarizeValues(name string, values []weighted) MetricSummary {
+	sort.Slice(values, func(i, j int) bool { return values[i].value < values[j].value })
+
+	total, sum := 0, 0.0
+	for _, v := range values {
+		total += v.count
+		sum += v.value * float64(v.count)
+	}
+
+	return MetricSummary{
+		Metric: name,
+		Min:    values[0].value,
+		Avg:    sum / float64(total),
+		Max:    values[len(values)-1].value,
+		P95:    percentile(values, total, 0.95),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted weighted values
+func percentile(sorted []weighted, total int, q float64) float64 {
+	rank := int(math.Ceil(q * float64(total)))
+	seen := 0
+	for _, v := range sorted {
+		seen += v.count
+		if seen >= rank {
+			return v.value
+		}
+	}
+	return sorted[len(sorted)-1].value
+}
+
+// FormatHistory renders summaries as one table per GPU
+func FormatHistory(summaries []GPUSummary) string {
+	var sb strings.Builder
+	for i, s := range summaries {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "GPU %d  %d samples  %s -> %s\n", s.GPU, s.Samples,
+			s.From.Local().Format("2006-01-02 15:04"), s.To.Local().Format("2006-01-02 15:04"))
+
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  METRIC\tMIN\tAVG\tMAX\tP95")
+		for _, ms := range s.Metrics {
+			m := lookupHistoryMetric(ms.Metric)
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", m.label,
+				m.format(ms.Min), m.format(ms.Avg), m.format(ms.Max), m.format(ms.P95))
+		}
+		tw.Flush()
+	}
+	return sb.String()
+}
+
+func lookupHistoryMetric(name string) historyMetric {
+	for _, m := range historyMetrics {
+		if m.name == name {
+			return m
+		}
+	}
+	return historyMetric{name: name, label: name, format: func(v float64) string { return fmt.Sprint(v) }}
+}
+
+// WriteCSV exports points with one row per GPU per sample. Metrics the
+// driver did not report are left empty.
+func WriteCSV(w io.Writer, points []Point) error {
+	cw := csv.NewWriter(w)
+	header := []string{"time", "gpu", "samples"}
+	for _, m := range historyMetrics {
+		header = append(header, m.name)
+	}
+	cw.Write(header)
+
+	for _, p := range points {
+		row := []string{p.Time.UTC().Format(time.RFC3339), strconv.Itoa(p.GPU), strconv.Itoa(p.Samples)}
+		for _, m := range historyMetrics {
+			cell := ""
+			if v := m.value(p); v != nil {
+				cell = strconv.FormatFloat(*v, 'f', -1, 64)
+			}
+			row = append(row, cell)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
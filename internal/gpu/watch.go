@@ -0,0 +1,117 @@
+package gpu
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Frame markers emitted by the watch loop on the DGX
+const (
+	markerGPU  = "@@gpu"
+	markerApps = "@@apps"
+	markerEnd  = "@@end"
+)
+
+// Sample is one snapshot of every GPU, with processes attached
+type Sample struct {
+	Time time.Time       `json:"time"`
+	GPUs []types.GPUInfo `json:"gpus"`
+}
+
+// watchCommand builds a shell loop that prints GPU metrics and compute apps
+// between frame markers every interval. A loop is used instead of
+// nvidia-smi -lms because -lms cannot report the process list alongside
+// the metrics, and empty process lists would be indistinguishable from gaps.
+func watchCommand(interval time.Duration) string {
+	return fmt.Sprintf(`while :; do
+echo %s; nvidia-smi --query-gpu=uuid,%s --format=csv,noheader,nounits
+echo %s; nvidia-smi --query-compute-apps=gpu_uuid,pid,process_name,used_memory --format=csv,noheader,nounits
+echo %s; sleep %.3f
+done`, markerGPU, gpuQueryFields, markerApps, markerEnd, interval.Seconds())
+}
+
+// Watch streams samples every interval over a single SSH session until ctx
+// is cancelled or the remote command exits. Samples are sent on the returned
+// channel, which is closed when watching stops; the error channel receives
+// at most one error.
+func (m *Monitor) Watch(ctx context.Context, interval time.Duration) (<-chan Sample, <-chan error) {
+	samples := make(chan Sample)
+	errs := make(chan error, 1)
+
+	reader, writer := io.Pipe()
+	go func() {
+		var stderr strings.Builder
+		err := m.sshClient.ExecuteStreamContext(ctx, watchCommand(interval), writer, &stderr)
+		if err != nil && ctx.Err() == nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			errs <- fmt.Errorf("GPU watch failed: %w", err)
+		}
+		writer.Close()
+	}()
+
+	go func() {
+		defer close(samples)
+		defer reader.Close()
+
+		parser := &frameParser{}
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			sample, ok := parser.feed(scanner.Text())
+			if !ok {
+				continue
+			}
+			select {
+			case samples <- sample:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return samples, errs
+}
+
+// frameParser incrementally assembles Samples from watch loop output
+type frameParser struct {
+	section string
+	gpus    strings.Builder
+	apps    strings.Builder
+}
+
+// feed consumes one line and returns a Sample when a frame completes
+func (p *frameParser) feed(line string) (Sample, bool) {
+	line = strings.TrimSpace(line)
+	switch line {
+	case markerGPU:
+		p.section = markerGPU
+		p.gpus.Reset()
+		p.apps.Reset()
+	case markerApps:
+		if p.section == markerGPU {
+			p.section = markerApps
+		}
+	case markerEnd:
+		if p.section != markerApps {
+			p.section = ""
+			return Sample{}, false
+		}
+		p.section = ""
+		return Sample{Time: time.Now(), GPUs: joinGPUs(p.gpus.String(), p.apps.String())}, true
+	default:
+		switch p.section {
+		case markerGPU:
+			p.gpus.WriteString(line + "\n")
+		case markerApps:
+			p.apps.WriteString(line + "\n")
+		}
+	}
+	return Sample{}, false
+}
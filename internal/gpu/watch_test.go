@@ -0,0 +1,66 @@
+package gpu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrameParser(t *testing.T) {
+	parser := &frameParser{}
+	var samples []Sample
+	for _, line := range strings.Split(readFixture(t, "watch-frames.txt"), "\n") {
+		if sample, ok := parser.feed(line); ok {
+			samples = append(samples, sample)
+		}
+	}
+
+	if len(samples) != 2 {
+		t.Fatalf("expected 2 samples, got %d", len(samples))
+	}
+
+	first := samples[0]
+	if len(first.GPUs) != 2 {
+		t.Fatalf("expected 2 GPUs, got %d", len(first.GPUs))
+	}
+	if first.GPUs[0].ID != 0 || first.GPUs[1].ID != 1 {
+		t.Fatalf("unexpected GPU order %d, %d", first.GPUs[0].ID, first.GPUs[1].ID)
+	}
+	if len(first.GPUs[0].Processes) != 1 || first.GPUs[0].Processes[0].PID != 4242 {
+		t.Fatalf("expected vllm on GPU 0, got %+v", first.GPUs[0].Processes)
+	}
+	if len(first.GPUs[1].Processes) != 1 || first.GPUs[1].Processes[0].PID != 5120 {
+		t.Fatalf("expected python3 on GPU 1, got %+v", first.GPUs[1].Processes)
+	}
+
+	second := samples[1]
+	if u := second.GPUs[0].UtilizationPercent; u == nil || *u != 91 {
+		t.Fatalf("unexpected utilization %v", u)
+	}
+	for _, g := range second.GPUs {
+		if len(g.Processes) != 0 {
+			t.Fatalf("expected no processes in second frame, got %+v", g.Processes)
+		}
+	}
+}
+
+func TestFrameParserSkipsPartialFrame(t *testing.T) {
+	parser := &frameParser{}
+	// Output joined mid-frame, before the first @@gpu marker
+	for _, line := range []string{"GPU-1111, 4242, vllm, 38912", "@@end"} {
+		if _, ok := parser.feed(line); ok {
+			t.Fatalf("expected partial frame to be skipped")
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]float64{0, 50, 100}, 100); got != "▁▄█" {
+		t.Fatalf("unexpected sparkline %q", got)
+	}
+	if got := Sparkline([]float64{1, 2, 4}, 0); got != "▂▄█" {
+		t.Fatalf("unexpected auto-scaled sparkline %q", got)
+	}
+	if got := Sparkline([]float64{200}, 100); got != "█" {
+		t.Fatalf("expected values above max to clamp, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Check statuses, from best to worst
+const (
+	StatusOK   = "ok"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+var severity = map[string]int{StatusOK: 0, StatusWarn: 1, StatusFail: 2}
+
+// Input is what probes evaluate: one snapshot of the DGX and the local
+// tunnels reconciled with the saved ones
+type Input struct {
+	Snapshot gpu.Snapshot
+	Tunnels  []types.Tunnel
+}
+
+// Probe is one check in the status report. Remote probes list the extra
+// commands they need, so all probes share a single SSH round trip.
+type Probe struct {
+	Name string
+	// Remote probes need the DGX and are skipped when it is unreachable
+	Remote   bool
+	Sections []gpu.Section
+	Check    func(in Input) (status, summary string)
+}
+
+// Sections returns the extra commands to collect for the probes
+func Sections(probes []Probe) []gpu.Section {
+	var sections []gpu.Section
+	for _, p := range probes {
+		sections = append(sections, p.Sections...)
+	}
+	return sections
+}
+
+// Evaluate runs every probe against the input. Remote probes are only run
+// when the DGX was reachable.
+func Evaluate(probes []Probe, in Input, reachable bool) []types.HealthCheck {
+	checks := make([]types.HealthCheck, 0, len(probes))
+	for _, p := range probes {
+		if p.Remote && !reachable {
+			continue
+		}
+		status, summary := p.Check(in)
+		checks = append(checks, types.HealthCheck{Name: p.Name, Status: status, Summary: summary})
+	}
+	return checks
+}
+
+// Worst returns the most severe status among checks, or ok if there are none
+func Worst(checks []types.HealthCheck) string {
+	worst := StatusOK
+	for _, c := range checks {
+		if severity[c.Status] > severity[worst] {
+			worst = c.Status
+		}
+	}
+	return worst
+}
+
+// Format formats checks as a table
+func Format(checks []types.HealthCheck) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tSUMMARY")
+	for _, c := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Status, c.Summary)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// Connection reports the SSH connection as a check. errMsg is empty when
+// the DGX was reached and the status collected.
+func Connection(latency time.Duration, errMsg string) types.HealthCheck {
+	c := types.HealthCheck{Name: "ssh", Status: StatusOK}
+	if errMsg != "" {
+		c.Status = StatusFail
+		c.Summary = errMsg
+		return c
+	}
+	c.Summary = fmt.Sprintf("connected in %v", latency.Round(time.Millisecond))
+	return c
+}
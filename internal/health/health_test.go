@@ -0,0 +1,136 @@
+package health
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func f64(v float64) *float64 { return &v }
+func u64(v uint64) *uint64   { return &v }
+
+// healthySnapshot is a GB10 under moderate load
+func healthySnapshot() gpu.Snapshot {
+	return gpu.Snapshot{
+		GPUs: []types.GPUInfo{{ID: 0, Name: "NVIDIA GB10", UtilizationPercent: f64(96), TemperatureCelsius: f64(71)}},
+		Host: types.HostInfo{
+			OS:                    "Ubuntu 24.04.3 LTS",
+			Kernel:                "6.11.0-1016-nvidia",
+			UptimeSeconds:         f64(350735),
+			Load1:                 f64(3.52),
+			Load5:                 f64(2.40),
+			Load15:                f64(1.33),
+			CPUs:                  20,
+			MemoryTotalBytes:      u64(128 << 30),
+			MemoryAvailableBytes:  u64(80 << 30),
+			MemoryPressurePercent: f64(1.25),
+			TemperatureCelsius:    f64(47),
+			Disks: []types.DiskUsage{
+				{Path: "/", Mount: "/", TotalBytes: 4000 << 30, UsedBytes: 1200 << 30, AvailableBytes: 2800 << 30},
+			},
+		},
+		Sections: map[string]gpu.SectionResult{
+			"ollama": {Output: "", Exit: 1},
+			"vllm":   {Output: "c0ffee123456\tUp 2 hours (healthy)\tvllm-server\n"},
+			"dmr":    {Output: "Docker Model Runner is running\n"},
+		},
+	}
+}
+
+func statuses(checks []types.HealthCheck) map[string]string {
+	m := make(map[string]string)
+	for _, c := range checks {
+		m[c.Name] = c.Status
+	}
+	return m
+}
+
+func TestHealthyReport(t *testing.T) {
+	checks := Evaluate(Probes(), Input{Snapshot: healthySnapshot()}, true)
+	if len(checks) != len(Probes()) {
+		t.Fatalf("expected %d checks, got %d", len(Probes()), len(checks))
+	}
+	for _, c := range checks {
+		if c.Status != StatusOK {
+			t.Fatalf("expected %s ok, got %s: %s", c.Name, c.Status, c.Summary)
+		}
+	}
+	if Worst(checks) != StatusOK {
+		t.Fatalf("expected overall ok, got %s", Worst(checks))
+	}
+}
+
+func TestDegradedReport(t *testing.T) {
+	s := healthySnapshot()
+	s.Host.MemoryAvailableBytes = u64(4 << 30) // ~3% of 128 GiB
+	s.Host.Load5 = f64(25)
+	s.Host.Disks = append(s.Host.Disks, types.DiskUsage{
+		Path: "/var/lib/docker", Mount: "/data", TotalBytes: 1000 << 30, UsedBytes: 920 << 30, AvailableBytes: 80 << 30,
+	})
+	s.GPUs[0].TemperatureCelsius = f64(88)
+	s.Sections["vllm"] = gpu.SectionResult{Output: "c0ffee123456\tExited (1) 3 minutes ago\tvllm-server\n"}
+
+	got := statuses(Evaluate(Probes(), Input{Snapshot: s}, true))
+	want := map[string]string{
+		"memory":   StatusFail,
+		"load":     StatusWarn,
+		"disk":     StatusWarn,
+		"thermals": StatusWarn,
+		"services": StatusWarn,
+		"gpu":      StatusOK,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Fatalf("expected %s %s, got %s", name, status, got[name])
+		}
+	}
+}
+
+func TestGPUFailure(t *testing.T) {
+	s := healthySnapshot()
+	s.GPUs = nil
+	s.GPUErr = errors.New("failed to query GPU: nvidia-smi: command not found")
+
+	for _, c := range Evaluate(Probes(), Input{Snapshot: s}, true) {
+		if c.Name == "gpu" && (c.Status != StatusFail || !strings.Contains(c.Summary, "command not found")) {
+			t.Fatalf("unexpected gpu check %+v", c)
+		}
+	}
+}
+
+func TestUnreachableSkipsRemoteProbes(t *testing.T) {
+	in := Input{Tunnels: []types.Tunnel{
+		{LocalPort: 8888, State: tunnel.StateDown, Autostart: true},
+		{LocalPort: 11434, State: tunnel.StateUp, Health: tunnel.HealthDegraded},
+	}}
+	checks := Evaluate(Probes(), in, false)
+	if len(checks) != 1 || checks[0].Name != "tunnels" {
+		t.Fatalf("expected only the tunnels check, got %+v", checks)
+	}
+	if checks[0].Status != StatusWarn || checks[0].Summary != "1 of 2 up (8888 down, 11434 degraded)" {
+		t.Fatalf("unexpected tunnels check %+v", checks[0])
+	}
+}
+
+func TestServices(t *testing.T) {
+	s := healthySnapshot()
+	s.Sections["ollama"] = gpu.SectionResult{Output: "1234\n5678\n"}
+
+	services := Services(s)
+	if len(services) != 3 {
+		t.Fatalf("expected 3 services, got %+v", services)
+	}
+	if !services[0].Running || services[0].PID != "1234,5678" {
+		t.Fatalf("unexpected ollama status %+v", services[0])
+	}
+	if !services[1].Running || services[1].Detail != "Up 2 hours (healthy)" {
+		t.Fatalf("unexpected vllm status %+v", services[1])
+	}
+	if !services[2].Running {
+		t.Fatalf("unexpected dmr status %+v", services[2])
+	}
+}
@@ -0,0 +1,282 @@
+package health
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Thresholds for warn and fail
+const (
+	recentBootSeconds = 10 * 60
+
+	loadWarnPerCPU = 1.0
+	loadFailPerCPU = 2.0
+
+	memoryWarnAvailablePercent = 15
+	memoryFailAvailablePercent = 5
+	pressureWarnPercent        = 5
+	pressureFailPercent        = 25
+
+	diskWarnFreePercent = 10
+	diskFailFreePercent = 5
+
+	thermalWarnCelsius = 85
+	thermalFailCelsius = 95
+)
+
+// Service sections, named after the playbook that runs each service.
+// The pgrep pattern is bracketed so it does not match the collect script.
+var serviceSections = []gpu.Section{
+	{Name: "ollama", Command: "pgrep -f '[o]llama serve'"},
+	{Name: "vllm", Command: "docker ps -a --filter name=^vllm-server$ --format '{{.ID}}\t{{.Status}}\t{{.Names}}'"},
+	{Name: "dmr", Command: "docker model status"},
+}
+
+// Probes returns the default status probes in report order
+func Probes() []Probe {
+	return []Probe{
+		{Name: "os", Remote: true, Check: checkOS},
+		{Name: "uptime", Remote: true, Check: checkUptime},
+		{Name: "load", Remote: true, Check: checkLoad},
+		{Name: "memory", Remote: true, Check: checkMemory},
+		{Name: "disk", Remote: true, Check: checkDisk},
+		{Name: "thermals", Remote: true, Check: checkThermals},
+		{Name: "gpu", Remote: true, Check: checkGPU},
+		{Name: "services", Remote: true, Sections: serviceSections, Check: checkServices},
+		{Name: "tunnels", Check: checkTunnels},
+	}
+}
+
+func checkOS(in Input) (string, string) {
+	h := in.Snapshot.Host
+	if h.Kernel == "" {
+		return StatusWarn, "unknown"
+	}
+	if h.OS == "" {
+		return StatusOK, "kernel " + h.Kernel
+	}
+	return StatusOK, fmt.Sprintf("%s, kernel %s", h.OS, h.Kernel)
+}
+
+func checkUptime(in Input) (string, string) {
+	up := in.Snapshot.Host.UptimeSeconds
+	if up == nil {
+		return StatusWarn, "unknown"
+	}
+	if *up < recentBootSeconds {
+		return StatusWarn, fmt.Sprintf("up %s (recently rebooted)", gpu.FormatUptime(*up))
+	}
+	return StatusOK, "up " + gpu.FormatUptime(*up)
+}
+
+func checkLoad(in Input) (string, string) {
+	h := in.Snapshot.Host
+	if h.Load1 == nil || h.Load5 == nil || h.Load15 == nil {
+		return StatusWarn, "unknown"
+	}
+	summary := fmt.Sprintf("%.2f %.2f %.2f", *h.Load1, *h.Load5, *h.Load15)
+	if h.CPUs == 0 {
+		return StatusOK, summary
+	}
+	summary += fmt.Sprintf(" (%d CPUs)", h.CPUs)
+
+	// The 5 minute average ignores short bursts
+	perCPU := *h.Load5 / float64(h.CPUs)
+	switch {
+	case perCPU >= loadFailPerCPU:
+		return StatusFail, summary
+	case perCPU >= loadWarnPerCPU:
+		return StatusWarn, summary
+	}
+	return StatusOK, summary
+}
+
+// checkMemory reports unified memory, which the GB10 shares between CPU
+// and GPU, so host pressure also starves models
+func checkMemory(in Input) (string, string) {
+	h := in.Snapshot.Host
+	if h.MemoryTotalBytes == nil || h.MemoryAvailableBytes == nil || *h.MemoryTotalBytes == 0 {
+		return StatusWarn, "unknown"
+	}
+	used := *h.MemoryTotalBytes - *h.MemoryAvailableBytes
+	available := float64(*h.MemoryAvailableBytes) / float64(*h.MemoryTotalBytes) * 100
+	summary := fmt.Sprintf("%s used, %.0f%% available", gpu.FormatMemory(&used, h.MemoryTotalBytes), available)
+
+	pressure := 0.0
+	if h.MemoryPressurePercent != nil {
+		pressure = *h.MemoryPressurePercent
+		summary += fmt.Sprintf(", pressure %.1f%%", pressure)
+	}
+
+	switch {
+	case available < memoryFailAvailablePercent || pressure >= pressureFailPercent:
+		return StatusFail, summary
+	case available < memoryWarnAvailablePercent || pressure >= pressureWarnPercent:
+		return StatusWarn, summary
+	}
+	return StatusOK, summary
+}
+
+func checkDisk(in Input) (string, string) {
+	disks := in.Snapshot.Host.Disks
+	if len(disks) == 0 {
+		return StatusWarn, "unknown"
+	}
+
+	status := StatusOK
+	var parts []string
+	for _, d := range disks {
+		if d.TotalBytes == 0 {
+			continue
+		}
+		free := float64(d.AvailableBytes) / float64(d.TotalBytes) * 100
+		parts = append(parts, fmt.Sprintf("%s %s free (%.0f%%)", d.Path, gpu.FormatBytes(&d.AvailableBytes), free))
+		switch {
+		case free < diskFailFreePercent:
+			status = StatusFail
+		case free < diskWarnFreePercent && status == StatusOK:
+			status = StatusWarn
+		}
+	}
+	return status, strings.Join(parts, ", ")
+}
+
+func checkThermals(in Input) (string, string) {
+	var parts []string
+	hottest := 0.0
+	add := func(name string, c *float64) {
+		if c == nil {
+			return
+		}
+		parts = append(parts, name+" "+gpu.FormatCelsius(c))
+		if *c > hottest {
+			hottest = *c
+		}
+	}
+
+	add("host", in.Snapshot.Host.TemperatureCelsius)
+	for _, g := range in.Snapshot.GPUs {
+		add(fmt.Sprintf("GPU %d", g.ID), g.TemperatureCelsius)
+	}
+	if len(parts) == 0 {
+		return StatusWarn, "unknown"
+	}
+
+	summary := strings.Join(parts, ", ")
+	switch {
+	case hottest >= thermalFailCelsius:
+		return StatusFail, summary
+	case hottest >= thermalWarnCelsius:
+		return StatusWarn, summary
+	}
+	return StatusOK, summary
+}
+
+func checkGPU(in Input) (string, string) {
+	s := in.Snapshot
+	if s.GPUErr != nil {
+		return StatusFail, s.GPUErr.Error()
+	}
+	if len(s.GPUs) == 0 {
+		return StatusFail, "no GPUs found"
+	}
+
+	var parts []string
+	for _, g := range s.GPUs {
+		parts = append(parts, fmt.Sprintf("GPU %d %s %s util, %d processes",
+			g.ID, g.Name, gpu.FormatPercent(g.UtilizationPercent), len(g.Processes)))
+	}
+	return StatusOK, strings.Join(parts, "; ")
+}
+
+// Services reports the playbook services found by the services probe
+func Services(s gpu.Snapshot) []types.PlaybookStatus {
+	var services []types.PlaybookStatus
+
+	if r, ok := s.Sections["ollama"]; ok {
+		status := types.PlaybookStatus{Playbook: "ollama"}
+		if pids := strings.Fields(r.Output); r.Exit == 0 && len(pids) > 0 {
+			status.Running = true
+			status.PID = strings.Join(pids, ",")
+		}
+		services = append(services, status)
+	}
+
+	if r, ok := s.Sections["vllm"]; ok {
+		// abc123<TAB>Up 2 hours<TAB>vllm-server
+		status := types.PlaybookStatus{Playbook: "vllm"}
+		line := strings.TrimSpace(r.Output)
+		if fields := strings.Split(line, "\t"); r.Exit == 0 && len(fields) == 3 {
+			status.Container = strings.Join(fields, " ")
+			status.Running = strings.HasPrefix(fields[1], "Up")
+			status.Detail = fields[1]
+		}
+		services = append(services, status)
+	}
+
+	if r, ok := s.Sections["dmr"]; ok {
+		status := types.PlaybookStatus{Playbook: "dmr", Detail: strings.TrimSpace(r.Output)}
+		status.Running = r.Exit == 0 && strings.Contains(strings.ToLower(status.Detail), "is running")
+		if !status.Running {
+			status.Detail = ""
+		}
+		services = append(services, status)
+	}
+	return services
+}
+
+// checkServices lists running services and warns about a vLLM container
+// that exists but is not up
+func checkServices(in Input) (string, string) {
+	status := StatusOK
+	var parts []string
+	for _, s := range Services(in.Snapshot) {
+		switch {
+		case s.Running:
+			parts = append(parts, s.Playbook+" running")
+		case s.Container != "":
+			status = StatusWarn
+			parts = append(parts, fmt.Sprintf("%s container %s", s.Playbook, strings.ToLower(s.Detail)))
+		default:
+			parts = append(parts, s.Playbook+" stopped")
+		}
+	}
+	return status, strings.Join(parts, ", ")
+}
+
+// checkTunnels warns about autostart tunnels that are down, tunnels bound
+// to a different target than saved, and unhealthy forwards
+func checkTunnels(in Input) (string, string) {
+	if len(in.Tunnels) == 0 {
+		return StatusOK, "none"
+	}
+
+	status := StatusOK
+	up := 0
+	var problems []string
+	for _, t := range in.Tunnels {
+		name := fmt.Sprintf("%d", t.LocalPort)
+		switch {
+		case t.State == tunnel.StateDown && t.Autostart:
+			problems = append(problems, name+" down")
+		case t.State == tunnel.StateMismatch:
+			problems = append(problems, name+" target mismatch")
+		case t.State != tunnel.StateDown && t.Health != "" && t.Health != tunnel.HealthUp:
+			problems = append(problems, name+" "+t.Health)
+		}
+		if t.State != tunnel.StateDown {
+			up++
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d up", up, len(in.Tunnels))
+	if len(problems) > 0 {
+		status = StatusWarn
+		summary += " (" + strings.Join(problems, ", ") + ")"
+	}
+	return status, summary
+}
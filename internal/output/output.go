@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with --output
+const (
+	FormatTable = "table" // Human-readable text (default)
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Process exit codes
+const (
+	ExitOK          = 0 // Success
+	ExitError       = 1 // Command failed (remote error, failed host or tunnel, bad state)
+	ExitUsage       = 2 // Invalid arguments or flags
+	ExitUnreachable = 3 // The DGX could not be reached over SSH
+)
+
+// ParseFormat validates an --output value
+func ParseFormat(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "", FormatTable, "text":
+		return FormatTable, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q (use table, json or yaml)", value)
+	}
+}
+
+// Structured reports whether a format is machine-readable
+func Structured(format string) bool {
+	return format == FormatJSON || format == FormatYAML
+}
+
+// Write encodes v as JSON or YAML. YAML output is derived from the JSON
+// encoding so both formats share the same field names and order.
+func Write(w io.Writer, format string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case FormatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		blockStyle(&node)
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		return enc.Close()
+	default:
+		return fmt.Errorf("format %q is not structured", format)
+	}
+}
+
+// blockStyle clears the flow and quoting styles yaml.v3 keeps from JSON input
+func blockStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style = 0
+	}
+	if node.Kind == yaml.ScalarNode && node.Style == yaml.DoubleQuotedStyle {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
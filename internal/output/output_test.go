@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+type sample struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags"`
+	Port  string   `json:"port"`
+}
+
+func TestWrite(t *testing.T) {
+	v := sample{Name: "spark", Count: 2, Tags: []string{"a", "b"}, Port: "8000"}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, v); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	wantJSON := "{\n  \"name\": \"spark\",\n  \"count\": 2,\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ],\n  \"port\": \"8000\"\n}\n"
+	if buf.String() != wantJSON {
+		t.Fatalf("unexpected JSON:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatYAML, v); err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	wantYAML := "name: spark\ncount: 2\ntags:\n  - a\n  - b\nport: \"8000\"\n"
+	if buf.String() != wantYAML {
+		t.Fatalf("unexpected YAML:\n%s", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]string{"": FormatTable, "JSON": FormatJSON, "yml": FormatYAML} {
+		got, err := ParseFormat(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatalf("expected xml to be rejected")
+	}
+}
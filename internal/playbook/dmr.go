@@ -3,6 +3,8 @@ package playbook
 import (
 	"fmt"
 	"strings"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
 // runDMR handles Docker Model Runner helper commands
@@ -122,6 +124,18 @@ func (m *Manager) dmrStatus() error {
 	return nil
 }
 
+// dmrState reports whether the Docker Model Runner controller is running
+func (m *Manager) dmrState() (types.PlaybookStatus, error) {
+	status := types.PlaybookStatus{Playbook: "dmr"}
+	output, err := m.sshClient.Execute("docker model status 2>&1 || true")
+	if err != nil {
+		return status, fmt.Errorf("failed to get Docker Model Runner status: %w", err)
+	}
+	status.Detail = strings.TrimSpace(output)
+	status.Running = strings.Contains(strings.ToLower(status.Detail), "is running")
+	return status, nil
+}
+
 func (m *Manager) dmrLogs(args []string) error {
 	cmd := "docker model logs"
 	if len(args) == 0 {
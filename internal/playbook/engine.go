@@ -0,0 +1,391 @@
+package playbook
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// envFile is sourced before every YAML playbook script so secrets stored
+// with dgx env are available
+const envFile = "~/.config/dgx/env.sh"
+
+// healthInterval is how often the health check is retried while waiting
+const healthInterval = 5
+
+// runSpec runs a YAML playbook subcommand: it parses and validates the
+// parameters, renders the script and streams it on the DGX, then waits for
+// the health check or opens the tunnel and prints the hints
+func (m *Manager) runSpec(s *Spec, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s command required. Usage: dgx run %s <%s>", s.Name, s.Name, strings.Join(s.CommandNames(), "|"))
+	}
+	c := s.command(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown %s command: %s", s.Name, args[0])
+	}
+
+	withTunnel := false
+	rest := args[1:]
+	if c.Tunnel {
+		withTunnel, rest = popFlag(rest, "--tunnel")
+	}
+	params, err := c.parseArgs(rest)
+	if err != nil {
+		return fmt.Errorf("%w. Usage: %s", err, s.usage(c))
+	}
+
+	prelude, err := s.render(s.Prelude, nil)
+	if err != nil {
+		return fmt.Errorf("failed to render %s prelude: %w", s.Name, err)
+	}
+	script, err := s.render(c.Script, params)
+	if err != nil {
+		return fmt.Errorf("failed to render %s %s: %w", s.Name, c.Name, err)
+	}
+	if prelude != "" {
+		script = prelude + "\n" + script
+	}
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("%s %s failed: %w", s.Name, c.Name, err)
+	}
+
+	if withTunnel {
+		wait := defaultHealthTimeout
+		if s.Health != nil {
+			wait = s.Health.Timeout
+		}
+		if err := m.openTunnel(s.Service, wait); err != nil {
+			return err
+		}
+	} else if c.Wait {
+		if err := m.waitHealthy(s); err != nil {
+			return err
+		}
+	}
+
+	for i, hint := range c.Hints {
+		text, err := s.render(hint, params)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			fmt.Println()
+		}
+		fmt.Println(text)
+	}
+	return nil
+}
+
+// remoteScript wraps a rendered script so it runs under bash -e with the
+// dgx env file sourced
+func remoteScript(script string) string {
+	return "bash -c " + shellQuote(fmt.Sprintf("set -e\n[ ! -f %s ] || . %s\n%s", envFile, envFile, script))
+}
+
+// portFreeCheck fails a script when a container already publishes port on
+// the DGX. docker run would otherwise create its container, fail to bind
+// and leave it Created under a name that blocks the next start.
+func portFreeCheck(port int, action string) string {
+	return fmt.Sprintf(`busy=$(docker ps --filter publish=%[1]d --format '{{.Names}}')
+if [ -n "$busy" ]; then
+  echo "port %[1]d is already published by $busy; stop it before %[2]s" >&2
+  exit 1
+fi
+`, port, action)
+}
+
+// removeOnFailure follows a docker run command to remove the container it
+// created when the start fails
+func removeOnFailure(container string) string {
+	return fmt.Sprintf(" || { docker rm -f %s >/dev/null 2>&1; exit 1; }", container)
+}
+
+// healthScript exits 0 once url answers, retrying until timeout
+func healthScript(url string, attempts int) string {
+	return fmt.Sprintf("for i in $(seq %d); do if curl -fsS -o /dev/null %s; then exit 0; fi; sleep %d; done; exit 1",
+		attempts, shellQuote(url), healthInterval)
+}
+
+// waitHealthy polls the playbook's health check on the DGX
+func (m *Manager) waitHealthy(s *Spec) error {
+	url, err := s.render(s.Health.URL, nil)
+	if err != nil {
+		return err
+	}
+	return m.waitReady(s.Name, url, s.Health.Timeout)
+}
+
+// waitReady polls url on the DGX until it answers or timeout passes
+func (m *Manager) waitReady(name, url string, timeout time.Duration) error {
+	fmt.Printf("\nWaiting up to %v for %s...\n", timeout, url)
+	attempts := max(1, int(timeout.Seconds())/healthInterval)
+	if _, err := m.sshClient.Execute(healthScript(url, attempts)); err != nil {
+		return fmt.Errorf("%s did not become healthy within %v (check its logs)", name, timeout)
+	}
+	fmt.Printf("%s is ready\n", name)
+	return nil
+}
+
+// specState reports a YAML playbook's container and health check
+func (m *Manager) specState(s *Spec) (types.PlaybookStatus, error) {
+	status := types.PlaybookStatus{Playbook: s.Name}
+	if s.Container == "" && s.Health == nil {
+		return status, fmt.Errorf("playbook '%s' does not report status", s.Name)
+	}
+
+	if s.Container != "" {
+		output, err := m.sshClient.Execute(fmt.Sprintf("docker ps -a --filter %s --format '{{.ID}} {{.Status}}'", shellQuote("name=^"+s.Container+"$")))
+		if err != nil {
+			return status, fmt.Errorf("failed to check status: %w", err)
+		}
+		line := strings.TrimSpace(output)
+		if line == "" {
+			return status, nil
+		}
+		status.Container = line + " " + s.Container
+		_, status.Detail, _ = strings.Cut(line, " ")
+		status.Running = strings.HasPrefix(status.Detail, "Up")
+		if !status.Running {
+			return status, nil
+		}
+	}
+
+	if s.Health != nil {
+		url, err := s.render(s.Health.URL, nil)
+		if err != nil {
+			return status, err
+		}
+		if _, err := m.sshClient.Execute(healthScript(url, 1)); err != nil {
+			status.Health = "not ready"
+		} else {
+			status.Health = "ok"
+			status.Running = true
+		}
+	}
+	return status, nil
+}
+
+// containerState reports a container by exact name, and its image. Running
+// is set when the container is up.
+func (m *Manager) containerState(playbook, container string) (types.PlaybookStatus, string, error) {
+	status := types.PlaybookStatus{Playbook: playbook}
+	output, err := m.sshClient.Execute(fmt.Sprintf("docker ps -a --filter %s --format '{{.ID}}\t{{.Status}}\t{{.Image}}'", shellQuote("name=^"+container+"$")))
+	if err != nil {
+		return status, "", fmt.Errorf("failed to check status: %w", err)
+	}
+	fields := strings.Split(strings.TrimSpace(output), "\t")
+	if len(fields) < 3 {
+		return status, "", nil
+	}
+	status.Container = fmt.Sprintf("%s %s %s", fields[0], fields[1], container)
+	status.Detail = fields[1]
+	status.Running = strings.HasPrefix(status.Detail, "Up")
+	return status, fields[2], nil
+}
+
+// answers reports whether a service's health endpoint answers on the DGX
+func (m *Manager) answers(service tunnel.Service) bool {
+	url := fmt.Sprintf("http://localhost:%d%s", service.Port, service.HealthPath)
+	_, err := m.sshClient.Execute(healthScript(url, 1))
+	return err == nil
+}
+
+// dockerLogs streams a container's logs; extra args go to docker logs
+func (m *Manager) dockerLogs(container string, args []string) error {
+	cmd := "docker logs --tail 200"
+	if len(args) > 0 {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = shellQuote(arg)
+		}
+		cmd = "docker logs " + strings.Join(quoted, " ")
+	}
+	if err := m.sshClient.ExecuteStream(cmd+" "+container, os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to retrieve %s logs: %w", container, err)
+	}
+	return nil
+}
+
+// parseArgs matches arguments to parameters: --name value, --name=value,
+// a bare --name for bool parameters, and positional values in order
+func (c *CommandSpec) parseArgs(args []string) (map[string]any, error) {
+	raw := make(map[string]string)
+	var positional []ParamSpec
+	for _, p := range c.Params {
+		if p.Positional {
+			positional = append(positional, p)
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") || arg == "--" {
+			// Skip positional parameters already given as flags
+			assigned := false
+			for len(positional) > 0 && !assigned {
+				p := positional[0]
+				positional = positional[1:]
+				if _, set := raw[p.Name]; !set {
+					raw[p.Name] = arg
+					assigned = true
+				}
+			}
+			if !assigned {
+				return nil, fmt.Errorf("unexpected argument %q", arg)
+			}
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		p := c.param(name)
+		if p == nil {
+			return nil, fmt.Errorf("unknown flag --%s", name)
+		}
+		switch {
+		case hasValue:
+		case p.Type == "bool":
+			value = "true"
+		case i+1 < len(args):
+			i++
+			value = args[i]
+		default:
+			return nil, fmt.Errorf("--%s needs a value", name)
+		}
+		raw[p.Name] = value
+	}
+
+	params := make(map[string]any, len(c.Params))
+	for _, p := range c.Params {
+		value, set := raw[p.Name]
+		if !set && p.Required {
+			return nil, fmt.Errorf("%s required", p.Name)
+		}
+		if !set && p.Default == "" {
+			params[p.Key()] = p.zero()
+			continue
+		}
+		if !set {
+			value = p.Default
+		}
+		v, err := p.convert(value)
+		if err != nil {
+			return nil, err
+		}
+		params[p.Key()] = v
+	}
+	return params, nil
+}
+
+// zero is the value of an optional parameter with no default
+func (p ParamSpec) zero() any {
+	switch p.Type {
+	case "int":
+		return 0
+	case "bool":
+		return false
+	default:
+		return ""
+	}
+}
+
+func (c *CommandSpec) param(name string) *ParamSpec {
+	for i := range c.Params {
+		if c.Params[i].Name == name {
+			return &c.Params[i]
+		}
+	}
+	return nil
+}
+
+// usage is the one-line synopsis of a subcommand
+func (s *Spec) usage(c *CommandSpec) string {
+	parts := []string{"dgx run", s.Name, c.Name}
+	for _, p := range c.Params {
+		if p.Positional {
+			if p.Required {
+				parts = append(parts, "<"+p.Name+">")
+			} else {
+				parts = append(parts, "[<"+p.Name+">]")
+			}
+		}
+	}
+	for _, p := range c.Params {
+		if p.Positional {
+			continue
+		}
+		flag := "--" + p.Name
+		if p.Type != "bool" {
+			flag += " <" + p.valueName() + ">"
+		}
+		if !p.Required {
+			flag = "[" + flag + "]"
+		}
+		parts = append(parts, flag)
+	}
+	if c.Tunnel {
+		parts = append(parts, "[--tunnel]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p ParamSpec) valueName() string {
+	if len(p.Choices) > 0 {
+		return strings.Join(p.Choices, "|")
+	}
+	if p.Type == "int" {
+		return "n"
+	}
+	return p.Name
+}
+
+// Help describes a YAML playbook's commands, parameters and examples
+func (s *Spec) Help() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%s) playbook\n", s.Description, s.Name)
+	sb.WriteString("Commands:\n")
+	for _, c := range s.Commands {
+		fmt.Fprintf(&sb, "  %-11s - %s\n", c.Name, c.Description)
+	}
+
+	for i := range s.Commands {
+		c := &s.Commands[i]
+		if len(c.Params) == 0 && !c.Tunnel {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s\n", s.usage(c))
+		for _, p := range c.Params {
+			desc := p.Description
+			if p.Default != "" {
+				desc += fmt.Sprintf(" (default %s)", p.Default)
+			}
+			name := "--" + p.Name
+			if p.Positional {
+				name = "<" + p.Name + ">"
+			}
+			fmt.Fprintf(&sb, "  %-24s %s\n", name, strings.TrimSpace(desc))
+		}
+		if c.Tunnel {
+			fmt.Fprintf(&sb, "  %-24s %s\n", "--tunnel", "Open the "+s.Service+" tunnel and wait for it to answer")
+		}
+	}
+
+	var examples []string
+	for _, c := range s.Commands {
+		examples = append(examples, c.Examples...)
+	}
+	if len(examples) > 0 {
+		sb.WriteString("\nExamples:\n")
+		for _, e := range examples {
+			fmt.Fprintf(&sb, "  %s\n", e)
+		}
+	}
+	if s.Source != "built-in" && s.Source != "" {
+		fmt.Fprintf(&sb, "\nDefined in %s\n", s.Source)
+	}
+	return sb.String()
+}
@@ -4,6 +4,11 @@ import "fmt"
 
 // PrintHelp prints playbook-specific usage guidance.
 func PrintHelp(name string) {
+	if s := findSpec(name); s != nil {
+		fmt.Print(s.Help())
+		return
+	}
+
 	switch name {
 	case "dmr":
 		fmt.Println("Docker Model Runner (dmr) playbook")
@@ -25,6 +30,30 @@ func PrintHelp(name string) {
 		fmt.Println("  dgx run dmr run ai/smollm2:360M-Q4_K_M \"Explain quantum computing\"")
 		fmt.Println("  dgx run dmr status")
 		fmt.Println("  dgx run dmr logs --tail 100")
+	case "speculative-decoding":
+		fmt.Print(specPlaybook.Help())
+	case "llama-factory":
+		fmt.Print(llamaFactoryPlaybook.Help())
+	case "unsloth":
+		fmt.Print(unslothPlaybook.Help())
+	case "nim":
+		fmt.Println("NVIDIA Inference Microservices (nim) playbook")
+		fmt.Println("Commands:")
+		fmt.Println("  login       - Log Docker on the DGX in to nvcr.io with the key from 'dgx env ngc-key'")
+		fmt.Println("  list        - List the NIMs in the catalogue and their ports")
+		fmt.Println("  catalogue   - Print the built-in catalogue, to start ~/.config/dgx/nim-catalogue.yaml")
+		fmt.Println("  deploy      - Start a NIM and wait until it is ready (usage: dgx run nim deploy <nim-image> [--tunnel])")
+		fmt.Println("  status      - Show the deployed NIM and its readiness")
+		fmt.Println("  stop        - Stop and remove the NIM (the model cache in ~/.cache/nim is kept)")
+		fmt.Println("  logs        - Show NIM logs (pass docker logs flags like --follow or --tail 50)")
+		fmt.Println()
+		fmt.Println("Examples:")
+		fmt.Println("  dgx env ngc-key")
+		fmt.Println("  dgx run nim login")
+		fmt.Println("  dgx run nim list")
+		fmt.Println("  dgx run nim deploy llama-3.1-8b-instruct --tunnel")
+		fmt.Println("  dgx run nim deploy nvcr.io/nim/meta/llama-3.1-8b-instruct-dgx-spark:1.0.0")
+		fmt.Println("  dgx run nim logs --follow")
 	default:
 		fmt.Printf("No dedicated help available for playbook '%s'. Refer to README/PLAYBOOKS for usage.\n", name)
 	}
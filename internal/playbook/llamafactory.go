@@ -0,0 +1,566 @@
+package playbook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// llamaFactoryVersion is the LLaMA-Factory release installed on top of
+	// PyTorchImage
+	llamaFactoryVersion = "v0.9.3"
+	llamaFactoryImage   = "dgx/llama-factory:" + llamaFactoryVersion
+
+	llamaFactoryDir   = "~/llama-factory"
+	llamaFactoryWebUI = "llama-factory-webui"
+)
+
+// llamaFactoryBuild builds the LLaMA-Factory image on the DGX the first time
+// it is needed
+var llamaFactoryBuild = fmt.Sprintf(`if ! docker image inspect %[1]s >/dev/null 2>&1; then
+  echo "Building %[1]s on the DGX (first run only, several minutes)..."
+  docker build -t %[1]s - <<'DOCKERFILE'
+FROM %[2]s
+RUN pip install --no-cache-dir "llamafactory[metrics] @ git+https://github.com/hiyouga/LLaMA-Factory.git@%[3]s" bitsandbytes
+DOCKERFILE
+fi
+`, llamaFactoryImage, PyTorchImage, llamaFactoryVersion)
+
+var llamaFactoryCommands = []CommandSpec{
+	{
+		Name:        "init",
+		Description: "Write a local training config from a template",
+		Params: []ParamSpec{
+			{Name: "name", Description: "Job name", Positional: true, Required: true, Pattern: `[a-z0-9][a-z0-9-]*`},
+			{Name: "model", Description: "Base model (Hugging Face ID)", Required: true, Pattern: hfModelPattern},
+			{Name: "dataset", Description: "Local JSON or JSONL dataset", Required: true},
+			{Name: "method", Description: "Fine-tuning method", Choices: []string{"lora", "qlora", "full"}, Default: "lora"},
+			{Name: "format", Description: "Dataset format", Choices: []string{"alpaca", "sharegpt"}, Default: "alpaca"},
+			{Name: "template", Description: "Chat template (guessed from the model when omitted)"},
+			{Name: "config", Description: "Config file to write (default <name>.yaml)"},
+			{Name: "force", Description: "Overwrite an existing config", Type: "bool"},
+		},
+		Examples: []string{
+			"dgx run llama-factory init support-bot --model meta-llama/Llama-3.1-8B-Instruct --dataset data/train.jsonl",
+			"dgx run llama-factory init chat-qlora --model Qwen/Qwen2.5-32B-Instruct --dataset chats.json --method qlora --format sharegpt",
+		},
+	},
+	{
+		Name:        "train",
+		Description: "Sync a config and its dataset to the DGX and start training in the background",
+		Params:      []ParamSpec{{Name: "config", Description: "Config written by init", Positional: true, Required: true}},
+		Examples: []string{
+			"dgx run llama-factory train support-bot.yaml",
+		},
+	},
+	{
+		Name:        "status",
+		Description: "List training jobs and the web UI",
+	},
+	{
+		Name:        "logs",
+		Description: "Show a training job's logs",
+		Params: []ParamSpec{
+			{Name: "name", Description: "Job name", Positional: true, Required: true},
+			{Name: "tail", Description: "Number of lines to show", Type: "int", Default: "200"},
+			{Name: "follow", Description: "Keep streaming new lines", Type: "bool"},
+		},
+		Examples: []string{
+			"dgx run llama-factory logs support-bot --follow",
+		},
+	},
+	{
+		Name:        "webui",
+		Description: "Start the LLaMA Board web UI",
+		Tunnel:      true,
+		Examples: []string{
+			"dgx run llama-factory webui --tunnel",
+		},
+	},
+	{
+		Name:        "stop",
+		Description: "Stop a training job, or the web UI with 'webui'",
+		Params:      []ParamSpec{{Name: "name", Description: "Job name or webui", Positional: true, Required: true}},
+	},
+	{
+		Name:        "export",
+		Description: "Merge the trained adapter into the base model and download it",
+		Params: []ParamSpec{
+			{Name: "config", Description: "Config written by init", Positional: true, Required: true},
+			{Name: "dest", Description: "Local directory (default ./<name>-export)"},
+		},
+		Examples: []string{
+			"dgx run llama-factory export support-bot.yaml --dest ./models/support-bot",
+		},
+	},
+}
+
+// llamaFactoryPlaybook describes llama-factory for usage and help; its
+// commands are implemented in Go
+var llamaFactoryPlaybook = &Spec{
+	Name:        "llama-factory",
+	Description: "LLaMA model fine-tuning toolkit",
+	Service:     "llama-factory",
+	Commands:    llamaFactoryCommands,
+}
+
+// runLlamaFactory handles LLaMA-Factory fine-tuning commands
+func (m *Manager) runLlamaFactory(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("llama-factory command required. Usage: dgx run llama-factory <%s>", strings.Join(llamaFactoryPlaybook.CommandNames(), "|"))
+	}
+	c := llamaFactoryPlaybook.command(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown llama-factory command: %s", args[0])
+	}
+	withTunnel, rest := popFlag(args[1:], "--tunnel")
+	if withTunnel && !c.Tunnel {
+		return fmt.Errorf("--tunnel is only accepted by webui")
+	}
+	params, err := c.parseArgs(rest)
+	if err != nil {
+		return fmt.Errorf("%w. Usage: %s", err, llamaFactoryPlaybook.usage(c))
+	}
+
+	switch c.Name {
+	case "init":
+		return llamaFactoryInit(params)
+	case "train":
+		return m.llamaFactoryTrain(params["config"].(string))
+	case "status":
+		return m.llamaFactoryStatus()
+	case "logs":
+		logArgs := []string{"--tail", fmt.Sprint(params["tail"])}
+		if params["follow"].(bool) {
+			logArgs = append(logArgs, "--follow")
+		}
+		return m.dockerLogs(trainContainer(params["name"].(string)), logArgs)
+	case "webui":
+		return m.llamaFactoryWebUI(withTunnel)
+	case "stop":
+		return m.llamaFactoryStop(params["name"].(string))
+	default:
+		return m.llamaFactoryExport(params["config"].(string), params["dest"].(string))
+	}
+}
+
+// trainJob is the dgx section of a training config: what the config's
+// dataset is and where it lives locally. It is removed before the config
+// reaches LLaMA-Factory.
+type trainJob struct {
+	Name    string `yaml:"name"`
+	Dataset string `yaml:"dataset"` // Local file, relative to the config
+	Format  string `yaml:"format"`  // alpaca or sharegpt
+}
+
+// trainDir is the job's directory on the DGX, mounted at /workspace
+func (j trainJob) trainDir() string {
+	return llamaFactoryDir + "/jobs/" + j.Name
+}
+
+func trainContainer(name string) string {
+	return "llama-factory-" + name
+}
+
+// trainConfigTemplate is the config init writes. Keys under ### follow the
+// LLaMA-Factory examples; dataset, dataset_dir and output_dir are set by
+// train.
+var trainConfigTemplate = template.Must(template.New("config").Parse(`# LLaMA-Factory training config written by dgx run llama-factory init.
+# Edit it freely, then: dgx run llama-factory train {{.File}}
+# dataset, dataset_dir and output_dir are set by dgx on the DGX.
+dgx:
+  name: {{.Name}}
+  dataset: {{printf "%q" .Dataset}}
+  format: {{.Format}}
+
+### model
+model_name_or_path: {{.Model}}
+trust_remote_code: true
+
+### method
+stage: sft
+do_train: true
+{{- if eq .Method "full"}}
+finetuning_type: full
+{{- else}}
+finetuning_type: lora
+lora_rank: 16
+lora_alpha: 32
+lora_dropout: 0.05
+lora_target: all
+{{- end}}
+{{- if eq .Method "qlora"}}
+quantization_bit: 4
+quantization_method: bitsandbytes
+{{- end}}
+
+### dataset
+template: {{.Template}}
+cutoff_len: 2048
+max_samples: 100000
+preprocessing_num_workers: 16
+
+### output
+logging_steps: 10
+save_steps: 500
+plot_loss: true
+overwrite_output_dir: true
+
+### train
+per_device_train_batch_size: 1
+gradient_accumulation_steps: 8
+learning_rate: {{if eq .Method "full"}}1.0e-5{{else}}1.0e-4{{end}}
+num_train_epochs: 3.0
+lr_scheduler_type: cosine
+warmup_ratio: 0.1
+bf16: true
+`))
+
+// chatTemplates maps model name fragments to LLaMA-Factory chat templates
+var chatTemplates = []struct{ fragment, template string }{
+	{"llama-3", "llama3"},
+	{"llama3", "llama3"},
+	{"qwen3", "qwen3"},
+	{"qwen", "qwen"},
+	{"mistral", "mistral"},
+	{"gemma", "gemma"},
+	{"phi-4", "phi4"},
+	{"deepseek", "deepseek3"},
+}
+
+// guessTemplate picks the chat template for a model, or default
+func guessTemplate(model string) string {
+	lower := strings.ToLower(model)
+	for _, t := range chatTemplates {
+		if strings.Contains(lower, t.fragment) {
+			return t.template
+		}
+	}
+	return "default"
+}
+
+// llamaFactoryInit writes a training config from the template
+func llamaFactoryInit(params map[string]any) error {
+	name := params["name"].(string)
+	path := params["config"].(string)
+	if path == "" {
+		path = name + ".yaml"
+	}
+	if _, err := os.Stat(path); err == nil && !params["force"].(bool) {
+		return fmt.Errorf("%s already exists (pass --force to overwrite)", path)
+	}
+
+	dataset := params["dataset"].(string)
+	if _, err := os.Stat(dataset); err != nil {
+		return fmt.Errorf("dataset: %w", err)
+	}
+	// The config refers to the dataset relative to itself
+	if abs, err := filepath.Abs(dataset); err == nil {
+		if dir, err := filepath.Abs(filepath.Dir(path)); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				dataset = rel
+			}
+		}
+	}
+
+	tmpl := params["template"].(string)
+	if tmpl == "" {
+		tmpl = guessTemplate(params["model"].(string))
+	}
+	var sb strings.Builder
+	err := trainConfigTemplate.Execute(&sb, map[string]string{
+		"File":     path,
+		"Name":     name,
+		"Dataset":  dataset,
+		"Format":   params["format"].(string),
+		"Model":    params["model"].(string),
+		"Method":   params["method"].(string),
+		"Template": tmpl,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s (%s fine-tuning of %s, chat template %s)\n", path, params["method"], params["model"], tmpl)
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  1. Review the hyperparameters in %s\n", path)
+	fmt.Println("  2. Store your Hugging Face token for gated models: dgx env hf-token")
+	fmt.Printf("  3. Start training: dgx run llama-factory train %s\n", path)
+	return nil
+}
+
+// loadTrainConfig reads a config written by init, returning its dgx section
+// and the config LLaMA-Factory runs: the rest, with the dataset and output
+// paths of the job directory on the DGX
+func loadTrainConfig(path string) (trainJob, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return trainJob{}, nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return trainJob{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return trainJob{}, nil, fmt.Errorf("%s: expected a mapping", path)
+	}
+	root := doc.Content[0]
+
+	var job trainJob
+	found := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "dgx" {
+			if err := root.Content[i+1].Decode(&job); err != nil {
+				return trainJob{}, nil, fmt.Errorf("%s: dgx: %w", path, err)
+			}
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			found = true
+			break
+		}
+	}
+	switch {
+	case !found:
+		return trainJob{}, nil, fmt.Errorf("%s has no dgx section; create configs with dgx run llama-factory init", path)
+	case !namePattern.MatchString(job.Name):
+		return trainJob{}, nil, fmt.Errorf("%s: dgx.name %q must be lowercase letters, digits and dashes", path, job.Name)
+	case job.Dataset == "":
+		return trainJob{}, nil, fmt.Errorf("%s: dgx.dataset is required", path)
+	case job.Format != "alpaca" && job.Format != "sharegpt":
+		return trainJob{}, nil, fmt.Errorf("%s: dgx.format must be alpaca or sharegpt", path)
+	}
+	if !filepath.IsAbs(job.Dataset) {
+		job.Dataset = filepath.Join(filepath.Dir(path), job.Dataset)
+	}
+
+	setKey(root, "dataset", job.Name)
+	setKey(root, "dataset_dir", "/workspace/data")
+	setKey(root, "output_dir", "/workspace/output")
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return trainJob{}, nil, err
+	}
+	return job, buf.Bytes(), nil
+}
+
+// setKey sets a scalar in a YAML mapping, replacing any existing value
+func setKey(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// datasetInfo is the dataset_info.json that registers the job's dataset
+// with LLaMA-Factory
+func (j trainJob) datasetInfo() ([]byte, error) {
+	entry := map[string]any{"file_name": filepath.Base(j.Dataset)}
+	if j.Format == "sharegpt" {
+		entry["formatting"] = "sharegpt"
+		entry["columns"] = map[string]string{"messages": "conversations"}
+	}
+	return json.MarshalIndent(map[string]any{j.Name: entry}, "", "  ")
+}
+
+// llamaFactoryTrain syncs the job to the DGX and starts a detached
+// training container
+func (m *Manager) llamaFactoryTrain(path string) error {
+	job, config, err := loadTrainConfig(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(job.Dataset); err != nil {
+		return fmt.Errorf("dataset: %w", err)
+	}
+	info, err := job.datasetInfo()
+	if err != nil {
+		return err
+	}
+
+	// Stage the files LLaMA-Factory reads, laid out as on the DGX
+	stage, err := os.MkdirTemp("", "dgx-llama-factory-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stage)
+	if err := os.MkdirAll(filepath.Join(stage, "data"), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(stage, "train.yaml"), config, 0o644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(stage, "data", "dataset_info.json"), info, 0o644); err != nil {
+		return err
+	}
+
+	container := trainContainer(job.Name)
+	_, err = m.sshClient.Execute(fmt.Sprintf(`[ "$(docker inspect -f '{{.State.Running}}' %[1]s 2>/dev/null)" = true ] && exit 3
+mkdir -p %[2]s/data %[2]s/output`, container, job.trainDir()))
+	if ssh.ExitStatus(err) == 3 {
+		return fmt.Errorf("job %s is already training (see dgx run llama-factory logs %s)", job.Name, job.Name)
+	} else if err != nil {
+		return fmt.Errorf("failed to prepare %s: %w", job.trainDir(), err)
+	}
+
+	fmt.Printf("Syncing %s and %s to the DGX...\n", path, job.Dataset)
+	if err := m.sshClient.Rsync(stage+"/", m.sshClient.RemotePath(job.trainDir()+"/"), false); err != nil {
+		return fmt.Errorf("failed to sync the config: %w", err)
+	}
+	if err := m.sshClient.Rsync(job.Dataset, m.sshClient.RemotePath(job.trainDir()+"/data/"), false); err != nil {
+		return fmt.Errorf("failed to sync the dataset: %w", err)
+	}
+
+	// docker run -d keeps training when the SSH session ends. The container
+	// runs as root, so it hands the output to the user on exit.
+	script := llamaFactoryBuild + fmt.Sprintf(`docker rm -f %[1]s >/dev/null 2>&1 || true
+mkdir -p ~/.cache/huggingface
+docker run -d --name %[1]s --gpus all --ipc=host \
+  --ulimit memlock=-1 --ulimit stack=67108864 \
+  -v %[2]s:/workspace \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN -e WANDB_API_KEY \
+  -e OWNER="$(id -u):$(id -g)" \
+  -w /workspace \
+  %[3]s \
+  bash -c 'trap "chown -R \"\$OWNER\" /workspace/output" EXIT; llamafactory-cli train /workspace/train.yaml'`, container, job.trainDir(), llamaFactoryImage)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to start training: %w", err)
+	}
+
+	fmt.Printf("\nTraining %s in the background on the DGX (container %s)\n", job.Name, container)
+	fmt.Println("\nTo follow progress:")
+	fmt.Printf("  dgx run llama-factory logs %s --follow\n", job.Name)
+	fmt.Println("\nWhen it finishes:")
+	fmt.Printf("  dgx run llama-factory export %s\n", path)
+	return nil
+}
+
+// llamaFactoryStatus lists the training containers and the web UI
+func (m *Manager) llamaFactoryStatus() error {
+	output, err := m.sshClient.Execute("docker ps -a --filter name=^llama-factory- --format 'table {{.Names}}\t{{.Status}}'")
+	if err != nil {
+		return fmt.Errorf("failed to check status: %w", err)
+	}
+	output = strings.TrimSpace(output)
+	if !strings.Contains(output, "\n") {
+		fmt.Println("No LLaMA-Factory jobs")
+		fmt.Println("\nTo start one:")
+		fmt.Println("  dgx run llama-factory init <name> --model <model> --dataset <file>")
+		return nil
+	}
+	fmt.Println(output)
+	return nil
+}
+
+// llamaFactoryWebUI starts LLaMA Board with the jobs directory mounted
+func (m *Manager) llamaFactoryWebUI(withTunnel bool) error {
+	script := llamaFactoryBuild + fmt.Sprintf(`if docker container inspect %[1]s >/dev/null 2>&1; then
+  echo "%[1]s already exists; stop it first with: dgx run llama-factory stop webui" >&2
+  exit 1
+fi
+`, llamaFactoryWebUI) + portFreeCheck(7860, "starting the web UI") + fmt.Sprintf(`mkdir -p %[2]s ~/.cache/huggingface
+docker run -d --name %[1]s --gpus all --ipc=host \
+  -p 7860:7860 \
+  -v %[2]s:/workspace \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN -e GRADIO_SERVER_NAME=0.0.0.0 -e GRADIO_SERVER_PORT=7860 \
+  -w /workspace \
+  %[3]s \
+  llamafactory-cli webui`, llamaFactoryWebUI, llamaFactoryDir, llamaFactoryImage) + removeOnFailure(llamaFactoryWebUI)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to start the web UI: %w", err)
+	}
+
+	if withTunnel {
+		return m.openTunnel("llama-factory", 5*time.Minute)
+	}
+	if err := m.waitReady("llama-factory", "http://localhost:7860/", 5*time.Minute); err != nil {
+		return err
+	}
+	fmt.Println("\nTo open LLaMA Board:")
+	fmt.Println("  dgx tunnel open llama-factory")
+	return nil
+}
+
+// llamaFactoryStop removes a training container or the web UI
+func (m *Manager) llamaFactoryStop(name string) error {
+	container := trainContainer(name)
+	if name == "webui" {
+		container = llamaFactoryWebUI
+	}
+	if _, err := m.sshClient.Execute("docker rm -f " + shellQuote(container)); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", container, err)
+	}
+	fmt.Printf("%s stopped and removed\n", container)
+	return nil
+}
+
+// llamaFactoryExport merges a LoRA adapter into its base model on the DGX,
+// or takes a full fine-tune as is, and downloads the result
+func (m *Manager) llamaFactoryExport(path, dest string) error {
+	job, config, err := loadTrainConfig(path)
+	if err != nil {
+		return err
+	}
+	var settings struct {
+		Model          string `yaml:"model_name_or_path"`
+		Template       string `yaml:"template"`
+		FinetuningType string `yaml:"finetuning_type"`
+	}
+	if err := yaml.Unmarshal(config, &settings); err != nil {
+		return err
+	}
+	if dest == "" {
+		dest = job.Name + "-export"
+	}
+
+	remote := job.trainDir() + "/output"
+	if settings.FinetuningType != "full" {
+		// Adapters merge into the unquantized base model
+		export := fmt.Sprintf("model_name_or_path: %s\nadapter_name_or_path: /workspace/output\ntemplate: %s\nfinetuning_type: lora\ntrust_remote_code: true\nexport_dir: /workspace/export\nexport_size: 5\nexport_device: cpu\nexport_legacy_format: false\n",
+			settings.Model, settings.Template)
+		script := llamaFactoryBuild + fmt.Sprintf(`if [ ! -f %[1]s/output/adapter_config.json ]; then
+  echo "No trained adapter in %[1]s/output; has training finished?" >&2
+  exit 1
+fi
+printf '%%s' %[2]s > %[1]s/export.yaml
+echo "Merging the adapter into %[3]s..."
+docker run --rm --ipc=host \
+  -v %[1]s:/workspace \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN \
+  -e OWNER="$(id -u):$(id -g)" \
+  %[4]s \
+  bash -c 'trap "chown -R \"\$OWNER\" /workspace/export" EXIT; rm -rf /workspace/export; llamafactory-cli export /workspace/export.yaml'`, job.trainDir(), shellQuote(export), settings.Model, llamaFactoryImage)
+		if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		remote = job.trainDir() + "/export"
+	}
+
+	fmt.Printf("\nDownloading %s to %s...\n", remote, dest)
+	if err := m.sshClient.Rsync(m.sshClient.RemotePath(remote+"/"), dest+"/", false); err != nil {
+		return fmt.Errorf("failed to download the model: %w", err)
+	}
+	fmt.Printf("Model saved to %s\n", dest)
+	return nil
+}
@@ -0,0 +1,123 @@
+package playbook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func initConfig(t *testing.T, args ...string) string {
+	t.Helper()
+	params, err := llamaFactoryPlaybook.command("init").parseArgs(args)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := llamaFactoryInit(params); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	return params["config"].(string)
+}
+
+func TestLlamaFactoryInitAndTrainConfig(t *testing.T) {
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "data", "train.jsonl")
+	os.MkdirAll(filepath.Dir(dataset), 0o755)
+	os.WriteFile(dataset, []byte(`{"instruction": "hi", "input": "", "output": "hello"}`+"\n"), 0o644)
+	path := filepath.Join(dir, "configs", "bot.yaml")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+
+	initConfig(t, "bot", "--model", "meta-llama/Llama-3.1-8B-Instruct", "--dataset", dataset, "--method", "qlora", "--config", path)
+	if err := llamaFactoryInit(map[string]any{"name": "bot", "config": path, "force": false}); err == nil {
+		t.Fatalf("expected init to refuse to overwrite %s", path)
+	}
+
+	written, _ := os.ReadFile(path)
+	if !strings.Contains(string(written), `dataset: "../data/train.jsonl"`) {
+		t.Fatalf("expected the dataset relative to the config:\n%s", written)
+	}
+
+	job, config, err := loadTrainConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if job.Name != "bot" || job.Format != "alpaca" || filepath.Clean(job.Dataset) != dataset {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	var settings map[string]any
+	if err := yaml.Unmarshal(config, &settings); err != nil {
+		t.Fatalf("generated config is not YAML: %v\n%s", err, config)
+	}
+	want := map[string]any{
+		"dataset":            "bot",
+		"dataset_dir":        "/workspace/data",
+		"output_dir":         "/workspace/output",
+		"template":           "llama3",
+		"finetuning_type":    "lora",
+		"quantization_bit":   4,
+		"model_name_or_path": "meta-llama/Llama-3.1-8B-Instruct",
+	}
+	for key, value := range want {
+		if settings[key] != value {
+			t.Fatalf("%s: expected %v, got %v", key, value, settings[key])
+		}
+	}
+	if _, ok := settings["dgx"]; ok {
+		t.Fatalf("dgx section must not reach LLaMA-Factory:\n%s", config)
+	}
+}
+
+func TestLlamaFactoryFullConfig(t *testing.T) {
+	dir := t.TempDir()
+	dataset := filepath.Join(dir, "chats.json")
+	os.WriteFile(dataset, []byte("[]"), 0o644)
+	path := initConfig(t, "chat", "--model", "Qwen/Qwen2.5-7B-Instruct", "--dataset", dataset, "--method", "full",
+		"--format", "sharegpt", "--config", filepath.Join(dir, "chat.yaml"))
+
+	job, config, err := loadTrainConfig(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if strings.Contains(string(config), "lora_rank") || !strings.Contains(string(config), "finetuning_type: full") {
+		t.Fatalf("unexpected full fine-tuning config:\n%s", config)
+	}
+	info, err := job.datasetInfo()
+	if err != nil || !strings.Contains(string(info), `"formatting": "sharegpt"`) || !strings.Contains(string(info), `"file_name": "chats.json"`) {
+		t.Fatalf("unexpected dataset_info.json: %s (%v)", info, err)
+	}
+}
+
+func TestLoadTrainConfigRejectsMistakes(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"no dgx section": "model_name_or_path: x\n",
+		"bad name":       "dgx: {name: Bot, dataset: a.json, format: alpaca}\n",
+		"no dataset":     "dgx: {name: bot, format: alpaca}\n",
+		"bad format":     "dgx: {name: bot, dataset: a.json, format: csv}\n",
+	}
+	for name, data := range tests {
+		path := filepath.Join(dir, "config.yaml")
+		os.WriteFile(path, []byte(data), 0o644)
+		if _, _, err := loadTrainConfig(path); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGuessTemplate(t *testing.T) {
+	tests := map[string]string{
+		"meta-llama/Llama-3.1-8B-Instruct": "llama3",
+		"Qwen/Qwen3-8B":                    "qwen3",
+		"Qwen/Qwen2.5-7B-Instruct":         "qwen",
+		"mistralai/Mistral-7B-v0.1":        "mistral",
+		"tiiuae/falcon-7b":                 "default",
+	}
+	for model, want := range tests {
+		if got := guessTemplate(model); got != want {
+			t.Fatalf("%s: expected %s, got %s", model, want, got)
+		}
+	}
+}
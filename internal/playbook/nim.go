@@ -0,0 +1,314 @@
+package playbook
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/config"
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// NIMCatalogueFile is the name of a local NIM catalogue in the dgx config
+// directory, used instead of the embedded one when present
+const NIMCatalogueFile = "nim-catalogue.yaml"
+
+//go:embed nim-catalogue.yaml
+var embeddedNIMCatalogue []byte
+
+// nimNamePattern allows the dots of model versions, as in llama-3.1-8b
+var nimNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+const (
+	nimContainer = "nim-server"
+	nimCacheDir  = "~/.cache/nim"
+
+	// NIMs download their model on first start
+	nimReadyTimeout = 20 * time.Minute
+
+	nimDefaultPort    = 8000
+	nimDefaultShmSize = "16g"
+)
+
+// NIMCatalogue lists NIM images known to run on DGX Spark
+type NIMCatalogue struct {
+	Version string `yaml:"version"`
+	NIMs    []NIM  `yaml:"nims"`
+
+	// Source is "embedded" or the file the catalogue was loaded from
+	Source string `yaml:"-"`
+}
+
+// NIM is a NIM container image and how to run it
+type NIM struct {
+	Name        string `yaml:"name"`
+	Image       string `yaml:"image"`
+	Port        int    `yaml:"port"`     // API port inside the container
+	ShmSize     string `yaml:"shm_size"` // docker run --shm-size
+	Description string `yaml:"description"`
+}
+
+// EmbeddedNIMCatalogue returns the catalogue built into dgx, as YAML
+func EmbeddedNIMCatalogue() []byte {
+	return embeddedNIMCatalogue
+}
+
+// LoadNIMCatalogue reads ~/.config/dgx/nim-catalogue.yaml, or the embedded
+// catalogue when there is none
+func LoadNIMCatalogue() (*NIMCatalogue, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, NIMCatalogueFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		c, err := ParseNIMCatalogue(embeddedNIMCatalogue)
+		if err != nil {
+			return nil, fmt.Errorf("embedded NIM catalogue: %w", err)
+		}
+		c.Source = "embedded"
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseNIMCatalogue(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.Source = path
+	return c, nil
+}
+
+// ParseNIMCatalogue parses and validates a NIM catalogue, filling in the
+// default port and shared memory size
+func ParseNIMCatalogue(data []byte) (*NIMCatalogue, error) {
+	var c NIMCatalogue
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid NIM catalogue: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range c.NIMs {
+		n := &c.NIMs[i]
+		if !nimNamePattern.MatchString(n.Name) {
+			return nil, fmt.Errorf("invalid NIM catalogue: name %q must be lowercase letters, digits, dots and dashes", n.Name)
+		}
+		if seen[n.Name] {
+			return nil, fmt.Errorf("invalid NIM catalogue: %s is listed twice", n.Name)
+		}
+		seen[n.Name] = true
+		if !strings.Contains(n.Image[strings.LastIndex(n.Image, "/")+1:], ":") {
+			return nil, fmt.Errorf("invalid NIM catalogue: %s: image %q needs a tag", n.Name, n.Image)
+		}
+		if n.Port == 0 {
+			n.Port = nimDefaultPort
+		}
+		if n.ShmSize == "" {
+			n.ShmSize = nimDefaultShmSize
+		}
+	}
+	return &c, nil
+}
+
+// Lookup finds a NIM by catalogue name or image. Images that are not in the
+// catalogue are run with the default port and shared memory size.
+func (c *NIMCatalogue) Lookup(ref string) (NIM, error) {
+	for _, n := range c.NIMs {
+		if n.Name == ref || n.Image == ref {
+			return n, nil
+		}
+	}
+	if !strings.Contains(ref, "/") {
+		return NIM{}, fmt.Errorf("unknown NIM %q; see dgx run nim list, or give a full image such as nvcr.io/nim/meta/llama-3.1-8b-instruct-dgx-spark:1.0.0", ref)
+	}
+	return NIM{Name: ref, Image: ref, Port: nimDefaultPort, ShmSize: nimDefaultShmSize}, nil
+}
+
+// runNIM handles NVIDIA Inference Microservice commands
+func (m *Manager) runNIM(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("nim command required. Usage: dgx run nim <login|list|catalogue|deploy|status|stop|logs>")
+	}
+
+	command := args[0]
+	withTunnel, args := popFlag(args, "--tunnel")
+
+	switch command {
+	case "login":
+		return m.nimLogin()
+	case "list":
+		return nimList()
+	case "catalogue":
+		fmt.Print(string(embeddedNIMCatalogue))
+		return nil
+	case "deploy":
+		if len(args) != 2 {
+			return fmt.Errorf("NIM name or image required. Usage: dgx run nim deploy <nim-image> [--tunnel]")
+		}
+		return m.nimDeploy(args[1], withTunnel)
+	case "status":
+		return m.nimStatus()
+	case "stop":
+		return m.nimStop()
+	case "logs":
+		return m.nimLogs(args[1:])
+	default:
+		return fmt.Errorf("unknown nim command: %s", command)
+	}
+}
+
+// nimKeyCheck fails a remote script early when no NGC key is stored
+const nimKeyCheck = `if [ -z "${NGC_API_KEY:-}" ]; then
+  echo "NGC_API_KEY is not set; store it with: dgx env ngc-key" >&2
+  exit 1
+fi
+`
+
+// nimLogin logs Docker on the DGX in to nvcr.io with the stored NGC key
+func (m *Manager) nimLogin() error {
+	script := nimKeyCheck + `printf '%s' "$NGC_API_KEY" | docker login nvcr.io --username '$oauthtoken' --password-stdin`
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("nvcr.io login failed: %w", err)
+	}
+	return nil
+}
+
+// nimList prints the catalogue
+func nimList() error {
+	c, err := LoadNIMCatalogue()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("NIM catalogue: %s (version %s)\n\n", c.Source, c.Version)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPORT\tIMAGE\tDESCRIPTION")
+	for _, n := range c.NIMs {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", n.Name, n.Port, n.Image, n.Description)
+	}
+	w.Flush()
+	fmt.Println("\nDeploy one with: dgx run nim deploy <name> --tunnel")
+	return nil
+}
+
+// nimDeploy starts a NIM with the model cache mounted and waits until it is
+// ready, through the tunnel when one is requested
+func (m *Manager) nimDeploy(ref string, withTunnel bool) error {
+	c, err := LoadNIMCatalogue()
+	if err != nil {
+		return err
+	}
+	n, err := c.Lookup(ref)
+	if err != nil {
+		return err
+	}
+	service, err := tunnel.LookupService("nim")
+	if err != nil {
+		return err
+	}
+	if n.Name == n.Image {
+		fmt.Printf("%s is not in the NIM catalogue; assuming its API is on port %d\n", n.Image, n.Port)
+	}
+
+	// vLLM serves on the same port
+	script := nimKeyCheck + fmt.Sprintf(`if docker container inspect %[1]s >/dev/null 2>&1; then
+  echo "%[1]s already exists; stop it first with: dgx run nim stop" >&2
+  exit 1
+fi
+`, nimContainer) + portFreeCheck(service.Port, "deploying a NIM") + fmt.Sprintf(`mkdir -p %[2]s
+docker run -d --name %[1]s \
+  --gpus all \
+  --shm-size=%[4]s \
+  -u "$(id -u)" \
+  -e NGC_API_KEY \
+  -v %[2]s:/opt/nim/.cache \
+  -p %[5]d:%[6]d \
+  %[3]s`, nimContainer, nimCacheDir, shellQuote(n.Image), shellQuote(n.ShmSize), service.Port, n.Port) + removeOnFailure(nimContainer)
+
+	fmt.Printf("Starting %s...\n", n.Image)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to start %s: %w", n.Name, err)
+	}
+
+	if withTunnel {
+		if err := m.openTunnel(service.Name, nimReadyTimeout); err != nil {
+			return err
+		}
+	} else {
+		url := fmt.Sprintf("http://localhost:%d%s", service.Port, service.HealthPath)
+		if err := m.waitReady(n.Name, url, nimReadyTimeout); err != nil {
+			return err
+		}
+		fmt.Println("\nTo access the API:")
+		fmt.Println("  dgx tunnel open nim")
+	}
+	fmt.Println("\nTo check logs:")
+	fmt.Println("  dgx run nim logs --follow")
+	return nil
+}
+
+// nimStatus prints the NIM container and its readiness
+func (m *Manager) nimStatus() error {
+	fmt.Println("Checking NIM status...")
+
+	status, err := m.nimState()
+	if err != nil {
+		return err
+	}
+	if status.Container == "" {
+		fmt.Println("No NIM is deployed")
+		fmt.Println("\nTo deploy one:")
+		fmt.Println("  dgx run nim list")
+		fmt.Println("  dgx run nim deploy <name>")
+		return nil
+	}
+
+	fmt.Printf("NIM container: %s\n", status.Container)
+	if status.Health != "" {
+		fmt.Printf("\nReadiness: %s\n", status.Health)
+	}
+	return nil
+}
+
+// nimState reports the NIM container and its readiness endpoint
+func (m *Manager) nimState() (types.PlaybookStatus, error) {
+	status, _, err := m.containerState("nim", nimContainer)
+	if err != nil || !status.Running {
+		return status, err
+	}
+	service, err := tunnel.LookupService("nim")
+	if err != nil {
+		return status, err
+	}
+	status.Health = "not ready"
+	if m.answers(service) {
+		status.Health = "ready"
+	}
+	return status, nil
+}
+
+// nimStop removes the NIM container; the model cache is kept
+func (m *Manager) nimStop() error {
+	fmt.Println("Stopping NIM...")
+	if _, err := m.sshClient.Execute("docker rm -f " + nimContainer); err != nil {
+		return fmt.Errorf("failed to stop NIM: %w", err)
+	}
+	fmt.Printf("NIM stopped and removed (model cache kept in %s)\n", nimCacheDir)
+	return nil
+}
+
+// nimLogs streams the NIM container logs; extra args go to docker logs
+func (m *Manager) nimLogs(args []string) error {
+	return m.dockerLogs(nimContainer, args)
+}
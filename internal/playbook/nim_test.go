@@ -0,0 +1,64 @@
+package playbook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedNIMCatalogue(t *testing.T) {
+	c, err := ParseNIMCatalogue(EmbeddedNIMCatalogue())
+	if err != nil {
+		t.Fatalf("embedded catalogue: %v", err)
+	}
+	if len(c.NIMs) == 0 {
+		t.Fatalf("expected NIMs in the embedded catalogue")
+	}
+	for _, n := range c.NIMs {
+		if n.Port == 0 || n.ShmSize == "" {
+			t.Fatalf("%s: defaults not filled in: %+v", n.Name, n)
+		}
+	}
+}
+
+func TestNIMCatalogueLookup(t *testing.T) {
+	c, err := ParseNIMCatalogue([]byte(`version: "1"
+nims:
+  - name: llama-3.1-8b-instruct
+    image: nvcr.io/nim/meta/llama-3.1-8b-instruct-dgx-spark:1.0.0
+    port: 9000
+    shm_size: 32g
+`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	for _, ref := range []string{"llama-3.1-8b-instruct", "nvcr.io/nim/meta/llama-3.1-8b-instruct-dgx-spark:1.0.0"} {
+		n, err := c.Lookup(ref)
+		if err != nil || n.Port != 9000 || n.ShmSize != "32g" {
+			t.Fatalf("%s: unexpected %+v (%v)", ref, n, err)
+		}
+	}
+
+	n, err := c.Lookup("nvcr.io/nim/qwen/qwen3-32b-dgx-spark:1.0.0")
+	if err != nil || n.Port != nimDefaultPort || n.ShmSize != nimDefaultShmSize {
+		t.Fatalf("expected defaults for an image outside the catalogue, got %+v (%v)", n, err)
+	}
+	if _, err := c.Lookup("qwen3"); err == nil || !strings.Contains(err.Error(), "dgx run nim list") {
+		t.Fatalf("expected an unknown NIM error, got %v", err)
+	}
+}
+
+func TestParseNIMCatalogueRejectsMistakes(t *testing.T) {
+	tests := map[string]string{
+		"untagged":      "nims: [{name: a, image: nvcr.io/nim/meta/a}]",
+		"registry port": "nims: [{name: a, image: 'registry:5000/nim/a'}]",
+		"duplicate":     "nims: [{name: a, image: 'nim/a:1'}, {name: a, image: 'nim/b:1'}]",
+		"bad name":      "nims: [{name: A, image: 'nim/a:1'}]",
+		"unknown field": "nims: [{name: a, image: 'nim/a:1', ports: 8000}]",
+	}
+	for name, data := range tests {
+		if _, err := ParseNIMCatalogue([]byte(data)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package playbook
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
 // runOllama handles Ollama playbook commands
@@ -12,6 +15,7 @@ func (m *Manager) runOllama(args []string) error {
 	}
 
 	command := args[0]
+	withTunnel, args := popFlag(args, "--tunnel")
 
 	switch command {
 	case "install":
@@ -24,7 +28,13 @@ func (m *Manager) runOllama(args []string) error {
 	case "list":
 		return m.ollamaList()
 	case "serve":
-		return m.ollamaServe()
+		if err := m.ollamaServe(withTunnel); err != nil {
+			return err
+		}
+		if withTunnel {
+			return m.openTunnel("ollama", time.Minute)
+		}
+		return nil
 	case "status":
 		return m.ollamaStatus()
 	case "run":
@@ -82,7 +92,7 @@ func (m *Manager) ollamaList() error {
 }
 
 // ollamaServe starts the Ollama service
-func (m *Manager) ollamaServe() error {
+func (m *Manager) ollamaServe(withTunnel bool) error {
 	fmt.Println("Starting Ollama service...")
 	fmt.Println("Note: This will run in the background on your DGX")
 
@@ -93,9 +103,10 @@ func (m *Manager) ollamaServe() error {
 
 	pid := strings.TrimSpace(output)
 	fmt.Printf("Ollama service started (PID: %s)\n", pid)
-	fmt.Println("\nTo access Ollama API:")
-	fmt.Println("  1. Create a tunnel: dgx tunnel create 11434:11434 \"Ollama\"")
-	fmt.Println("  2. Access at: http://localhost:11434")
+	if !withTunnel {
+		fmt.Println("\nTo access Ollama API:")
+		fmt.Println("  dgx tunnel open ollama")
+	}
 	return nil
 }
 
@@ -103,24 +114,45 @@ func (m *Manager) ollamaServe() error {
 func (m *Manager) ollamaStatus() error {
 	fmt.Println("Checking Ollama status...")
 
-	output, err := m.sshClient.Execute("pgrep -f 'ollama serve'")
-	if err != nil || output == "" {
+	status, err := m.ollamaState()
+	if err != nil {
+		return err
+	}
+
+	if !status.Running {
 		fmt.Println("Ollama is not running")
 		fmt.Println("\nTo start Ollama:")
 		fmt.Println("  dgx run ollama serve")
 		return nil
 	}
 
-	pids := strings.TrimSpace(output)
-	fmt.Printf("Ollama is running (PID: %s)\n", pids)
+	fmt.Printf("Ollama is running (PID: %s)\n", status.PID)
+	if status.Version != "" {
+		fmt.Printf("Version: %s\n", status.Version)
+	}
+
+	return nil
+}
+
+// ollamaState reports the Ollama server processes and version
+func (m *Manager) ollamaState() (types.PlaybookStatus, error) {
+	status := types.PlaybookStatus{Playbook: "ollama"}
+
+	output, err := m.sshClient.Execute("pgrep -f 'ollama serve'")
+	if err != nil || strings.TrimSpace(output) == "" {
+		return status, nil
+	}
+
+	status.Running = true
+	status.PID = strings.Join(strings.Fields(output), ",")
 
 	// Try to get version
 	version, err := m.sshClient.Execute("ollama --version")
 	if err == nil {
-		fmt.Printf("Version: %s\n", strings.TrimSpace(version))
+		status.Version = strings.TrimSpace(version)
 	}
 
-	return nil
+	return status, nil
 }
 
 // ollamaRun runs a model with an optional prompt
@@ -2,21 +2,29 @@ package playbook
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
 // Playbook represents a DGX Spark workflow
 type Playbook struct {
-	Name        string
-	Description string
-	Category    string
-	Commands    []string
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Commands    []string `json:"commands,omitempty"`
+	Source      string   `json:"source,omitempty"` // YAML playbooks: "built-in" or the file
 }
 
 // Manager handles DGX Spark playbook execution
 type Manager struct {
 	sshClient *ssh.Client
+
+	// OpenTunnel opens a named service tunnel (see 'dgx tunnel open') and
+	// waits up to the given duration for it to answer. Set by the CLI;
+	// 'serve --tunnel' is unavailable when nil.
+	OpenTunnel func(service string, wait time.Duration) error
 }
 
 // NewManager creates a new playbook manager
@@ -26,6 +34,13 @@ func NewManager(client *ssh.Client) *Manager {
 	}
 }
 
+// Container images the Go playbooks pin. YAML playbooks pin theirs under
+// images; see PinnedImages.
+const (
+	VLLMImage    = "nvcr.io/nvidia/vllm:25.09-py3"
+	PyTorchImage = "nvcr.io/nvidia/pytorch:25.11-py3" // Base of the fine-tuning images
+)
+
 // Available playbook categories
 const (
 	CategoryInference   = "Inference & Serving"
@@ -35,8 +50,31 @@ const (
 	CategoryAdvanced    = "Advanced Applications"
 )
 
-// GetAvailablePlaybooks returns a list of all available playbooks
+// GetAvailablePlaybooks returns a list of all available playbooks: the
+// catalogue below, with YAML playbooks replacing entries of the same name
+// and appended after them
 func GetAvailablePlaybooks() []Playbook {
+	list := catalogue()
+	specs, _ := Specs()
+	for _, s := range specs {
+		p := Playbook{Name: s.Name, Description: s.Description, Category: s.Category, Commands: s.CommandNames(), Source: s.Source}
+		replaced := false
+		for i := range list {
+			if list[i].Name == s.Name {
+				list[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// catalogue is the DGX Spark playbook list, including those not yet
+// implemented
+func catalogue() []Playbook {
 	return []Playbook{
 		// Inference & Serving
 		{
@@ -147,16 +185,74 @@ func (m *Manager) Execute(playbookName string, args []string) error {
 		return err
 	}
 
+	if s := findSpec(playbookName); s != nil {
+		return m.runSpec(s, args)
+	}
+
 	switch playbookName {
 	case "ollama":
 		return m.runOllama(args)
 	case "vllm":
 		return m.runVLLM(args)
-	case "nvfp4":
-		return m.runNVFP4(args)
 	case "dmr":
 		return m.runDMR(args)
+	case "nim":
+		return m.runNIM(args)
+	case "speculative-decoding":
+		return m.runSpeculative(args)
+	case "llama-factory":
+		return m.runLlamaFactory(args)
+	case "unsloth":
+		return m.runUnsloth(args)
 	default:
 		return fmt.Errorf("playbook '%s' is not yet implemented", playbook.Name)
 	}
 }
+
+// Status returns the machine-readable status of a playbook's service
+func (m *Manager) Status(playbookName string) (types.PlaybookStatus, error) {
+	if _, err := GetPlaybook(playbookName); err != nil {
+		return types.PlaybookStatus{}, err
+	}
+	if s := findSpec(playbookName); s != nil {
+		return m.specState(s)
+	}
+
+	switch playbookName {
+	case "ollama":
+		return m.ollamaState()
+	case "vllm":
+		return m.vllmState()
+	case "dmr":
+		return m.dmrState()
+	case "nim":
+		return m.nimState()
+	case "speculative-decoding":
+		return m.specDecodeState()
+	default:
+		return types.PlaybookStatus{}, fmt.Errorf("playbook '%s' does not report status", playbookName)
+	}
+}
+
+// openTunnel opens a service tunnel after a 'serve --tunnel'
+func (m *Manager) openTunnel(service string, wait time.Duration) error {
+	if m.OpenTunnel == nil {
+		return fmt.Errorf("tunnel support is not available")
+	}
+	fmt.Println()
+	return m.OpenTunnel(service, wait)
+}
+
+// popFlag removes a boolean flag from args and reports whether it was present
+func popFlag(args []string, name string) (bool, []string) {
+	found := false
+	rest := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return found, rest
+}
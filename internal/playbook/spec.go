@@ -0,0 +1,451 @@
+package playbook
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/config"
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"gopkg.in/yaml.v3"
+)
+
+// CategoryCustom is the category of YAML playbooks that do not name one
+const CategoryCustom = "Custom"
+
+// defaultHealthTimeout bounds waiting for a playbook's health check
+const defaultHealthTimeout = 5 * time.Minute
+
+//go:embed playbooks/*.yaml
+var embeddedSpecs embed.FS
+
+// goPlaybooks are implemented in Go; YAML playbooks cannot replace them.
+// Each needs something a command script cannot do:
+//   - ollama installs through an interactive sudo prompt and runs as a host
+//     service, so its status is a PID and version rather than a container
+//   - vllm serves a Hub model or mounts a DGX directory, and its image is
+//     pinned for speculative-decoding
+//   - dmr passes extra docker model flags through to logs, list and pull,
+//     and has no container of its own
+//   - nim picks the image, port and shared memory from the NIM catalogue
+//   - speculative-decoding compares tokenizers and benchmark results locally
+//   - llama-factory renders training configs locally and syncs datasets and
+//     exports with rsync
+//   - unsloth syncs datasets, streams training and registers GGUF exports
+//     with Ollama
+var goPlaybooks = []string{"ollama", "vllm", "dmr", "nim", "speculative-decoding", "llama-factory", "unsloth"}
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	paramPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+)
+
+// Spec is a playbook defined in YAML: metadata, the images and ports it
+// uses, and subcommands whose scripts run on the DGX
+type Spec struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Category    string            `yaml:"category"`
+	Images      map[string]string `yaml:"images"`    // Pinned container images, by key
+	Ports       map[string]int    `yaml:"ports"`     // DGX ports, by key
+	Container   string            `yaml:"container"` // Container reported by status -o json
+	Service     string            `yaml:"service"`   // Tunnel service opened by --tunnel
+	Health      *HealthSpec       `yaml:"health"`
+	Prelude     string            `yaml:"prelude"` // Shell run before every script, such as shared functions
+	Commands    []CommandSpec     `yaml:"commands"`
+
+	// Source is "built-in" or the file the playbook was loaded from
+	Source string `yaml:"-"`
+}
+
+// HealthSpec is an HTTP endpoint on the DGX that answers once the
+// playbook's service is ready
+type HealthSpec struct {
+	URL     string        `yaml:"url"` // Template, such as http://localhost:{{.Ports.api}}/health
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// CommandSpec is one subcommand of a YAML playbook
+type CommandSpec struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Params      []ParamSpec `yaml:"params"`
+	Script      string      `yaml:"script"` // Template run with bash -e on the DGX
+	Wait        bool        `yaml:"wait"`   // Wait for the health check after the script
+	Tunnel      bool        `yaml:"tunnel"` // Accept --tunnel to open the service tunnel
+	Hints       []string    `yaml:"hints"`  // Templates printed after success
+	Examples    []string    `yaml:"examples"`
+}
+
+// ParamSpec is a command parameter, given as --name value or, when
+// positional, in order after the command
+type ParamSpec struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Type        string   `yaml:"type"` // string (default), int or bool
+	Positional  bool     `yaml:"positional"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Choices     []string `yaml:"choices"`
+	Pattern     string   `yaml:"pattern"` // Regular expression the whole value must match
+}
+
+// Key is the parameter's name in templates: dashes become underscores, so
+// --max-batch is {{.Params.max_batch}}
+func (p ParamSpec) Key() string {
+	return strings.ReplaceAll(p.Name, "-", "_")
+}
+
+// ParseSpec parses and validates a YAML playbook
+func ParseSpec(data []byte) (*Spec, error) {
+	var s Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("invalid playbook: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		if s.Name != "" {
+			return nil, fmt.Errorf("playbook %s: %w", s.Name, err)
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Spec) validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits and dashes", s.Name)
+	}
+	for _, name := range goPlaybooks {
+		if s.Name == name {
+			return fmt.Errorf("name %q is taken by a built-in playbook", s.Name)
+		}
+	}
+	if s.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+	if s.Category == "" {
+		s.Category = CategoryCustom
+	}
+	if s.Service != "" {
+		if _, err := tunnel.LookupService(s.Service); err != nil {
+			return err
+		}
+	}
+	if s.Health != nil {
+		if s.Health.URL == "" {
+			return fmt.Errorf("health needs a url")
+		}
+		if s.Health.Timeout <= 0 {
+			s.Health.Timeout = defaultHealthTimeout
+		}
+		if err := s.checkTemplate("health url", s.Health.URL, nil); err != nil {
+			return err
+		}
+	}
+	if err := s.checkTemplate("prelude", s.Prelude, nil); err != nil {
+		return err
+	}
+	if len(s.Commands) == 0 {
+		return fmt.Errorf("at least one command is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range s.Commands {
+		if !namePattern.MatchString(c.Name) {
+			return fmt.Errorf("command name %q must be lowercase letters, digits and dashes", c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("command %q is defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		if err := s.validateCommand(c); err != nil {
+			return fmt.Errorf("command %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Spec) validateCommand(c CommandSpec) error {
+	if strings.TrimSpace(c.Script) == "" {
+		return fmt.Errorf("script is required")
+	}
+	if c.Wait && s.Health == nil {
+		return fmt.Errorf("wait needs a playbook health check")
+	}
+	if c.Tunnel && s.Service == "" {
+		return fmt.Errorf("tunnel needs a playbook service")
+	}
+
+	seen := make(map[string]bool)
+	optional := false
+	for _, p := range c.Params {
+		if !paramPattern.MatchString(p.Name) || p.Name == "tunnel" {
+			return fmt.Errorf("invalid parameter name %q", p.Name)
+		}
+		if seen[p.Key()] {
+			return fmt.Errorf("parameter %q is defined twice", p.Name)
+		}
+		seen[p.Key()] = true
+		switch p.Type {
+		case "", "string", "int", "bool":
+		default:
+			return fmt.Errorf("parameter %s: unknown type %q (valid: string, int, bool)", p.Name, p.Type)
+		}
+		if p.Positional {
+			if p.Type == "bool" {
+				return fmt.Errorf("parameter %s: bool parameters cannot be positional", p.Name)
+			}
+			if p.Required && optional {
+				return fmt.Errorf("parameter %s: required positional parameters must come first", p.Name)
+			}
+			optional = optional || !p.Required
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("parameter %s: invalid pattern: %w", p.Name, err)
+			}
+		}
+		if p.Default != "" {
+			if _, err := p.convert(p.Default); err != nil {
+				return fmt.Errorf("default: %w", err)
+			}
+		}
+	}
+
+	if err := s.checkTemplate("script", c.Script, c.Params); err != nil {
+		return err
+	}
+	for _, hint := range c.Hints {
+		if err := s.checkTemplate("hint", hint, c.Params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTemplate parses a template and renders it with zero-valued
+// parameters so typos in image, port and parameter names fail at load
+func (s *Spec) checkTemplate(what, text string, params []ParamSpec) error {
+	values := make(map[string]any)
+	for _, p := range params {
+		values[p.Key()] = p.zero()
+	}
+	if _, err := s.render(text, values); err != nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return nil
+}
+
+// convert validates a parameter value and converts it to its type
+func (p ParamSpec) convert(value string) (any, error) {
+	if len(p.Choices) > 0 {
+		found := false
+		for _, c := range p.Choices {
+			found = found || c == value
+		}
+		if !found {
+			return nil, fmt.Errorf("--%s must be one of %s, got %q", p.Name, strings.Join(p.Choices, ", "), value)
+		}
+	}
+	if p.Pattern != "" && !regexp.MustCompile(`^(?:`+p.Pattern+`)$`).MatchString(value) {
+		return nil, fmt.Errorf("invalid %s %q", p.Name, value)
+	}
+	switch p.Type {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("--%s must be a number, got %q", p.Name, value)
+		}
+		return n, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("--%s must be true or false, got %q", p.Name, value)
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// templateData is what script, hint and health templates see
+type templateData struct {
+	Name   string
+	Params map[string]any
+	Images map[string]string
+	Ports  map[string]int
+}
+
+var templateFuncs = template.FuncMap{
+	"quote": func(v any) string { return shellQuote(fmt.Sprint(v)) },
+}
+
+// render executes a template against the playbook and parameter values.
+// Unknown image, port and parameter names are errors.
+func (s *Spec) render(text string, params map[string]any) (string, error) {
+	t, err := template.New(s.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	data := templateData{Name: s.Name, Params: params, Images: s.Images, Ports: s.Ports}
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// command returns the named subcommand, or nil
+func (s *Spec) command(name string) *CommandSpec {
+	for i := range s.Commands {
+		if s.Commands[i].Name == name {
+			return &s.Commands[i]
+		}
+	}
+	return nil
+}
+
+// CommandNames lists the subcommands in definition order
+func (s *Spec) CommandNames() []string {
+	names := make([]string, 0, len(s.Commands))
+	for _, c := range s.Commands {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// UserDir is where teams add their own YAML playbooks
+// (~/.config/dgx/playbooks)
+func UserDir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "playbooks"), nil
+}
+
+var (
+	specsOnce sync.Once
+	specs     []*Spec
+	specErrs  []error
+)
+
+// Specs returns the YAML playbooks: the embedded set plus the user
+// directory, where a file replaces an embedded playbook of the same name.
+// Files that fail to load are returned as errors and skipped.
+func Specs() ([]*Spec, []error) {
+	specsOnce.Do(func() {
+		dir, err := UserDir()
+		specs, specErrs = loadSpecs(embeddedSpecs, dir)
+		if err != nil {
+			specErrs = append(specErrs, err)
+		}
+	})
+	return specs, specErrs
+}
+
+// loadSpecs loads the embedded playbooks and then those in dir, if any
+func loadSpecs(embedded fs.FS, dir string) ([]*Spec, []error) {
+	byName := make(map[string]*Spec)
+	var errs []error
+
+	files, _ := fs.Glob(embedded, "playbooks/*.yaml")
+	for _, f := range files {
+		data, err := fs.ReadFile(embedded, f)
+		if err == nil {
+			var s *Spec
+			if s, err = ParseSpec(data); err == nil {
+				s.Source = "built-in"
+				byName[s.Name] = s
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f, err))
+		}
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			s, err := LoadSpec(path)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			byName[s.Name] = s
+		}
+	}
+
+	list := make([]*Spec, 0, len(byName))
+	for _, s := range byName {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, errs
+}
+
+// LoadSpec reads a YAML playbook from a file
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSpec(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Source = path
+	return s, nil
+}
+
+// findSpec returns the YAML playbook with the given name, or nil
+func findSpec(name string) *Spec {
+	list, _ := Specs()
+	for _, s := range list {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// PinnedImages returns every container image the built-in playbooks pin
+func PinnedImages() []string {
+	seen := map[string]bool{VLLMImage: true, PyTorchImage: true}
+	images := []string{VLLMImage, PyTorchImage}
+	list, _ := loadSpecs(embeddedSpecs, "")
+	for _, s := range list {
+		keys := make([]string, 0, len(s.Images))
+		for k := range s.Images {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if img := s.Images[k]; !seen[img] {
+				seen[img] = true
+				images = append(images, img)
+			}
+		}
+	}
+	return images
+}
@@ -0,0 +1,162 @@
+package playbook
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedSpecsLoad(t *testing.T) {
+	specs, errs := loadSpecs(embeddedSpecs, "")
+	if len(errs) > 0 {
+		t.Fatalf("embedded playbooks failed to load: %v", errs)
+	}
+	if len(specs) == 0 {
+		t.Fatalf("expected embedded playbooks")
+	}
+	for _, s := range specs {
+		if s.Source != "built-in" {
+			t.Fatalf("%s: expected built-in source, got %s", s.Name, s.Source)
+		}
+		if _, err := GetPlaybook(s.Name); err != nil {
+			t.Fatalf("%s is not listed: %v", s.Name, err)
+		}
+	}
+}
+
+func TestUserDirReplacesEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"nvfp4.yaml", "echo.yaml"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "broken.yml"), []byte("name: Broken\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a playbook"), 0o644)
+
+	specs, errs := loadSpecs(embeddedSpecs, dir)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.yml") {
+		t.Fatalf("expected one error for broken.yml, got %v", errs)
+	}
+	found := 0
+	for _, s := range specs {
+		switch s.Name {
+		case "nvfp4":
+			found++
+			if s.Images["tensorrt"] != "nvcr.io/nvidia/tensorrt:26.01-py3" || s.Source != filepath.Join(dir, "nvfp4.yaml") {
+				t.Fatalf("user nvfp4 should replace the embedded one: %+v", s)
+			}
+		case "echo-server":
+			found++
+			if s.Category != CategoryCustom || s.Health.Timeout != defaultHealthTimeout {
+				t.Fatalf("expected defaults to be filled in: %+v", s)
+			}
+		}
+	}
+	if found != 2 {
+		t.Fatalf("expected nvfp4 and echo-server, got %d", found)
+	}
+}
+
+func TestParseSpecRejectsMistakes(t *testing.T) {
+	base := "name: demo\ndescription: Demo\n"
+	tests := map[string]string{
+		"built-in name":    "name: vllm\ndescription: x\ncommands: [{name: a, script: b}]\n",
+		"no commands":      base,
+		"unknown field":    base + "commands: [{name: a, script: b, scirpt: c}]\n",
+		"unknown image":    base + "commands: [{name: a, script: '{{.Images.vllm}}'}]\n",
+		"unknown param":    base + "commands: [{name: a, script: '{{.Params.model}}'}]\n",
+		"bad template":     base + "commands: [{name: a, script: '{{.Params'}]\n",
+		"prelude params":   base + "prelude: '{{.Params.model}}'\ncommands: [{name: a, script: b}]\n",
+		"wait, no health":  base + "commands: [{name: a, script: b, wait: true}]\n",
+		"unknown service":  base + "service: nope\ncommands: [{name: a, script: b}]\n",
+		"bad default":      base + "commands: [{name: a, script: b, params: [{name: n, type: int, default: x}]}]\n",
+		"bad choice":       base + "commands: [{name: a, script: b, params: [{name: n, choices: [x], default: y}]}]\n",
+		"duplicate":        base + "commands: [{name: a, script: b}, {name: a, script: c}]\n",
+		"optional first":   base + "commands: [{name: a, script: b, params: [{name: x, positional: true}, {name: y, positional: true, required: true}]}]\n",
+		"positional bool":  base + "commands: [{name: a, script: b, params: [{name: x, positional: true, type: bool}]}]\n",
+		"reserved tunnel":  base + "commands: [{name: a, script: b, params: [{name: tunnel}]}]\n",
+		"tunnel, no svc":   base + "commands: [{name: a, script: b, tunnel: true}]\n",
+		"invalid pattern":  base + "commands: [{name: a, script: b, params: [{name: x, pattern: '('}]}]\n",
+		"invalid cmd name": base + "commands: [{name: Serve, script: b}]\n",
+	}
+	for name, data := range tests {
+		if _, err := ParseSpec([]byte(data)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseArgsAndRender(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "echo.yaml"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	s, err := ParseSpec(data)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	c := s.command("serve")
+
+	params, err := c.parseArgs([]string{"--log-level=debug", "it's here", "--detach", "--replicas", "3"})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if params["text"] != "it's here" || params["replicas"] != 3 || params["log_level"] != "debug" || params["detach"] != true {
+		t.Fatalf("unexpected params: %#v", params)
+	}
+	script, err := s.render(c.Script, params)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	want := `docker run -d --name echo-server -p 5678:5678 hashicorp/http-echo -text='it'"'"'s here' -log=debug --detach`
+	if strings.TrimSpace(script) != want {
+		t.Fatalf("unexpected script:\n%s", script)
+	}
+
+	// --text can also be given as a flag
+	params, err = c.parseArgs([]string{"--text", "hi"})
+	if err != nil || params["replicas"] != 1 || params["log_level"] != "info" || params["detach"] != false {
+		t.Fatalf("expected defaults, got %#v (%v)", params, err)
+	}
+
+	for _, args := range [][]string{
+		{},                          // text is required
+		{"a", "b"},                  // too many positional values
+		{"a", "--replicas", "many"}, // not a number
+		{"a", "--log-level", "trace"},
+		{"a", "--replicas"},
+		{"a", "--unknown"},
+	} {
+		if _, err := c.parseArgs(args); err == nil {
+			t.Fatalf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "echo.yaml"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	s, err := ParseSpec(data)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	help := s.Help()
+	for _, want := range []string{
+		"Tiny HTTP echo service (echo-server) playbook",
+		"  serve       - Start the echo container",
+		"dgx run echo-server serve <text> [--replicas <n>] [--log-level <info|debug>] [--detach]",
+		"(default info)",
+	} {
+		if !strings.Contains(help, want) {
+			t.Fatalf("help is missing %q:\n%s", want, help)
+		}
+	}
+}
@@ -0,0 +1,494 @@
+package playbook
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/tunnel"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+//go:embed speculative-prompts.txt
+var defaultBenchPrompts string
+
+const (
+	specContainer = "spec-decode-server"
+
+	// hfModelPattern matches Hugging Face model IDs such as org/name
+	hfModelPattern = `[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)?`
+
+	// Marks the result lines of the remote Python scripts
+	tokenizersMarker = "TOKENIZERS "
+	benchMarker      = "BENCH "
+)
+
+// Parameters shared by serve and bench
+var specPairParams = []ParamSpec{
+	{Name: "target", Description: "Model to serve", Required: true, Pattern: hfModelPattern},
+	{Name: "draft", Description: "Smaller model from the same family that proposes tokens", Required: true, Pattern: hfModelPattern},
+	{Name: "num-speculative-tokens", Description: "Tokens the draft proposes per step", Type: "int", Default: "5"},
+	{Name: "engine", Description: "Inference engine", Choices: []string{"vllm", "trt-llm"}, Default: "vllm"},
+	{Name: "skip-tokenizer-check", Description: "Start even if the tokenizers differ", Type: "bool"},
+}
+
+var specCommands = []CommandSpec{
+	{
+		Name:        "serve",
+		Description: "Serve the target with the draft model on an OpenAI-compatible API",
+		Params:      specPairParams,
+		Tunnel:      true,
+		Examples: []string{
+			"dgx run speculative-decoding serve --target meta-llama/Llama-3.1-8B-Instruct --draft meta-llama/Llama-3.2-1B-Instruct --tunnel",
+			"dgx run speculative-decoding serve --target Qwen/Qwen2.5-32B-Instruct --draft Qwen/Qwen2.5-0.5B-Instruct --num-speculative-tokens 4",
+		},
+	},
+	{
+		Name:        "bench",
+		Description: "Compare tokens/sec with and without speculation on the same prompts",
+		Params: append(append([]ParamSpec{}, specPairParams...),
+			ParamSpec{Name: "prompts", Description: "Local file with one prompt per line (default: built-in set)"},
+			ParamSpec{Name: "max-tokens", Description: "Tokens to generate per prompt", Type: "int", Default: "256"},
+		),
+		Examples: []string{
+			"dgx run speculative-decoding bench --target Qwen/Qwen2.5-32B-Instruct --draft Qwen/Qwen2.5-0.5B-Instruct",
+			"dgx run speculative-decoding bench --target meta-llama/Llama-3.1-8B-Instruct --draft meta-llama/Llama-3.2-1B-Instruct --prompts prompts.txt --engine trt-llm",
+		},
+	},
+	{Name: "status", Description: "Show the server and its health check"},
+	{Name: "stop", Description: "Stop and remove the server"},
+	{Name: "logs", Description: "Show server logs (pass docker logs flags like --follow)"},
+}
+
+// specPlaybook describes speculative-decoding for usage and help; its
+// commands are implemented in Go
+var specPlaybook = &Spec{
+	Name:        "speculative-decoding",
+	Description: "Faster inference with speculative decoding",
+	Service:     "vllm or trt-llm",
+	Commands:    specCommands,
+}
+
+// runSpeculative handles speculative decoding commands
+func (m *Manager) runSpeculative(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("speculative-decoding command required. Usage: dgx run speculative-decoding <serve|bench|status|stop|logs>")
+	}
+
+	c := specPlaybook.command(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown speculative-decoding command: %s", args[0])
+	}
+	switch c.Name {
+	case "status":
+		return m.specDecodeStatus()
+	case "stop":
+		return m.specDecodeStop()
+	case "logs":
+		return m.specDecodeLogs(args[1:])
+	}
+
+	withTunnel, rest := popFlag(args[1:], "--tunnel")
+	if withTunnel && c.Name != "serve" {
+		return fmt.Errorf("--tunnel is only accepted by serve")
+	}
+	params, err := c.parseArgs(rest)
+	if err != nil {
+		return fmt.Errorf("%w. Usage: %s", err, specPlaybook.usage(c))
+	}
+	pair := specPair{
+		Target:    params["target"].(string),
+		Draft:     params["draft"].(string),
+		NumTokens: params["num_speculative_tokens"].(int),
+		Engine:    params["engine"].(string),
+	}
+	if pair.NumTokens < 1 {
+		return fmt.Errorf("--num-speculative-tokens must be at least 1")
+	}
+	if pair.Target == pair.Draft {
+		return fmt.Errorf("the draft model must differ from the target")
+	}
+
+	var prompts []string
+	if c.Name == "bench" {
+		text := defaultBenchPrompts
+		if path := params["prompts"].(string); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			text = string(data)
+		}
+		if prompts = splitPrompts(text); len(prompts) == 0 {
+			return fmt.Errorf("no prompts to benchmark")
+		}
+	}
+
+	if !params["skip_tokenizer_check"].(bool) {
+		if err := m.checkTokenizers(pair); err != nil {
+			return err
+		}
+	}
+	if c.Name == "serve" {
+		return m.specDecodeServe(pair, withTunnel)
+	}
+	return m.specDecodeBench(pair, prompts, params["max_tokens"].(int))
+}
+
+// specPair is a target model with its draft
+type specPair struct {
+	Target    string
+	Draft     string
+	NumTokens int
+	Engine    string // vllm or trt-llm
+}
+
+// image is the container the pair runs in: the vLLM or trt-llm playbook pin
+func (p specPair) image() string {
+	if p.Engine == "trt-llm" {
+		if s := findSpec("trt-llm"); s != nil && s.Images["trtllm"] != "" {
+			return s.Images["trtllm"]
+		}
+	}
+	return VLLMImage
+}
+
+// service is the tunnel service the engine's API is published as
+func (p specPair) service() (tunnel.Service, error) {
+	if p.Engine == "trt-llm" {
+		return tunnel.LookupService("trt-llm")
+	}
+	return tunnel.LookupService("vllm")
+}
+
+// dockerRun starts a docker run for the pair's image with the GPU, the
+// Hugging Face cache and HF_TOKEN
+func (p specPair) dockerRun(flags string) string {
+	return fmt.Sprintf(`mkdir -p ~/.cache/huggingface
+docker run %s --gpus all --ipc=host \
+  --ulimit memlock=-1 --ulimit stack=67108864 \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN \
+  %s`, flags, p.image())
+}
+
+// tokenizerScript compares the tokenizers of TARGET and DRAFT
+const tokenizerScript = `
+import json, os
+from transformers import AutoTokenizer
+
+target = AutoTokenizer.from_pretrained(os.environ["TARGET"])
+draft = AutoTokenizer.from_pretrained(os.environ["DRAFT"])
+sample = "Speculative decoding on DGX Spark: 3.14159, naïve café, def f(x): return x ** 2\n"
+print("TOKENIZERS " + json.dumps({
+    "target_vocab": len(target),
+    "draft_vocab": len(draft),
+    "target_eos": target.eos_token_id,
+    "draft_eos": draft.eos_token_id,
+    "same_ids": target.encode(sample, add_special_tokens=False) == draft.encode(sample, add_special_tokens=False),
+}))
+`
+
+// tokenizerReport is what tokenizerScript prints
+type tokenizerReport struct {
+	TargetVocab int  `json:"target_vocab"`
+	DraftVocab  int  `json:"draft_vocab"`
+	TargetEOS   *int `json:"target_eos"`
+	DraftEOS    *int `json:"draft_eos"`
+	SameIDs     bool `json:"same_ids"`
+}
+
+// problems lists why the draft cannot propose tokens for the target
+func (r tokenizerReport) problems() []string {
+	var problems []string
+	if r.TargetVocab != r.DraftVocab {
+		problems = append(problems, fmt.Sprintf("vocabulary sizes differ (target %d, draft %d)", r.TargetVocab, r.DraftVocab))
+	}
+	if !r.SameIDs {
+		problems = append(problems, "the tokenizers encode the same text differently")
+	}
+	if (r.TargetEOS == nil) != (r.DraftEOS == nil) || (r.TargetEOS != nil && *r.TargetEOS != *r.DraftEOS) {
+		problems = append(problems, "end-of-sequence tokens differ")
+	}
+	return problems
+}
+
+// checkTokenizers loads both tokenizers on the DGX and fails if the draft's
+// tokens would mean something else to the target
+func (m *Manager) checkTokenizers(p specPair) error {
+	fmt.Printf("Checking that %s and %s share a tokenizer...\n", p.Target, p.Draft)
+	script := p.dockerRun(fmt.Sprintf("--rm -e TARGET=%s -e DRAFT=%s", shellQuote(p.Target), shellQuote(p.Draft))) +
+		" python3 -c " + shellQuote(tokenizerScript)
+
+	var stdout, stderr bytes.Buffer
+	if err := m.sshClient.ExecuteStream(remoteScript(script), &stdout, &stderr); err != nil {
+		os.Stderr.Write(stderr.Bytes())
+		return fmt.Errorf("failed to load the tokenizers: %w", err)
+	}
+	lines := markedLines(stdout.String(), tokenizersMarker)
+	if len(lines) == 0 {
+		return fmt.Errorf("tokenizer check printed no result")
+	}
+	var r tokenizerReport
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		return fmt.Errorf("failed to read the tokenizer check: %w", err)
+	}
+	if problems := r.problems(); len(problems) > 0 {
+		return fmt.Errorf("%s cannot draft for %s: %s (pass --skip-tokenizer-check to try anyway)",
+			p.Draft, p.Target, strings.Join(problems, "; "))
+	}
+	fmt.Printf("Tokenizers match (%d tokens)\n", r.TargetVocab)
+	return nil
+}
+
+// serveCommand is the server command line for the pair
+func (p specPair) serveCommand(port int) string {
+	if p.Engine == "trt-llm" {
+		// trtllm-serve reads the speculative config from an options file
+		options := fmt.Sprintf("speculative_config:\n  decoding_type: DraftTarget\n  max_draft_len: %d\n  speculative_model_dir: %s\n", p.NumTokens, p.Draft)
+		return fmt.Sprintf("bash -c %s", shellQuote(fmt.Sprintf(
+			"printf '%%s' %s > /tmp/spec.yaml && exec trtllm-serve %s --backend pytorch --extra_llm_api_options /tmp/spec.yaml --host 0.0.0.0 --port %d",
+			shellQuote(options), shellQuote(p.Target), port)))
+	}
+	config, _ := json.Marshal(map[string]any{"model": p.Draft, "num_speculative_tokens": p.NumTokens})
+	return fmt.Sprintf("vllm serve %s --speculative-config %s --host 0.0.0.0 --port %d",
+		shellQuote(p.Target), shellQuote(string(config)), port)
+}
+
+// specDecodeServe starts the server and waits until it answers
+func (m *Manager) specDecodeServe(p specPair, withTunnel bool) error {
+	service, err := p.service()
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf(`if docker container inspect %[1]s >/dev/null 2>&1; then
+  echo "%[1]s already exists; stop it first with: dgx run speculative-decoding stop" >&2
+  exit 1
+fi
+`, specContainer) + portFreeCheck(service.Port, "serving with speculative decoding") +
+		p.dockerRun(fmt.Sprintf("-d --name %s -p %d:%d", specContainer, service.Port, service.Port)) +
+		" " + p.serveCommand(service.Port) + removeOnFailure(specContainer)
+
+	fmt.Printf("Starting %s with draft %s (%d speculative tokens) on %s...\n", p.Target, p.Draft, p.NumTokens, p.Engine)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to start the speculative decoding server: %w", err)
+	}
+
+	// Both models download and load on first start
+	wait := 20 * time.Minute
+	if withTunnel {
+		if err := m.openTunnel(service.Name, wait); err != nil {
+			return err
+		}
+	} else {
+		url := fmt.Sprintf("http://localhost:%d%s", service.Port, service.HealthPath)
+		if err := m.waitReady("speculative-decoding", url, wait); err != nil {
+			return err
+		}
+		fmt.Println("\nTo access the API:")
+		fmt.Printf("  dgx tunnel open %s\n", service.Name)
+	}
+	fmt.Println("\nTo check logs:")
+	fmt.Println("  dgx run speculative-decoding logs --follow")
+	return nil
+}
+
+// benchScript generates PROMPTS with TARGET, drafting with DRAFT when set,
+// and prints the generated tokens and the time taken. A first prompt warms
+// up the engine so loading and compilation are not counted.
+const benchScript = `
+import json, os, time
+
+if os.environ["ENGINE"] == "trt-llm":
+    from tensorrt_llm import LLM, SamplingParams
+    from tensorrt_llm.llmapi import DraftTargetDecodingConfig
+    def speculative(draft, n):
+        return DraftTargetDecodingConfig(max_draft_len=n, speculative_model_dir=draft)
+else:
+    from vllm import LLM, SamplingParams
+    def speculative(draft, n):
+        return {"model": draft, "num_speculative_tokens": n}
+
+prompts = json.loads(os.environ["PROMPTS"])
+draft = os.environ.get("DRAFT", "")
+kwargs = {"speculative_config": speculative(draft, int(os.environ["NUM_SPEC"]))} if draft else {}
+llm = LLM(model=os.environ["TARGET"], **kwargs)
+params = SamplingParams(temperature=0, max_tokens=int(os.environ["MAX_TOKENS"]))
+
+llm.generate(prompts[:1], params)
+start = time.perf_counter()
+outputs = llm.generate(prompts, params)
+seconds = time.perf_counter() - start
+tokens = sum(len(o.outputs[0].token_ids) for o in outputs)
+print("BENCH " + json.dumps({"speculative": bool(draft), "tokens": tokens, "seconds": seconds}))
+`
+
+// benchRun is one pass over the prompt set
+type benchRun struct {
+	Speculative bool    `json:"speculative"`
+	Tokens      int     `json:"tokens"`
+	Seconds     float64 `json:"seconds"`
+}
+
+// TokensPerSecond is the generation throughput of the run
+func (r benchRun) TokensPerSecond() float64 {
+	if r.Seconds <= 0 {
+		return 0
+	}
+	return float64(r.Tokens) / r.Seconds
+}
+
+// specDecodeBench runs the prompts without and then with speculation, each
+// in its own container so the first run's memory is freed, and compares them
+func (m *Manager) specDecodeBench(p specPair, prompts []string, maxTokens int) error {
+	encoded, err := json.Marshal(prompts)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, draft := range []string{"", p.Draft} {
+		env := fmt.Sprintf("--rm -e ENGINE=%s -e TARGET=%s -e DRAFT=%s -e NUM_SPEC=%d -e MAX_TOKENS=%d -e PROMPTS=%s",
+			p.Engine, shellQuote(p.Target), shellQuote(draft), p.NumTokens, maxTokens, shellQuote(string(encoded)))
+		sb.WriteString(p.dockerRun(env) + " python3 -c " + shellQuote(benchScript) + "\n")
+	}
+
+	fmt.Printf("Benchmarking %d prompts, %d tokens each, without and then with speculation (%s)...\n", len(prompts), maxTokens, p.Engine)
+	var stdout bytes.Buffer
+	if err := m.sshClient.ExecuteStream(remoteScript(sb.String()), io.MultiWriter(os.Stdout, &stdout), os.Stderr); err != nil {
+		return fmt.Errorf("benchmark failed: %w", err)
+	}
+	baseline, speculative, err := parseBench(stdout.String())
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Print(formatBench(p, baseline, speculative))
+	return nil
+}
+
+// parseBench reads the baseline and speculative runs from bench output
+func parseBench(output string) (baseline, speculative benchRun, err error) {
+	found := 0
+	for _, line := range markedLines(output, benchMarker) {
+		var r benchRun
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			return baseline, speculative, fmt.Errorf("failed to read benchmark result: %w", err)
+		}
+		if r.Speculative {
+			speculative = r
+			found |= 2
+		} else {
+			baseline = r
+			found |= 1
+		}
+	}
+	if found != 3 {
+		return baseline, speculative, fmt.Errorf("benchmark did not report both runs")
+	}
+	return baseline, speculative, nil
+}
+
+// formatBench formats the two runs and the speedup
+func formatBench(p specPair, baseline, speculative benchRun) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tTOKENS\tSECONDS\tTOKENS/SEC")
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%.1f\n", p.Target, baseline.Tokens, baseline.Seconds, baseline.TokensPerSecond())
+	fmt.Fprintf(w, "+ %s, %d tokens\t%d\t%.1f\t%.1f\n", p.Draft, p.NumTokens, speculative.Tokens, speculative.Seconds, speculative.TokensPerSecond())
+	w.Flush()
+
+	if base := baseline.TokensPerSecond(); base > 0 {
+		speedup := speculative.TokensPerSecond() / base
+		fmt.Fprintf(&sb, "\nSpeedup: %.2fx", speedup)
+		if speedup < 1 {
+			sb.WriteString(" (speculation is slower; try fewer speculative tokens or a smaller draft)")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// splitPrompts returns the non-empty lines of a prompt file
+func splitPrompts(text string) []string {
+	var prompts []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			prompts = append(prompts, line)
+		}
+	}
+	return prompts
+}
+
+// markedLines returns the rest of each line that starts with marker
+func markedLines(output, marker string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), marker); ok {
+			lines = append(lines, rest)
+		}
+	}
+	return lines
+}
+
+// specDecodeStatus prints the server container and its health
+func (m *Manager) specDecodeStatus() error {
+	fmt.Println("Checking speculative decoding status...")
+	status, err := m.specDecodeState()
+	if err != nil {
+		return err
+	}
+	if status.Container == "" {
+		fmt.Println("Speculative decoding server is not running")
+		fmt.Println("\nTo start it:")
+		fmt.Println("  dgx run speculative-decoding serve --target <model> --draft <model>")
+		return nil
+	}
+	fmt.Printf("Speculative decoding server: %s\n", status.Container)
+	if status.Health != "" {
+		fmt.Printf("\nHealth check: %s\n", status.Health)
+	}
+	return nil
+}
+
+// specDecodeState reports the server container and its health endpoint
+func (m *Manager) specDecodeState() (types.PlaybookStatus, error) {
+	status, image, err := m.containerState("speculative-decoding", specContainer)
+	if err != nil || !status.Running {
+		return status, err
+	}
+	p := specPair{Engine: "vllm"}
+	if strings.Contains(image, "tensorrt-llm") {
+		p.Engine = "trt-llm"
+	}
+	service, err := p.service()
+	if err != nil {
+		return status, err
+	}
+	status.Health = "not ready"
+	if m.answers(service) {
+		status.Health = "ok"
+	}
+	return status, nil
+}
+
+// specDecodeStop removes the server container
+func (m *Manager) specDecodeStop() error {
+	fmt.Println("Stopping speculative decoding server...")
+	if _, err := m.sshClient.Execute("docker rm -f " + specContainer); err != nil {
+		return fmt.Errorf("failed to stop server: %w", err)
+	}
+	fmt.Println("Speculative decoding server stopped and removed")
+	return nil
+}
+
+// specDecodeLogs streams the server logs; extra args go to docker logs
+func (m *Manager) specDecodeLogs(args []string) error {
+	return m.dockerLogs(specContainer, args)
+}
@@ -0,0 +1,75 @@
+package playbook
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizerProblems(t *testing.T) {
+	eos := func(id int) *int { return &id }
+
+	same := tokenizerReport{TargetVocab: 128256, DraftVocab: 128256, TargetEOS: eos(128009), DraftEOS: eos(128009), SameIDs: true}
+	if problems := same.problems(); len(problems) != 0 {
+		t.Fatalf("expected a compatible pair, got %v", problems)
+	}
+
+	other := tokenizerReport{TargetVocab: 128256, DraftVocab: 32000, TargetEOS: eos(128009), DraftEOS: nil, SameIDs: false}
+	if problems := other.problems(); len(problems) != 3 {
+		t.Fatalf("expected three problems, got %v", problems)
+	}
+}
+
+func TestParseBench(t *testing.T) {
+	output := `INFO loading model
+BENCH {"speculative": false, "tokens": 3072, "seconds": 40.0}
+INFO loading draft
+BENCH {"speculative": true, "tokens": 3072, "seconds": 25.6}
+`
+	baseline, speculative, err := parseBench(output)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if baseline.TokensPerSecond() != 76.8 || speculative.TokensPerSecond() != 120 {
+		t.Fatalf("unexpected runs %+v %+v", baseline, speculative)
+	}
+
+	p := specPair{Target: "meta-llama/Llama-3.1-8B-Instruct", Draft: "meta-llama/Llama-3.2-1B-Instruct", NumTokens: 4}
+	if got := formatBench(p, baseline, speculative); !strings.Contains(got, "Speedup: 1.56x\n") {
+		t.Fatalf("unexpected report:\n%s", got)
+	}
+	if got := formatBench(p, speculative, baseline); !strings.Contains(got, "speculation is slower") {
+		t.Fatalf("expected a slowdown hint:\n%s", got)
+	}
+
+	if _, _, err := parseBench(strings.SplitN(output, "INFO loading draft", 2)[0]); err == nil {
+		t.Fatalf("expected an error when a run is missing")
+	}
+}
+
+func TestServeCommand(t *testing.T) {
+	p := specPair{Target: "Qwen/Qwen2.5-32B-Instruct", Draft: "Qwen/Qwen2.5-0.5B-Instruct", NumTokens: 4, Engine: "vllm"}
+	want := `vllm serve 'Qwen/Qwen2.5-32B-Instruct' --speculative-config '{"model":"Qwen/Qwen2.5-0.5B-Instruct","num_speculative_tokens":4}' --host 0.0.0.0 --port 8000`
+	if got := p.serveCommand(8000); got != want {
+		t.Fatalf("unexpected command:\n%s", got)
+	}
+
+	p.Engine = "trt-llm"
+	if got := p.serveCommand(8355); !strings.Contains(got, "max_draft_len: 4") || !strings.Contains(got, "--backend pytorch") {
+		t.Fatalf("unexpected command:\n%s", got)
+	}
+	if p.image() == VLLMImage {
+		t.Fatalf("trt-llm should run in the trt-llm playbook image")
+	}
+}
+
+func TestSplitPrompts(t *testing.T) {
+	prompts := splitPrompts(defaultBenchPrompts)
+	if len(prompts) == 0 {
+		t.Fatalf("expected built-in prompts")
+	}
+	for _, p := range prompts {
+		if strings.HasPrefix(p, "#") {
+			t.Fatalf("comment kept as a prompt: %q", p)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+"""LoRA fine-tuning and export with Unsloth, for dgx run unsloth.
+
+dgx writes this file to ~/unsloth on the DGX and runs it in the dgx/unsloth
+container, where ~/unsloth is mounted at /workspace.
+
+  train   fine-tunes a LoRA adapter and saves it, with the settings used, to
+          the output directory
+  export  loads an adapter saved by train and writes a merged Hugging Face
+          checkpoint or a GGUF file
+"""
+
+import argparse
+import json
+import os
+
+# Unsloth patches transformers and TRL, so it is imported first
+from unsloth import FastLanguageModel
+
+SETTINGS = "dgx-train.json"
+
+ALPACA_PROMPT = """Below is an instruction that describes a task. Write a response that appropriately completes the request.
+
+### Instruction:
+{}
+
+### Response:
+{}"""
+
+ALPACA_INPUT_PROMPT = """Below is an instruction that describes a task, paired with an input that provides further context. Write a response that appropriately completes the request.
+
+### Instruction:
+{}
+
+### Input:
+{}
+
+### Response:
+{}"""
+
+SHAREGPT_ROLES = {"system": "system", "human": "user", "user": "user", "gpt": "assistant", "assistant": "assistant"}
+
+
+def load_dataset(name):
+    """Loads a JSON, JSONL, CSV or Parquet file, or a Hugging Face dataset."""
+    import datasets
+
+    if os.path.exists(name):
+        kind = {".csv": "csv", ".parquet": "parquet"}.get(os.path.splitext(name)[1], "json")
+        return datasets.load_dataset(kind, data_files=name, split="train")
+    return datasets.load_dataset(name, split="train")
+
+
+def to_text(dataset, tokenizer):
+    """Renders each row to the text column SFTTrainer trains on.
+
+    Rows may already have text, be chat conversations (messages, or ShareGPT
+    conversations), or be Alpaca instructions. Returns the dataset and the
+    tokenizer, which gains a ChatML template when a chat dataset meets a
+    model without one.
+    """
+    columns = dataset.column_names
+    if "text" in columns:
+        return dataset, tokenizer
+
+    if "messages" in columns or "conversations" in columns:
+        if tokenizer.chat_template is None:
+            from unsloth.chat_templates import get_chat_template
+
+            tokenizer = get_chat_template(tokenizer, chat_template="chatml")
+        key = "messages" if "messages" in columns else "conversations"
+
+        def render(row):
+            messages = [
+                {"role": SHAREGPT_ROLES[m["from"]], "content": m["value"]} if "from" in m else m
+                for m in row[key]
+            ]
+            return {"text": tokenizer.apply_chat_template(messages, tokenize=False)}
+
+        return dataset.map(render, remove_columns=columns), tokenizer
+
+    if "instruction" in columns and "output" in columns:
+        eos = tokenizer.eos_token or ""
+
+        def render(row):
+            if row.get("input"):
+                text = ALPACA_INPUT_PROMPT.format(row["instruction"], row["input"], row["output"])
+            else:
+                text = ALPACA_PROMPT.format(row["instruction"], row["output"])
+            return {"text": text + eos}
+
+        return dataset.map(render, remove_columns=columns), tokenizer
+
+    raise SystemExit(
+        "Unrecognised dataset columns %s: expected text, messages, conversations, "
+        "or instruction/input/output" % columns
+    )
+
+
+def train(args):
+    from trl import SFTConfig, SFTTrainer
+
+    model, tokenizer = FastLanguageModel.from_pretrained(
+        model_name=args.model,
+        max_seq_length=args.max_seq_len,
+        load_in_4bit=args.load_in_4bit,
+    )
+    model = FastLanguageModel.get_peft_model(
+        model,
+        r=args.lora_rank,
+        lora_alpha=args.lora_rank,
+        lora_dropout=0,
+        target_modules=["q_proj", "k_proj", "v_proj", "o_proj", "gate_proj", "up_proj", "down_proj"],
+        use_gradient_checkpointing="unsloth",
+        random_state=3407,
+    )
+    dataset, tokenizer = to_text(load_dataset(args.dataset), tokenizer)
+    print("Training on %d examples from %s" % (len(dataset), args.dataset), flush=True)
+
+    trainer = SFTTrainer(
+        model=model,
+        tokenizer=tokenizer,
+        train_dataset=dataset,
+        args=SFTConfig(
+            dataset_text_field="text",
+            max_seq_length=args.max_seq_len,
+            per_device_train_batch_size=2,
+            gradient_accumulation_steps=4,
+            warmup_steps=5,
+            max_steps=args.max_steps,
+            learning_rate=2e-4,
+            logging_steps=1,
+            optim="adamw_8bit",
+            weight_decay=0.01,
+            lr_scheduler_type="linear",
+            seed=3407,
+            bf16=True,
+            output_dir=os.path.join(args.output_dir, "checkpoints"),
+            save_steps=max(args.max_steps // 4, 50),
+            report_to="none",
+        ),
+    )
+    trainer.train()
+
+    model.save_pretrained(args.output_dir)
+    tokenizer.save_pretrained(args.output_dir)
+    with open(os.path.join(args.output_dir, SETTINGS), "w") as f:
+        json.dump({k: v for k, v in vars(args).items() if k != "command"}, f, indent=2)
+    print("Adapter saved to %s" % args.output_dir, flush=True)
+
+
+def export(args):
+    with open(os.path.join(args.adapter, SETTINGS)) as f:
+        settings = json.load(f)
+
+    # The adapter is loaded as it was trained; both exports merge it into
+    # the 16-bit base model
+    model, tokenizer = FastLanguageModel.from_pretrained(
+        model_name=args.adapter,
+        max_seq_length=settings["max_seq_len"],
+        load_in_4bit=settings["load_in_4bit"],
+    )
+    if args.format == "gguf":
+        model.save_pretrained_gguf(args.export_dir, tokenizer, quantization_method=args.quantization)
+    else:
+        model.save_pretrained_merged(args.export_dir, tokenizer, save_method="merged_16bit")
+    print("Exported %s to %s" % (settings["model"], args.export_dir), flush=True)
+
+
+def main():
+    parser = argparse.ArgumentParser(description=__doc__, formatter_class=argparse.RawDescriptionHelpFormatter)
+    commands = parser.add_subparsers(dest="command", required=True)
+
+    t = commands.add_parser("train")
+    t.add_argument("--model", required=True)
+    t.add_argument("--dataset", required=True)
+    t.add_argument("--lora-rank", type=int, default=16)
+    t.add_argument("--max-steps", type=int, default=60)
+    t.add_argument("--max-seq-len", type=int, default=2048)
+    t.add_argument("--load-in-4bit", action="store_true")
+    t.add_argument("--output-dir", required=True)
+
+    e = commands.add_parser("export")
+    e.add_argument("--adapter", required=True)
+    e.add_argument("--format", choices=["gguf", "hf"], default="gguf")
+    e.add_argument("--quantization", default="q4_k_m")
+    e.add_argument("--export-dir", required=True)
+
+    args = parser.parse_args()
+    if args.command == "train":
+        train(args)
+    else:
+        export(args)
+
+
+if __name__ == "__main__":
+    main()
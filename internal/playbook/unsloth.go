@@ -0,0 +1,381 @@
+package playbook
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/weatherman/dgx-manager/internal/ssh"
+)
+
+//go:embed unsloth-train.py
+var unslothTrainScript string
+
+const (
+	// unslothVersion and unslothZooVersion are the Unsloth releases
+	// installed on top of PyTorchImage
+	unslothVersion    = "2025.11.1"
+	unslothZooVersion = "2025.11.1"
+	unslothImage      = "dgx/unsloth:" + unslothVersion
+
+	unslothDir = "~/unsloth"
+
+	// outputNamePattern matches the names of training runs and their
+	// containers
+	outputNamePattern = `[a-z0-9][a-z0-9-]*`
+)
+
+var (
+	nonNameChars     = regexp.MustCompile(`[^a-z0-9]+`)
+	hfDatasetPattern = regexp.MustCompile(`^` + hfModelPattern + `$`)
+)
+
+// unslothBuild builds the Unsloth image on the DGX the first time it is
+// needed. PyTorch comes from the base image, so Unsloth is installed
+// without its dependencies and the rest are added by name.
+var unslothBuild = fmt.Sprintf(`if ! docker image inspect %[1]s >/dev/null 2>&1; then
+  echo "Building %[1]s on the DGX (first run only, several minutes)..."
+  docker build -t %[1]s - <<'DOCKERFILE'
+FROM %[2]s
+RUN pip install --no-cache-dir "trl==0.19.1" peft accelerate datasets hf_transfer bitsandbytes sentencepiece protobuf \
+ && pip install --no-cache-dir --no-deps "unsloth==%[3]s" "unsloth_zoo==%[4]s" cut_cross_entropy
+DOCKERFILE
+fi
+mkdir -p %[5]s/data %[5]s/outputs %[5]s/exports ~/.cache/huggingface
+printf '%%s' %[6]s > %[5]s/unsloth-train.py
+`, unslothImage, PyTorchImage, unslothVersion, unslothZooVersion, unslothDir, shellQuote(unslothTrainScript))
+
+var unslothCommands = []CommandSpec{
+	{
+		Name:        "setup",
+		Description: "Build the pinned Unsloth container and install the training script",
+		Examples: []string{
+			"dgx run unsloth setup",
+		},
+	},
+	{
+		Name:        "train",
+		Description: "Fine-tune a LoRA adapter and stream the training logs",
+		Params: []ParamSpec{
+			{Name: "model", Description: "Base model (Hugging Face ID)", Positional: true, Required: true, Pattern: hfModelPattern},
+			{Name: "dataset", Description: "Local JSON, JSONL, CSV or Parquet file, or a Hugging Face dataset ID", Required: true},
+			{Name: "lora-rank", Description: "LoRA rank", Type: "int", Default: "16"},
+			{Name: "max-steps", Description: "Training steps", Type: "int", Default: "60"},
+			{Name: "max-seq-len", Description: "Longest training example, in tokens", Type: "int", Default: "2048"},
+			{Name: "load-in-4bit", Description: "Train on the 4-bit quantized model (QLoRA)", Type: "bool"},
+			{Name: "output-dir", Description: "Directory under ~/unsloth/outputs for the adapter (default from the model)", Pattern: outputNamePattern},
+			{Name: "detach", Description: "Start training and return without streaming the logs", Type: "bool"},
+		},
+		Examples: []string{
+			"dgx run unsloth train meta-llama/Llama-3.1-8B-Instruct --dataset data/train.jsonl --output-dir support-bot",
+			"dgx run unsloth train Qwen/Qwen2.5-7B-Instruct --dataset yahma/alpaca-cleaned --lora-rank 32 --max-steps 500 --load-in-4bit",
+		},
+	},
+	{
+		Name:        "status",
+		Description: "List training runs, trained adapters and exports",
+	},
+	{
+		Name:        "logs",
+		Description: "Show a training run's logs",
+		Params: []ParamSpec{
+			{Name: "name", Description: "Output directory name, as listed by status", Positional: true, Required: true, Pattern: outputNamePattern},
+			{Name: "tail", Description: "Number of lines to show", Type: "int", Default: "200"},
+			{Name: "follow", Description: "Keep streaming new lines", Type: "bool"},
+		},
+	},
+	{
+		Name:        "stop",
+		Description: "Stop a training run",
+		Params:      []ParamSpec{{Name: "name", Description: "Output directory name, as listed by status", Positional: true, Required: true, Pattern: outputNamePattern}},
+	},
+	{
+		Name:        "export",
+		Description: "Merge a trained adapter into its base model as GGUF (for Ollama) or Hugging Face (for vLLM)",
+		Params: []ParamSpec{
+			{Name: "name", Description: "Output directory name, as listed by status", Positional: true, Required: true, Pattern: outputNamePattern},
+			{Name: "format", Description: "Export format", Choices: []string{"gguf", "hf"}, Default: "gguf"},
+			{Name: "quantization", Description: "GGUF quantization", Choices: []string{"q4_k_m", "q5_k_m", "q8_0", "f16"}, Default: "q4_k_m"},
+			{Name: "dest", Description: "Also download the export to this local directory"},
+		},
+		Examples: []string{
+			"dgx run unsloth export support-bot --format gguf --quantization q5_k_m",
+			"dgx run unsloth export support-bot --format hf --dest ./models/support-bot",
+		},
+	},
+}
+
+// unslothPlaybook describes unsloth for usage and help; its commands are
+// implemented in Go
+var unslothPlaybook = &Spec{
+	Name:        "unsloth",
+	Description: "Fast fine-tuning optimization",
+	Commands:    unslothCommands,
+}
+
+// runUnsloth handles Unsloth fine-tuning commands
+func (m *Manager) runUnsloth(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("unsloth command required. Usage: dgx run unsloth <%s>", strings.Join(unslothPlaybook.CommandNames(), "|"))
+	}
+	c := unslothPlaybook.command(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown unsloth command: %s", args[0])
+	}
+	params, err := c.parseArgs(args[1:])
+	if err != nil {
+		return fmt.Errorf("%w. Usage: %s", err, unslothPlaybook.usage(c))
+	}
+
+	switch c.Name {
+	case "setup":
+		return m.unslothSetup()
+	case "train":
+		return m.unslothTrain(params)
+	case "status":
+		return m.unslothStatus()
+	case "logs":
+		logArgs := []string{"--tail", fmt.Sprint(params["tail"])}
+		if params["follow"].(bool) {
+			logArgs = append(logArgs, "--follow")
+		}
+		return m.dockerLogs(unslothContainer(params["name"].(string)), logArgs)
+	case "stop":
+		container := unslothContainer(params["name"].(string))
+		if _, err := m.sshClient.Execute("docker rm -f " + shellQuote(container)); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", container, err)
+		}
+		fmt.Printf("%s stopped and removed\n", container)
+		return nil
+	default:
+		return m.unslothExport(params)
+	}
+}
+
+func unslothContainer(output string) string {
+	return "unsloth-" + output
+}
+
+// unslothOutputName derives an output name from a model ID, as in
+// Qwen/Qwen2.5-7B-Instruct -> qwen2-5-7b-instruct
+func unslothOutputName(model string) string {
+	name := strings.ToLower(model[strings.LastIndex(model, "/")+1:])
+	name = nonNameChars.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
+
+// datasetFileExts are the extensions of the local datasets the training
+// script reads
+var datasetFileExts = []string{".json", ".jsonl", ".csv", ".parquet"}
+
+// unslothDataset resolves --dataset. A local file is returned as local, to
+// be synced to the DGX; anything else is a Hugging Face dataset ID, passed
+// to the training script as is.
+func unslothDataset(dataset string) (local string, err error) {
+	if info, err := os.Stat(dataset); err == nil {
+		if info.IsDir() {
+			return "", fmt.Errorf("dataset %s is a directory; give a JSON, JSONL, CSV or Parquet file", dataset)
+		}
+		return dataset, nil
+	}
+	ext := strings.ToLower(filepath.Ext(dataset))
+	for _, e := range datasetFileExts {
+		if ext == e {
+			return "", fmt.Errorf("dataset %s: no such file", dataset)
+		}
+	}
+	if !hfDatasetPattern.MatchString(dataset) {
+		return "", fmt.Errorf("dataset %q is neither a local file nor a Hugging Face dataset ID", dataset)
+	}
+	return "", nil
+}
+
+// unslothSetup builds the image and writes the training script
+func (m *Manager) unslothSetup() error {
+	fmt.Println("Setting up Unsloth on the DGX...")
+	script := unslothBuild + fmt.Sprintf(`echo "Image: %s"
+echo "Training script: %s/unsloth-train.py"`, unslothImage, unslothDir)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("setup failed: %w", err)
+	}
+	fmt.Println("\nUnsloth environment setup complete!")
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Store your Hugging Face token for gated models: dgx env hf-token")
+	fmt.Println("  2. Train an adapter: dgx run unsloth train <model> --dataset <file-or-dataset-id>")
+	return nil
+}
+
+// unslothTrain syncs a local dataset, starts a training container that
+// outlives the SSH session and streams its logs until training ends
+func (m *Manager) unslothTrain(params map[string]any) error {
+	model := params["model"].(string)
+	output := params["output_dir"].(string)
+	if output == "" {
+		output = unslothOutputName(model)
+	}
+	local, err := unslothDataset(params["dataset"].(string))
+	if err != nil {
+		return err
+	}
+	container := unslothContainer(output)
+
+	_, err = m.sshClient.Execute(fmt.Sprintf(`[ "$(docker inspect -f '{{.State.Running}}' %[1]s 2>/dev/null)" = true ] && exit 3
+mkdir -p %[2]s/data/%[3]s`, container, unslothDir, output))
+	if ssh.ExitStatus(err) == 3 {
+		return fmt.Errorf("%s is already training (see dgx run unsloth logs %s)", output, output)
+	} else if err != nil {
+		return fmt.Errorf("failed to prepare %s: %w", unslothDir, err)
+	}
+
+	dataset := params["dataset"].(string)
+	if local != "" {
+		fmt.Printf("Syncing %s to the DGX...\n", local)
+		remote := fmt.Sprintf("%s/data/%s/", unslothDir, output)
+		if err := m.sshClient.Rsync(local, m.sshClient.RemotePath(remote), false); err != nil {
+			return fmt.Errorf("failed to sync the dataset: %w", err)
+		}
+		dataset = fmt.Sprintf("data/%s/%s", output, filepath.Base(local))
+	}
+
+	trainArgs := fmt.Sprintf("--model %s --dataset %s --lora-rank %d --max-steps %d --max-seq-len %d --output-dir outputs/%s",
+		shellQuote(model), shellQuote(dataset), params["lora_rank"], params["max_steps"], params["max_seq_len"], output)
+	if params["load_in_4bit"].(bool) {
+		trainArgs += " --load-in-4bit"
+	}
+
+	// docker run -d keeps training when the SSH session ends; the logs are
+	// followed until the container exits, and its exit status returned. The
+	// container runs as root, so it hands the adapter to the user on exit.
+	script := unslothBuild + fmt.Sprintf(`docker rm -f %[1]s >/dev/null 2>&1 || true
+docker run -d --name %[1]s --gpus all --ipc=host \
+  --ulimit memlock=-1 --ulimit stack=67108864 \
+  -v %[2]s:/workspace \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN -e HF_HUB_ENABLE_HF_TRANSFER=1 \
+  -e OWNER="$(id -u):$(id -g)" -e OUT=outputs/%[5]s \
+  -w /workspace \
+  %[3]s \
+  bash -c 'trap "chown -R \"\$OWNER\" \"\$OUT\"" EXIT; "$@"' bash \
+  python unsloth-train.py train %[4]s >/dev/null
+echo "Training %[5]s in container %[1]s"`, container, unslothDir, unslothImage, trainArgs, output)
+	if !params["detach"].(bool) {
+		script += fmt.Sprintf(`
+echo "Ctrl-C stops following the logs; training continues on the DGX"
+echo
+docker logs -f %[1]s
+exit "$(docker wait %[1]s)"`, container)
+	}
+
+	fmt.Printf("Fine-tuning %s on %s (LoRA rank %d, %d steps)\n", model, params["dataset"], params["lora_rank"], params["max_steps"])
+	err = m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr)
+	if params["detach"].(bool) {
+		if err != nil {
+			return fmt.Errorf("failed to start training: %w", err)
+		}
+		fmt.Println("\nTo follow progress:")
+		fmt.Printf("  dgx run unsloth logs %s --follow\n", output)
+		fmt.Println("\nWhen it finishes:")
+		fmt.Printf("  dgx run unsloth export %s\n", output)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("training failed: %w", err)
+	}
+	fmt.Printf("\nAdapter saved to %s/outputs/%s on the DGX\n", unslothDir, output)
+	fmt.Println("\nTo export it:")
+	fmt.Printf("  dgx run unsloth export %s --format gguf   # for Ollama\n", output)
+	fmt.Printf("  dgx run unsloth export %s --format hf     # for vLLM\n", output)
+	return nil
+}
+
+// unslothStatus lists the training containers, adapters and exports
+func (m *Manager) unslothStatus() error {
+	script := fmt.Sprintf(`runs=$(docker ps -a --filter name=^unsloth- --format 'table {{.Names}}\t{{.Status}}')
+if [ "$(echo "$runs" | wc -l)" -gt 1 ]; then
+  echo "$runs"
+else
+  echo "No training runs"
+fi
+for kind in outputs exports; do
+  echo
+  echo "$kind (%[1]s/$kind):"
+  found=
+  for dir in %[1]s/$kind/*/; do
+    [ -d "$dir" ] || continue
+    found=1
+    echo "  $(basename "$dir")  $(du -sh "$dir" | cut -f1)"
+  done
+  [ -n "$found" ] || echo "  none"
+done`, unslothDir)
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("failed to check status: %w", err)
+	}
+	return nil
+}
+
+// unslothExport merges an adapter into its base model on the DGX. GGUF
+// exports are registered with Ollama when it is installed; Hugging Face
+// exports can be served by vLLM from their directory.
+func (m *Manager) unslothExport(params map[string]any) error {
+	output := params["name"].(string)
+	format := params["format"].(string)
+	quant := params["quantization"].(string)
+	exportDir := fmt.Sprintf("exports/%s-%s", output, format)
+
+	script := unslothBuild + fmt.Sprintf(`if [ ! -f %[1]s/outputs/%[2]s/dgx-train.json ]; then
+  echo "No trained adapter in %[1]s/outputs/%[2]s; has training finished?" >&2
+  exit 1
+fi
+echo "Exporting %[2]s as %[4]s..."
+docker run --rm --gpus all --ipc=host \
+  -v %[1]s:/workspace \
+  -v ~/.cache/huggingface:/root/.cache/huggingface \
+  -e HF_TOKEN \
+  -e OWNER="$(id -u):$(id -g)" -e OUT=%[3]s \
+  -w /workspace \
+  %[5]s \
+  bash -c 'trap "chown -R \"\$OWNER\" \"\$OUT\"* llama.cpp 2>/dev/null" EXIT; rm -rf "$OUT"; "$@"' bash \
+  python unsloth-train.py export --adapter outputs/%[2]s --format %[4]s --quantization %[6]s --export-dir %[3]s`,
+		unslothDir, output, exportDir, format, unslothImage, quant)
+	if format == "gguf" {
+		script += fmt.Sprintf(`
+gguf=$(find %[1]s/%[2]s* -iname '*%[3]s*.gguf' | head -n1)
+[ -n "$gguf" ] || gguf=$(find %[1]s/%[2]s* -name '*.gguf' | head -n1)
+if [ -z "$gguf" ]; then
+  echo "The export did not produce a GGUF file" >&2
+  exit 1
+fi
+echo "GGUF file: $gguf"
+if command -v ollama >/dev/null 2>&1; then
+  dir=$(dirname "$gguf")
+  [ -f "$dir/Modelfile" ] || echo "FROM $gguf" > "$dir/Modelfile"
+  (cd "$dir" && ollama create %[4]s -f Modelfile)
+  echo "Registered with Ollama as %[4]s"
+else
+  echo "Ollama is not installed on the DGX; install it with dgx run ollama install and export again to register the model"
+fi`, unslothDir, exportDir, quant, output)
+	}
+	if err := m.sshClient.ExecuteStream(remoteScript(script), os.Stdout, os.Stderr); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	remote := unslothDir + "/" + exportDir
+	if dest := params["dest"].(string); dest != "" {
+		fmt.Printf("\nDownloading %s to %s...\n", remote, dest)
+		if err := m.sshClient.Rsync(m.sshClient.RemotePath(remote+"/"), dest+"/", false); err != nil {
+			return fmt.Errorf("failed to download the export: %w", err)
+		}
+		fmt.Printf("Model saved to %s\n", dest)
+	}
+
+	fmt.Println("\nTo use it:")
+	if format == "gguf" {
+		fmt.Printf("  dgx run ollama run %s\n", output)
+	} else {
+		fmt.Printf("  dgx run vllm serve '%s' --tunnel\n", remote)
+	}
+	return nil
+}
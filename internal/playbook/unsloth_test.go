@@ -0,0 +1,68 @@
+package playbook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnslothOutputName(t *testing.T) {
+	tests := map[string]string{
+		"Qwen/Qwen2.5-7B-Instruct":         "qwen2-5-7b-instruct",
+		"meta-llama/Llama-3.1-8B-Instruct": "llama-3-1-8b-instruct",
+		"unsloth/gemma-3-4b-it":            "gemma-3-4b-it",
+		"gpt2":                             "gpt2",
+	}
+	for model, want := range tests {
+		if got := unslothOutputName(model); got != want {
+			t.Fatalf("%s: expected %s, got %s", model, want, got)
+		}
+	}
+}
+
+func TestUnslothDataset(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "train.jsonl")
+	os.WriteFile(file, []byte(`{"text": "hello"}`+"\n"), 0o644)
+
+	local, err := unslothDataset(file)
+	if err != nil || local != file {
+		t.Fatalf("expected local file %s, got %q (%v)", file, local, err)
+	}
+	local, err = unslothDataset("yahma/alpaca-cleaned")
+	if err != nil || local != "" {
+		t.Fatalf("expected a Hugging Face dataset, got %q (%v)", local, err)
+	}
+
+	for _, bad := range []string{filepath.Join(dir, "missing.json"), "data/missing.parquet", dir, "../a b"} {
+		if _, err := unslothDataset(bad); err == nil {
+			t.Fatalf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestUnslothTrainDefaults(t *testing.T) {
+	params, err := unslothPlaybook.command("train").parseArgs([]string{"Qwen/Qwen2.5-7B-Instruct", "--dataset", "yahma/alpaca-cleaned"})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if params["lora_rank"] != 16 || params["max_steps"] != 60 || params["max_seq_len"] != 2048 || params["load_in_4bit"] != false || params["output_dir"] != "" {
+		t.Fatalf("unexpected defaults %v", params)
+	}
+
+	if _, err := unslothPlaybook.command("train").parseArgs([]string{"Qwen/Qwen2.5-7B-Instruct", "--dataset", "x/y", "--output-dir", "Bad_Name"}); err == nil {
+		t.Fatalf("expected an invalid output name to be rejected")
+	}
+}
+
+func TestDGXPath(t *testing.T) {
+	tests := map[string]string{
+		"~/unsloth/exports/bot-hf": "~/'unsloth/exports/bot-hf'",
+		"/data/models/it's":        `'/data/models/it'"'"'s'`,
+	}
+	for p, want := range tests {
+		if got := dgxPath(p); got != want {
+			t.Fatalf("%s: expected %s, got %s", p, want, got)
+		}
+	}
+}
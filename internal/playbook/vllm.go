@@ -2,7 +2,11 @@ package playbook
 
 import (
 	"fmt"
+	"path"
 	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
 // runVLLM handles vLLM playbook commands
@@ -12,15 +16,23 @@ func (m *Manager) runVLLM(args []string) error {
 	}
 
 	command := args[0]
+	withTunnel, args := popFlag(args, "--tunnel")
 
 	switch command {
 	case "pull":
 		return m.vllmPull()
 	case "serve":
 		if len(args) < 2 {
-			return fmt.Errorf("model name required. Usage: dgx run vllm serve <model>")
+			return fmt.Errorf("model name required. Usage: dgx run vllm serve <model> [--tunnel]")
+		}
+		if err := m.vllmServe(args[1], withTunnel); err != nil {
+			return err
+		}
+		if withTunnel {
+			// Model download and load can take a while on first start
+			return m.openTunnel("vllm", 15*time.Minute)
 		}
-		return m.vllmServe(args[1])
+		return nil
 	case "status":
 		return m.vllmStatus()
 	case "stop":
@@ -33,9 +45,9 @@ func (m *Manager) runVLLM(args []string) error {
 // vllmPull pulls the vLLM Docker container
 func (m *Manager) vllmPull() error {
 	fmt.Println("Pulling vLLM container...")
-	fmt.Println("Image: nvcr.io/nvidia/vllm:25.09-py3")
+	fmt.Println("Image: " + VLLMImage)
 
-	output, err := m.sshClient.Execute("docker pull nvcr.io/nvidia/vllm:25.09-py3")
+	output, err := m.sshClient.Execute("docker pull " + VLLMImage)
 	if err != nil {
 		return fmt.Errorf("failed to pull container: %w", err)
 	}
@@ -46,20 +58,28 @@ func (m *Manager) vllmPull() error {
 }
 
 // vllmServe starts a vLLM server with the specified model
-func (m *Manager) vllmServe(model string) error {
+func (m *Manager) vllmServe(model string, withTunnel bool) error {
 	fmt.Printf("Starting vLLM server with model: %s\n", model)
 	fmt.Println("This will run the server in a Docker container...")
 
+	// A model directory on the DGX, such as a dgx run unsloth export, is
+	// mounted into the container and served under its directory name
+	mount, served := "", model
+	if strings.HasPrefix(model, "/") || strings.HasPrefix(model, "~/") {
+		mount = fmt.Sprintf("-v %s:/model:ro \\\n\t\t", dgxPath(model))
+		served = "/model --served-model-name " + shellQuote(path.Base(model))
+	}
+
 	// Build the Docker run command
 	cmd := fmt.Sprintf(`docker run -d \
 		--name vllm-server \
 		--gpus all \
 		--shm-size=10g \
 		-p 8000:8000 \
-		nvcr.io/nvidia/vllm:25.09-py3 \
+		%s%s \
 		vllm serve %s \
 		--host 0.0.0.0 \
-		--port 8000`, model)
+		--port 8000`, mount, VLLMImage, served)
 
 	output, err := m.sshClient.Execute(cmd)
 	if err != nil {
@@ -68,40 +88,73 @@ func (m *Manager) vllmServe(model string) error {
 
 	containerID := strings.TrimSpace(output)
 	fmt.Printf("vLLM server started (Container: %s)\n", containerID[:12])
-	fmt.Println("\nTo access the API:")
-	fmt.Println("  1. Create a tunnel: dgx tunnel create 8000:8000 \"vLLM\"")
-	fmt.Println("  2. API endpoint: http://localhost:8000/v1")
+	if !withTunnel {
+		fmt.Println("\nTo access the API:")
+		fmt.Println("  dgx tunnel open vllm")
+	}
 	fmt.Println("\nTo check logs:")
 	fmt.Println("  dgx exec docker logs -f vllm-server")
 	return nil
 }
 
+// dgxPath quotes a path on the DGX for the remote shell, leaving a leading
+// ~/ to expand to the home directory
+func dgxPath(p string) string {
+	if strings.HasPrefix(p, "~/") {
+		return "~/" + shellQuote(p[2:])
+	}
+	return shellQuote(p)
+}
+
 // vllmStatus checks if vLLM is running
 func (m *Manager) vllmStatus() error {
 	fmt.Println("Checking vLLM status...")
 
-	output, err := m.sshClient.Execute("docker ps --filter name=vllm-server --format '{{.ID}} {{.Status}} {{.Names}}'")
+	status, err := m.vllmState()
 	if err != nil {
-		return fmt.Errorf("failed to check status: %w", err)
+		return err
 	}
 
-	if output == "" {
+	if !status.Running {
 		fmt.Println("vLLM server is not running")
 		fmt.Println("\nTo start vLLM:")
 		fmt.Println("  dgx run vllm serve <model-name>")
 		return nil
 	}
 
-	fmt.Printf("vLLM server is running\n%s\n", output)
+	fmt.Printf("vLLM server is running\n%s\n", status.Container)
+	if status.Health != "" {
+		fmt.Printf("\nHealth check: %s\n", status.Health)
+	}
+
+	return nil
+}
+
+// vllmState reports the vLLM container and its health endpoint
+func (m *Manager) vllmState() (types.PlaybookStatus, error) {
+	status := types.PlaybookStatus{Playbook: "vllm"}
+
+	output, err := m.sshClient.Execute("docker ps --filter name=vllm-server --format '{{.ID}} {{.Status}} {{.Names}}'")
+	if err != nil {
+		return status, fmt.Errorf("failed to check status: %w", err)
+	}
+
+	status.Container = strings.TrimSpace(output)
+	status.Running = status.Container != ""
+	if !status.Running {
+		return status, nil
+	}
 
 	// Try to get health status
 	healthCmd := "docker exec vllm-server curl -s http://localhost:8000/health || echo 'Not accessible'"
 	health, _ := m.sshClient.Execute(healthCmd)
-	if health != "" {
-		fmt.Printf("\nHealth check: %s\n", strings.TrimSpace(health))
+	status.Health = strings.TrimSpace(health)
+	if status.Health == "" {
+		// vLLM answers /health with an empty 200
+		status.Health = "ok"
 	}
 
-	return nil
+	return status, nil
 }
 
 // vllmStop stops the vLLM server
@@ -1,12 +1,15 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/weatherman/dgx-manager/pkg/types"
@@ -17,6 +20,8 @@ import (
 // Client manages SSH connections to the DGX
 type Client struct {
 	config *types.Config
+	batch  bool
+	mu     sync.Mutex
 	client *ssh.Client
 }
 
@@ -27,6 +32,12 @@ func NewClient(config *types.Config) (*Client, error) {
 	}, nil
 }
 
+// SetBatchMode makes Connect fail on unknown or changed host keys instead of
+// prompting, for clients that run without a terminal
+func (c *Client) SetBatchMode(batch bool) {
+	c.batch = batch
+}
+
 // Connect establishes an SSH connection
 func (c *Client) Connect() error {
 	// Load SSH key
@@ -66,6 +77,9 @@ func (c *Client) Connect() error {
 	if err != nil {
 		// Check if it's a known_hosts error
 		if strings.Contains(err.Error(), "knownhosts:") || strings.Contains(err.Error(), "key is unknown") {
+			if c.batch {
+				return fmt.Errorf("failed to connect to %s: %w (connect once from a terminal to review the host key)", addr, err)
+			}
 			fmt.Fprintf(os.Stderr, "\nWarning: Host key for %s not found in known_hosts\n", c.config.Host)
 			fmt.Fprintf(os.Stderr, "This is normal for first-time connections.\n\n")
 			fmt.Fprintf(os.Stderr, "Add host key to ~/.ssh/known_hosts? [Y/n]: ")
@@ -99,18 +113,70 @@ func (c *Client) Connect() error {
 		}
 	}
 
+	// Swap in the new connection; forwards pick it up on their next dial
+	c.mu.Lock()
+	old := c.client
 	c.client = client
+	c.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
 	return nil
 }
 
 // Close closes the SSH connection
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.client != nil {
-		return c.client.Close()
+		err := c.client.Close()
+		c.client = nil
+		return err
 	}
 	return nil
 }
 
+// conn returns the current underlying connection, which may be nil
+func (c *Client) conn() *ssh.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client
+}
+
+// Keepalive sends an OpenSSH keepalive request and waits up to timeout
+// for the server to answer
+func (c *Client) Keepalive(timeout time.Duration) error {
+	conn := c.conn()
+	if conn == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("keepalive failed: %w", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("keepalive timed out after %v", timeout)
+	}
+}
+
+// Dial opens a connection to addr from the remote host
+func (c *Client) Dial(network, addr string) (net.Conn, error) {
+	conn := c.conn()
+	if conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	return conn.Dial(network, addr)
+}
+
 // addHostKey adds the host key to known_hosts
 func (c *Client) addHostKey() error {
 	home, err := os.UserHomeDir()
@@ -141,26 +207,35 @@ func (c *Client) addHostKey() error {
 	return nil
 }
 
-// Execute runs a command on the remote host
-func (c *Client) Execute(command string) (string, error) {
+// newSession opens a session, connecting or reconnecting once as needed
+func (c *Client) newSession() (*ssh.Session, error) {
 	// Ensure we're connected
-	if c.client == nil {
+	if c.conn() == nil {
 		if err := c.Connect(); err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
-	session, err := c.client.NewSession()
+	session, err := c.conn().NewSession()
 	if err != nil {
 		// If session creation fails, try reconnecting once
 		if err := c.Connect(); err != nil {
-			return "", fmt.Errorf("failed to reconnect: %w", err)
+			return nil, fmt.Errorf("failed to reconnect: %w", err)
 		}
-		session, err = c.client.NewSession()
+		session, err = c.conn().NewSession()
 		if err != nil {
-			return "", fmt.Errorf("failed to create session: %w", err)
+			return nil, fmt.Errorf("failed to create session: %w", err)
 		}
 	}
+	return session, nil
+}
+
+// Execute runs a command on the remote host
+func (c *Client) Execute(command string) (string, error) {
+	session, err := c.newSession()
+	if err != nil {
+		return "", err
+	}
 	defer session.Close()
 
 	output, err := session.CombinedOutput(command)
@@ -171,6 +246,69 @@ func (c *Client) Execute(command string) (string, error) {
 	return string(output), nil
 }
 
+// ExecuteStream runs a command on the remote host, copying its output to
+// stdout and stderr as it arrives instead of buffering it
+func (c *Client) ExecuteStream(command string, stdout, stderr io.Writer) error {
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Run(command); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
+
+// ExecuteStreamContext runs a command like ExecuteStream but stops it when
+// ctx is cancelled, returning ctx.Err()
+func (c *Client) ExecuteStreamContext(ctx context.Context, command string, stdout, stderr io.Writer) error {
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Start(command); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("command failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		// Not every sshd honours signals; closing the channel also ends the command
+		session.Signal(ssh.SIGTERM)
+		session.Close()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// ExitStatus returns the remote exit code carried by an Execute or
+// ExecuteStream error, 0 for nil, and -1 when the command never ran
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	return -1
+}
+
 // InteractiveShell starts an interactive SSH shell
 func (c *Client) InteractiveShell() error {
 	// Use native SSH command for interactive shell (better terminal handling)
@@ -205,6 +343,25 @@ func (c *Client) RunInteractive(command string) error {
 	return cmd.Run()
 }
 
+// RunTTY runs a shell script on the remote host with a terminal allocated,
+// so commands such as sudo can prompt for a password
+func (c *Client) RunTTY(script string) error {
+	args := []string{
+		"-t",
+		"-i", c.config.IdentityFile,
+		"-p", fmt.Sprintf("%d", c.config.Port),
+		fmt.Sprintf("%s@%s", c.config.User, c.config.Host),
+		"bash -c '" + strings.ReplaceAll(script, "'", `'"'"'`) + "'",
+	}
+
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
+
 // CheckConnection tests the connection without keeping it open
 func (c *Client) CheckConnection() (time.Duration, error) {
 	start := time.Now()
@@ -218,11 +375,12 @@ func (c *Client) CheckConnection() (time.Duration, error) {
 	return latency, nil
 }
 
-// ForwardPort creates an SSH tunnel
-func (c *Client) ForwardPort(localPort, remotePort int, remoteHost string) error {
-	if c.client == nil {
+// ForwardPort creates an SSH tunnel. Closing the returned listener stops
+// accepting new connections on the local port.
+func (c *Client) ForwardPort(localPort, remotePort int, remoteHost string) (net.Listener, error) {
+	if c.conn() == nil {
 		if err := c.Connect(); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -230,7 +388,7 @@ func (c *Client) ForwardPort(localPort, remotePort int, remoteHost string) error
 	localAddr := fmt.Sprintf("localhost:%d", localPort)
 	listener, err := net.Listen("tcp", localAddr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", localAddr, err)
+		return nil, fmt.Errorf("failed to listen on %s: %w", localAddr, err)
 	}
 
 	go func() {
@@ -245,7 +403,7 @@ func (c *Client) ForwardPort(localPort, remotePort int, remoteHost string) error
 		}
 	}()
 
-	return nil
+	return listener, nil
 }
 
 // handleForward handles a single forwarded connection
@@ -253,23 +411,67 @@ func (c *Client) handleForward(localConn net.Conn, remoteHost string, remotePort
 	defer localConn.Close()
 
 	remoteAddr := fmt.Sprintf("%s:%d", remoteHost, remotePort)
-	remoteConn, err := c.client.Dial("tcp", remoteAddr)
+	remoteConn, err := c.Dial("tcp", remoteAddr)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to remote %s: %v\n", remoteAddr, err)
 		return
 	}
 	defer remoteConn.Close()
 
-	// Copy data bidirectionally
+	pipe(localConn, remoteConn)
+}
+
+// ReverseForward listens on remotePort on the remote host's loopback and
+// connects each incoming connection to localAddr. The listener lives on
+// the current SSH connection and must be re-created after a reconnect.
+func (c *Client) ReverseForward(remotePort int, localAddr string) (net.Listener, error) {
+	if c.conn() == nil {
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	remoteAddr := fmt.Sprintf("127.0.0.1:%d", remotePort)
+	listener, err := c.conn().Listen("tcp", remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on remote %s: %w", remoteAddr, err)
+	}
+
+	go func() {
+		defer listener.Close()
+		for {
+			remoteConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer remoteConn.Close()
+				localConn, err := net.Dial("tcp", localAddr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to connect to local %s: %v\n", localAddr, err)
+					return
+				}
+				defer localConn.Close()
+				pipe(remoteConn, localConn)
+			}()
+		}
+	}()
+
+	return listener, nil
+}
+
+// pipe copies data bidirectionally until either side closes
+func pipe(a, b net.Conn) {
 	done := make(chan struct{}, 2)
 
 	go func() {
-		io.Copy(remoteConn, localConn)
+		io.Copy(a, b)
 		done <- struct{}{}
 	}()
 
 	go func() {
-		io.Copy(localConn, remoteConn)
+		io.Copy(b, a)
 		done <- struct{}{}
 	}()
 
@@ -293,6 +495,12 @@ func (c *Client) CopyFile(source, dest string) error {
 	return cmd.Run()
 }
 
+// RemotePath returns path on the DGX in the user@host:path form rsync and
+// scp take
+func (c *Client) RemotePath(path string) string {
+	return fmt.Sprintf("%s@%s:%s", c.config.User, c.config.Host, path)
+}
+
 // Rsync syncs files using rsync over SSH
 func (c *Client) Rsync(source, dest string, deleteExtraneous bool) error {
 	args := []string{
@@ -0,0 +1,88 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// hostKeyServer accepts TCP connections and completes the SSH handshake
+// with a fresh host key, so only host key checking can fail
+func hostKeyServer(t *testing.T) string {
+	t.Helper()
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("host key: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestConnectBatchModeRejectsHostKeys(t *testing.T) {
+	addr := hostKeyServer(t)
+	host, portText, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portText)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+
+	_, clientKey, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatalf("client key: %v", err)
+	}
+	identity := filepath.Join(home, ".ssh", "id_ed25519")
+	os.WriteFile(identity, pem.EncodeToMemory(block), 0o600)
+
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	otherPub, _ := ssh.NewPublicKey(otherKey)
+	tests := map[string]string{
+		"unknown host": "",
+		"changed key":  knownhosts.Line([]string{knownhosts.Normalize(addr)}, otherPub) + "\n",
+	}
+	for name, knownHosts := range tests {
+		path := filepath.Join(home, ".ssh", "known_hosts")
+		os.WriteFile(path, []byte(knownHosts), 0o600)
+
+		client, _ := NewClient(&types.Config{Host: host, Port: port, User: "dgx", IdentityFile: identity})
+		client.SetBatchMode(true)
+		err := client.Connect()
+		if err == nil || !strings.Contains(err.Error(), "knownhosts") {
+			t.Fatalf("%s: expected a host key error, got %v", name, err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != knownHosts {
+			t.Fatalf("%s: known_hosts was changed:\n%s", name, data)
+		}
+	}
+}
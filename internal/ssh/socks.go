@@ -0,0 +1,162 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+)
+
+// SOCKS5 protocol constants (RFC 1928)
+const (
+	socksVersion5      = 0x05
+	socksNoAuth        = 0x00
+	socksNoAcceptable  = 0xff
+	socksCmdConnect    = 0x01
+	socksAtypIPv4      = 0x01
+	socksAtypDomain    = 0x03
+	socksAtypIPv6      = 0x04
+	socksReplySuccess  = 0x00
+	socksReplyRefused  = 0x05
+	socksReplyBadCmd   = 0x07
+	socksReplyBadAtype = 0x08
+)
+
+// DynamicForward starts a SOCKS5 proxy on localPort whose connections are
+// opened from the remote host. Closing the returned listener stops it.
+func (c *Client) DynamicForward(localPort int) (net.Listener, error) {
+	if c.conn() == nil {
+		if err := c.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	localAddr := fmt.Sprintf("localhost:%d", localPort)
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", localAddr, err)
+	}
+
+	go func() {
+		defer listener.Close()
+		for {
+			localConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go c.handleSOCKS(localConn)
+		}
+	}()
+
+	return listener, nil
+}
+
+// handleSOCKS serves a single SOCKS5 client connection
+func (c *Client) handleSOCKS(localConn net.Conn) {
+	defer localConn.Close()
+
+	target, err := socks5Handshake(localConn)
+	if err != nil {
+		return
+	}
+
+	remoteConn, err := c.Dial("tcp", target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "SOCKS connect to %s failed: %v\n", target, err)
+		socks5Reply(localConn, socksReplyRefused)
+		return
+	}
+	defer remoteConn.Close()
+
+	if err := socks5Reply(localConn, socksReplySuccess); err != nil {
+		return
+	}
+	pipe(localConn, remoteConn)
+}
+
+// socks5Handshake negotiates no-auth and reads a CONNECT request,
+// returning the requested host:port
+func socks5Handshake(rw io.ReadWriter) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return "", err
+	}
+	if header[0] != socksVersion5 {
+		return "", fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", err
+	}
+	noAuth := false
+	for _, m := range methods {
+		if m == socksNoAuth {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		rw.Write([]byte{socksVersion5, socksNoAcceptable})
+		return "", fmt.Errorf("client does not offer no-auth")
+	}
+	if _, err := rw.Write([]byte{socksVersion5, socksNoAuth}); err != nil {
+		return "", err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(rw, request); err != nil {
+		return "", err
+	}
+	if request[0] != socksVersion5 {
+		return "", fmt.Errorf("unsupported SOCKS version %d", request[0])
+	}
+	if request[1] != socksCmdConnect {
+		socks5Reply(rw, socksReplyBadCmd)
+		return "", fmt.Errorf("unsupported SOCKS command %d", request[1])
+	}
+
+	var host string
+	switch request[3] {
+	case socksAtypIPv4:
+		addr := make([]byte, net.IPv4len)
+		if _, err := io.ReadFull(rw, addr); err != nil {
+			return "", err
+		}
+		host = net.IP(addr).String()
+	case socksAtypIPv6:
+		addr := make([]byte, net.IPv6len)
+		if _, err := io.ReadFull(rw, addr); err != nil {
+			return "", err
+		}
+		host = net.IP(addr).String()
+	case socksAtypDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(rw, length); err != nil {
+			return "", err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(rw, domain); err != nil {
+			return "", err
+		}
+		host = string(domain)
+	default:
+		socks5Reply(rw, socksReplyBadAtype)
+		return "", fmt.Errorf("unsupported SOCKS address type %d", request[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(rw, port); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socks5Reply sends a reply with an unspecified bound address
+func socks5Reply(w io.Writer, code byte) error {
+	_, err := w.Write([]byte{socksVersion5, code, 0x00, socksAtypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
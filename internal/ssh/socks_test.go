@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+// socksConn replays scripted client bytes and captures server replies
+type socksConn struct {
+	in  *bytes.Reader
+	out bytes.Buffer
+}
+
+func (c *socksConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *socksConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func newSOCKSConn(request []byte) *socksConn {
+	greeting := []byte{0x05, 0x01, 0x00}
+	return &socksConn{in: bytes.NewReader(append(greeting, request...))}
+}
+
+func TestSOCKS5Handshake(t *testing.T) {
+	tests := []struct {
+		name    string
+		request []byte
+		want    string
+	}{
+		{
+			name:    "ipv4",
+			request: []byte{0x05, 0x01, 0x00, 0x01, 192, 168, 1, 50, 0x1f, 0x90},
+			want:    "192.168.1.50:8080",
+		},
+		{
+			name:    "domain",
+			request: append(append([]byte{0x05, 0x01, 0x00, 0x03, 9}, "spark.lan"...), 0x00, 0x50),
+			want:    "spark.lan:80",
+		},
+		{
+			name:    "ipv6",
+			request: append(append([]byte{0x05, 0x01, 0x00, 0x04}, net.ParseIP("fe80::1")...), 0x01, 0xbb),
+			want:    "[fe80::1]:443",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newSOCKSConn(tt.request)
+
+			got, err := socks5Handshake(conn)
+			if err != nil {
+				t.Fatalf("handshake: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+			if !bytes.Equal(conn.out.Bytes(), []byte{0x05, 0x00}) {
+				t.Fatalf("unexpected method reply %v", conn.out.Bytes())
+			}
+		})
+	}
+}
+
+func TestSOCKS5HandshakeRejectsBind(t *testing.T) {
+	conn := newSOCKSConn([]byte{0x05, 0x02, 0x00, 0x01, 127, 0, 0, 1, 0x00, 0x50})
+
+	if _, err := socks5Handshake(conn); err == nil {
+		t.Fatalf("expected BIND to be rejected")
+	}
+
+	out := conn.out.Bytes()
+	if len(out) != 12 || !bytes.Equal(out[2:4], []byte{0x05, socksReplyBadCmd}) {
+		t.Fatalf("unexpected reply %v", out)
+	}
+}
@@ -0,0 +1,207 @@
+package support
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/diagnostics"
+	"github.com/weatherman/dgx-manager/internal/gpu"
+)
+
+// EnvFile is the bundle path of the DGX's ~/.config/dgx/env.sh
+const EnvFile = "remote/env.sh"
+
+// remoteFile is a bundle file produced by a command on the DGX
+type remoteFile struct {
+	name    string
+	command string
+}
+
+// remoteFiles are collected in one SSH session
+var remoteFiles = []remoteFile{
+	{"remote/nvidia-smi.txt", "nvidia-smi"},
+	{"remote/nvidia-smi-q.txt", "nvidia-smi -q"},
+	{"remote/dmesg.txt", "dmesg 2>/dev/null || sudo -n dmesg 2>/dev/null || journalctl -k -b --no-pager"},
+	{"remote/journal.txt", "journalctl -b --no-pager -n 5000"},
+	{"remote/journal-docker.txt", "journalctl -u docker -b --no-pager -n 2000"},
+	{"remote/docker-info.txt", "docker info"},
+	{"remote/docker-ps.txt", "docker ps -a"},
+	{"remote/docker-daemon.json", "cat /etc/docker/daemon.json"},
+	{"remote/nvidia-container-toolkit.txt", "nvidia-ctk --version && nvidia-container-cli info"},
+	{"remote/packages.txt", "dpkg -l | grep -Ei 'nvidia|cuda|docker'"},
+	{"remote/os.txt", "uname -a; cat /etc/os-release; [ ! -f /etc/dgx-release ] || cat /etc/dgx-release"},
+	{"remote/lsmod.txt", "lsmod"},
+	{"remote/resources.txt", "uptime; free -h; df -h"},
+	{EnvFile, "cat ~/.config/dgx/env.sh"},
+}
+
+// Entry describes one file in the bundle
+type Entry struct {
+	Name   string `json:"name"`
+	Source string `json:"source"` // Command or local path it came from
+	Bytes  int    `json:"bytes"`
+	Exit   int    `json:"exit,omitempty"`
+}
+
+// Redactions records what was scrubbed from the bundle
+type Redactions struct {
+	Secrets []string `json:"secrets"`
+	IPs     bool     `json:"ips"`
+	Users   bool     `json:"users"`
+}
+
+// Manifest is written to the bundle as manifest.json
+type Manifest struct {
+	Created  time.Time  `json:"created"`
+	Profile  string     `json:"profile"`
+	Version  string     `json:"dgx_version"`
+	Redacted Redactions `json:"redacted"`
+	Files    []Entry    `json:"files"`
+	Failures []string   `json:"failures"`
+}
+
+// Bundle gathers files for a support archive
+type Bundle struct {
+	manifest Manifest
+	data     map[string][]byte
+}
+
+// New creates an empty bundle for a profile
+func New(profile, version string, now time.Time) *Bundle {
+	return &Bundle{
+		manifest: Manifest{Created: now.UTC().Truncate(time.Second), Profile: profile, Version: version, Failures: []string{}},
+		data:     make(map[string][]byte),
+	}
+}
+
+// Name is the archive's base name and top-level directory
+func (b *Bundle) Name() string {
+	return fmt.Sprintf("dgx-support-%s-%s", b.manifest.Profile, b.manifest.Created.Format("20060102-150405"))
+}
+
+// Add adds a file to the bundle
+func (b *Bundle) Add(name, source string, data []byte) {
+	b.add(Entry{Name: name, Source: source, Bytes: len(data)}, data)
+}
+
+func (b *Bundle) add(e Entry, data []byte) {
+	b.manifest.Files = append(b.manifest.Files, e)
+	b.data[e.Name] = data
+}
+
+// AddJSON adds v as an indented JSON file
+func (b *Bundle) AddJSON(name, source string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		b.Fail(name, err)
+		return
+	}
+	b.Add(name, source, append(data, '\n'))
+}
+
+// Fail records something that could not be collected
+func (b *Bundle) Fail(what string, err error) {
+	b.manifest.Failures = append(b.manifest.Failures, fmt.Sprintf("%s: %v", what, err))
+}
+
+// Data returns a collected file, or nil
+func (b *Bundle) Data(name string) []byte {
+	return b.data[name]
+}
+
+// Manifest returns the files and failures collected so far
+func (b *Bundle) Manifest() Manifest {
+	return b.manifest
+}
+
+// CollectRemote runs every remote command and the diagnostics checks in
+// one SSH session. Commands that fail are kept, with their output, and
+// recorded as failures.
+func (b *Bundle) CollectRemote(monitor *gpu.Monitor) error {
+	checks := diagnostics.Catalogue(diagnostics.Options{SkipContainer: true})
+	sections := diagnostics.Sections(checks)
+	for _, f := range remoteFiles {
+		sections = append(sections, gpu.Section{Name: f.name, Command: f.command})
+	}
+
+	results, err := monitor.RunSections(sections)
+	if err != nil {
+		b.Fail("ssh", err)
+		return err
+	}
+
+	for _, f := range remoteFiles {
+		r, ok := results[f.name]
+		if !ok {
+			b.Fail(f.name, fmt.Errorf("no output"))
+			continue
+		}
+		b.add(Entry{Name: f.name, Source: f.command, Bytes: len(r.Output), Exit: r.Exit}, []byte(r.Output))
+		if err := r.Err(f.name); err != nil {
+			b.manifest.Failures = append(b.manifest.Failures, firstLine(err.Error()))
+		}
+	}
+	b.AddJSON("remote/diagnostics.json", "dgx diagnostics --skip-container", diagnostics.Results(checks, results))
+	return nil
+}
+
+// Write writes the bundle as a tar.gz, redacting every file and the
+// manifest. Secret assignments in env.sh are replaced whatever their
+// quoting.
+func (b *Bundle) Write(w io.Writer, r *Redactor) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := b.manifest
+	manifest.Redacted = Redactions{Secrets: r.SecretNames(), IPs: r.IPs, Users: len(r.users) > 0}
+	if manifest.Redacted.Secrets == nil {
+		manifest.Redacted.Secrets = []string{}
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	write := func(name string, data []byte) error {
+		data = r.Redact(data)
+		hdr := &tar.Header{
+			Name:    b.Name() + "/" + name,
+			Mode:    0o600,
+			Size:    int64(len(data)),
+			ModTime: b.manifest.Created,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write("manifest.json", append(manifestData, '\n')); err != nil {
+		return err
+	}
+	for _, e := range b.manifest.Files {
+		data := b.data[e.Name]
+		if e.Name == EnvFile {
+			data = RedactEnvFile(data)
+		}
+		if err := write(e.Name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,82 @@
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteBundle(t *testing.T) {
+	env, err := os.ReadFile(filepath.Join("testdata", "env.sh"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	now := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	b := New("spark", "0.1.0", now)
+	b.Add(EnvFile, "cat ~/.config/dgx/env.sh", env)
+	b.Add("remote/docker-info.txt", "docker info", []byte("Runtimes: nvidia runc\nHTTP Proxy: http://10.0.0.5:3128\n"))
+	b.Fail("remote/lsmod.txt", errors.New("exit status 127"))
+
+	r := NewRedactor()
+	for name, value := range EnvSecrets(b.Data(EnvFile)) {
+		r.AddSecret(name, value)
+	}
+	r.IPs = true
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf, r); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	files := readArchive(t, &buf)
+	prefix := "dgx-support-spark-20261017-093000/"
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	if strings.Contains(files[prefix+EnvFile], "hf_") || !strings.Contains(files[prefix+EnvFile], "CUDA_VISIBLE_DEVICES=0") {
+		t.Fatalf("env.sh not redacted:\n%s", files[prefix+EnvFile])
+	}
+	if !strings.Contains(files[prefix+"remote/docker-info.txt"], "http://[IP]:3128") {
+		t.Fatalf("IP not redacted:\n%s", files[prefix+"remote/docker-info.txt"])
+	}
+
+	var m Manifest
+	if err := json.Unmarshal([]byte(files[prefix+"manifest.json"]), &m); err != nil {
+		t.Fatalf("bad manifest: %v", err)
+	}
+	if len(m.Files) != 2 || len(m.Failures) != 1 || m.Failures[0] != "remote/lsmod.txt: exit status 127" {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if strings.Join(m.Redacted.Secrets, ",") != "CODEX_API_KEY,HF_TOKEN,WANDB_API_KEY" || !m.Redacted.IPs {
+		t.Fatalf("unexpected redactions %+v", m.Redacted)
+	}
+}
+
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatalf("not gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("bad tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(data)
+	}
+}
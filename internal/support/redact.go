@@ -0,0 +1,184 @@
+package support
+
+import (
+	"bufio"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretNames are always redacted from env.sh. Other variables whose names
+// end in one of secretSuffixes are treated as secrets too.
+var (
+	secretNames    = []string{"HF_TOKEN", "WANDB_API_KEY", "CODEX_API_KEY", "NGC_API_KEY"}
+	secretSuffixes = []string{"_TOKEN", "_KEY", "_SECRET", "_PASSWORD"}
+)
+
+// ipv4Pattern matches dotted IPv4 addresses
+var ipv4Pattern = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)
+
+// Redactor scrubs secrets, and optionally IP addresses, host names and
+// usernames, from bundle files
+type Redactor struct {
+	secrets map[string]string // Value -> name
+	IPs     bool
+	hosts   []string
+	users   []string
+}
+
+// NewRedactor creates a redactor that only scrubs secrets
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: make(map[string]string)}
+}
+
+// AddSecret redacts every occurrence of value as [REDACTED:name]
+func (r *Redactor) AddSecret(name, value string) {
+	// Very short values would redact unrelated text
+	if len(value) >= 4 {
+		r.secrets[value] = name
+	}
+}
+
+// AddHost redacts a host name along with IP addresses
+func (r *Redactor) AddHost(host string) {
+	if host != "" && !ipv4Pattern.MatchString(host) && host != "localhost" {
+		r.hosts = append(r.hosts, host)
+	}
+}
+
+// AddUser redacts a username as a whole word. root is never redacted.
+func (r *Redactor) AddUser(user string) {
+	if user != "" && user != "root" {
+		r.users = append(r.users, user)
+	}
+}
+
+// SecretNames returns the names of the secrets being redacted
+func (r *Redactor) SecretNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range r.secrets {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redact returns data with every configured value replaced
+func (r *Redactor) Redact(data []byte) []byte {
+	s := string(data)
+
+	// Longest first so a secret containing another is replaced whole
+	values := make([]string, 0, len(r.secrets))
+	for v := range r.secrets {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, "[REDACTED:"+r.secrets[v]+"]")
+	}
+
+	if r.IPs {
+		s = ipv4Pattern.ReplaceAllStringFunc(s, func(ip string) string {
+			if strings.HasPrefix(ip, "127.") || ip == "0.0.0.0" {
+				return ip
+			}
+			return "[IP]"
+		})
+		for _, h := range r.hosts {
+			s = strings.ReplaceAll(s, h, "[HOST]")
+		}
+	}
+
+	for _, u := range r.users {
+		s = regexp.MustCompile(`\b`+regexp.QuoteMeta(u)+`\b`).ReplaceAllString(s, "[USER]")
+	}
+	return []byte(s)
+}
+
+// EnvSecrets reads secret assignments from an env.sh written by dgx env,
+// such as export HF_TOKEN='hf_...'
+func EnvSecrets(envFile []byte) map[string]string {
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(envFile)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || !isSecretName(name) {
+			continue
+		}
+		if v := unquote(strings.TrimSpace(value)); v != "" {
+			secrets[name] = v
+		}
+	}
+	return secrets
+}
+
+func isSecretName(name string) bool {
+	for _, n := range secretNames {
+		if name == n {
+			return true
+		}
+	}
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// unquote reverses shell quoting of a single word, including shlex.quote's
+// 'a'"'"'b' form for embedded single quotes
+func unquote(value string) string {
+	var sb strings.Builder
+	for len(value) > 0 {
+		switch value[0] {
+		case '\'':
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return sb.String() + value[1:]
+			}
+			sb.WriteString(value[1 : end+1])
+			value = value[end+2:]
+		case '"':
+			end := strings.IndexByte(value[1:], '"')
+			if end < 0 {
+				return sb.String() + value[1:]
+			}
+			sb.WriteString(value[1 : end+1])
+			value = value[end+2:]
+		default:
+			sb.WriteByte(value[0])
+			value = value[1:]
+		}
+	}
+	return sb.String()
+}
+
+// RedactEnvFile replaces the value of every secret assignment in env.sh,
+// whatever its quoting
+func RedactEnvFile(envFile []byte) []byte {
+	lines := strings.SplitAfter(string(envFile), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		export := strings.HasPrefix(trimmed, "export ")
+		name, _, ok := strings.Cut(strings.TrimPrefix(trimmed, "export "), "=")
+		if !ok || !isSecretName(name) {
+			continue
+		}
+		redacted := name + "=[REDACTED:" + name + "]"
+		if export {
+			redacted = "export " + redacted
+		}
+		if strings.HasSuffix(line, "\n") {
+			redacted += "\n"
+		}
+		lines[i] = redacted
+	}
+	return []byte(strings.Join(lines, ""))
+}
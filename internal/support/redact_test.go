@@ -0,0 +1,68 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnvSecrets(t *testing.T) {
+	env, err := os.ReadFile(filepath.Join("testdata", "env.sh"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	secrets := EnvSecrets(env)
+	want := map[string]string{
+		"HF_TOKEN":      "hf_QnV3cmVkYWN0ZWRUb2tlbkZvclRlc3Rz",
+		"WANDB_API_KEY": "0123456789abcdef'0123456789abcdef",
+		"CODEX_API_KEY": "sk-proj-test-codex-key",
+	}
+	if len(secrets) != len(want) {
+		t.Fatalf("expected %d secrets, got %v", len(want), secrets)
+	}
+	for name, value := range want {
+		if secrets[name] != value {
+			t.Fatalf("expected %s=%q, got %q", name, value, secrets[name])
+		}
+	}
+}
+
+func TestRedactEnvFile(t *testing.T) {
+	env, err := os.ReadFile(filepath.Join("testdata", "env.sh"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	got := string(RedactEnvFile(env))
+	want := `# Managed by dgx env
+export HF_TOKEN=[REDACTED:HF_TOKEN]
+export WANDB_API_KEY=[REDACTED:WANDB_API_KEY]
+export CODEX_API_KEY=[REDACTED:CODEX_API_KEY]
+export CUDA_VISIBLE_DEVICES=0
+`
+	if got != want {
+		t.Fatalf("unexpected env.sh:\n%s", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r := NewRedactor()
+	r.AddSecret("HF_TOKEN", "hf_QnV3cmVkYWN0ZWRUb2tlbkZvclRlc3Rz")
+	r.AddSecret("SHORT", "abc")
+	r.IPs = true
+	r.AddHost("spark-a.lab")
+	r.AddUser("alice")
+	r.AddUser("root")
+
+	input := "docker run -e HF_TOKEN=hf_QnV3cmVkYWN0ZWRUb2tlbkZvclRlc3Rz vllm\n" +
+		"alice@spark-a.lab (192.168.1.20) via 127.0.0.1, /home/alice/models, owner root, malice abc\n"
+	got := string(r.Redact([]byte(input)))
+	want := "docker run -e HF_TOKEN=[REDACTED:HF_TOKEN] vllm\n" +
+		"[USER]@[HOST] ([IP]) via 127.0.0.1, /home/[USER]/models, owner root, malice abc\n"
+	if got != want {
+		t.Fatalf("unexpected redaction:\n%s", got)
+	}
+	if names := strings.Join(r.SecretNames(), ","); names != "HF_TOKEN" {
+		t.Fatalf("unexpected secret names %s", names)
+	}
+}
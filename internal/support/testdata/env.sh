@@ -0,0 +1,5 @@
+# Managed by dgx env
+export HF_TOKEN=hf_QnV3cmVkYWN0ZWRUb2tlbkZvclRlc3Rz
+export WANDB_API_KEY='0123456789abcdef'"'"'0123456789abcdef'
+export CODEX_API_KEY="sk-proj-test-codex-key"
+export CUDA_VISIBLE_DEVICES=0
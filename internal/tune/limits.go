@@ -0,0 +1,600 @@
+package tune
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Setting statuses
+const (
+	StatusOK     = "ok"
+	StatusChange = "change" // dgx tune limits apply fixes it
+	StatusManual = "manual" // Needs a change dgx does not make
+	StatusNA     = "n/a"    // Not present on this system
+)
+
+// Recommended profile for AI workloads. Values the DGX already exceeds are
+// kept, so applying never lowers a limit.
+const (
+	RecommendedNoFile      = 1048576
+	RecommendedMaxMapCount = 1048576
+	RecommendedSwappiness  = 10
+	RecommendedTHP         = "madvise"
+	RecommendedStack       = 67108864
+	RecommendedDockerShm   = "16G"
+	unlimited              = "unlimited"
+)
+
+// Files written by dgx tune limits apply
+const (
+	SysctlFile   = "/etc/sysctl.d/90-dgx.conf"
+	LimitsFile   = "/etc/security/limits.d/90-dgx.conf"
+	TmpfilesFile = "/etc/tmpfiles.d/dgx-thp.conf"
+	DaemonFile   = "/etc/docker/daemon.json"
+)
+
+const (
+	managedHeader    = "# Managed by dgx tune limits\n"
+	daemonBackup     = DaemonFile + ".dgx-backup"
+	thpPath          = "/sys/kernel/mm/transparent_hugepage/enabled"
+	dockerDefaultShm = "64M"
+)
+
+var managedFiles = []string{SysctlFile, LimitsFile, TmpfilesFile, DaemonFile}
+
+// Sections returns the commands that read the current limits
+func Sections() []gpu.Section {
+	sections := []gpu.Section{
+		{Name: "nofile", Command: "ulimit -Sn; ulimit -Hn"},
+		{Name: "memlock", Command: "ulimit -Sl; ulimit -Hl"},
+		{Name: "shm", Command: "df -P -B1 /dev/shm | tail -n 1; grep '^MemTotal:' /proc/meminfo"},
+		{Name: "vm", Command: "cat /proc/sys/vm/max_map_count /proc/sys/vm/swappiness"},
+		{Name: "thp", Command: "cat " + thpPath},
+		{Name: "docker", Command: "command -v docker"},
+	}
+	for _, path := range managedFiles {
+		sections = append(sections, gpu.Section{Name: "file " + path, Command: "cat " + path})
+	}
+	return sections
+}
+
+// State is the DGX's current limits as seen by a new SSH session
+type State struct {
+	NoFile      [2]string // Soft and hard; a number or "unlimited"
+	Memlock     [2]string // Soft and hard, in KiB
+	ShmBytes    uint64
+	MemBytes    uint64
+	MaxMapCount int64 // -1 when unknown
+	Swappiness  int64 // -1 when unknown
+	THP         string
+	Docker      bool
+	// Files holds the managed files that exist, by path
+	Files map[string]string
+}
+
+// Read collects the current limits in one SSH session
+func Read(monitor *gpu.Monitor) (State, error) {
+	results, err := monitor.RunSections(Sections())
+	if err != nil {
+		return State{}, fmt.Errorf("failed to read limits: %w", err)
+	}
+	return Parse(results), nil
+}
+
+// Parse builds a State from collected sections
+func Parse(results map[string]gpu.SectionResult) State {
+	s := State{MaxMapCount: -1, Swappiness: -1, Files: make(map[string]string)}
+	lines := func(name string) []string {
+		r, ok := results[name]
+		if !ok || r.Exit != 0 {
+			return nil
+		}
+		return strings.Fields(r.Output)
+	}
+
+	if f := lines("nofile"); len(f) == 2 {
+		s.NoFile = [2]string{f[0], f[1]}
+	}
+	if f := lines("memlock"); len(f) == 2 {
+		s.Memlock = [2]string{f[0], f[1]}
+	}
+	if r, ok := results["shm"]; ok {
+		for _, line := range strings.Split(r.Output, "\n") {
+			f := strings.Fields(line)
+			switch {
+			case len(f) >= 3 && f[0] == "MemTotal:":
+				kb, _ := strconv.ParseUint(f[1], 10, 64)
+				s.MemBytes = kb * 1024
+			case len(f) >= 6 && f[5] == "/dev/shm":
+				s.ShmBytes, _ = strconv.ParseUint(f[1], 10, 64)
+			}
+		}
+	}
+	if f := lines("vm"); len(f) == 2 {
+		if v, err := strconv.ParseInt(f[0], 10, 64); err == nil {
+			s.MaxMapCount = v
+		}
+		if v, err := strconv.ParseInt(f[1], 10, 64); err == nil {
+			s.Swappiness = v
+		}
+	}
+	for _, mode := range lines("thp") {
+		if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+			s.THP = strings.Trim(mode, "[]")
+		}
+	}
+	s.Docker = results["docker"].Exit == 0 && strings.TrimSpace(results["docker"].Output) != ""
+	for _, path := range managedFiles {
+		if r, ok := results["file "+path]; ok && r.Exit == 0 {
+			s.Files[path] = r.Output
+		}
+	}
+	return s
+}
+
+// dockerUlimit is one entry of daemon.json's default-ulimits
+type dockerUlimit struct {
+	Name string `json:"Name"`
+	Hard int64  `json:"Hard"`
+	Soft int64  `json:"Soft"`
+}
+
+// recommendedUlimits are the Docker defaults NGC containers expect; -1 is
+// unlimited
+var recommendedUlimits = []dockerUlimit{
+	{Name: "memlock", Hard: -1, Soft: -1},
+	{Name: "nofile", Hard: RecommendedNoFile, Soft: RecommendedNoFile},
+	{Name: "stack", Hard: RecommendedStack, Soft: RecommendedStack},
+}
+
+// daemonConfig is daemon.json with the keys dgx manages decoded and every
+// other key kept verbatim
+type daemonConfig struct {
+	raw     map[string]json.RawMessage
+	ulimits map[string]dockerUlimit
+	shmSize string
+}
+
+func parseDaemon(data string) (daemonConfig, error) {
+	d := daemonConfig{raw: make(map[string]json.RawMessage), ulimits: make(map[string]dockerUlimit)}
+	if strings.TrimSpace(data) == "" {
+		return d, nil
+	}
+	if err := json.Unmarshal([]byte(data), &d.raw); err != nil {
+		return d, fmt.Errorf("%s is not valid JSON: %w", DaemonFile, err)
+	}
+	if v, ok := d.raw["default-ulimits"]; ok {
+		if err := json.Unmarshal(v, &d.ulimits); err != nil {
+			return d, fmt.Errorf("%s: invalid default-ulimits: %w", DaemonFile, err)
+		}
+	}
+	if v, ok := d.raw["default-shm-size"]; ok {
+		if err := json.Unmarshal(v, &d.shmSize); err != nil {
+			return d, fmt.Errorf("%s: invalid default-shm-size: %w", DaemonFile, err)
+		}
+	}
+	return d, nil
+}
+
+// Settings compares the state with the recommended profile
+func (s State) Settings() []types.TuneSetting {
+	settings := []types.TuneSetting{
+		{
+			Name:        "ulimit -n",
+			Current:     formatLimit(s.NoFile),
+			Recommended: strconv.Itoa(RecommendedNoFile),
+			Status:      limitStatus(s.NoFile, RecommendedNoFile),
+			File:        LimitsFile,
+		},
+		{
+			Name:        "ulimit -l",
+			Current:     formatLimit(s.Memlock),
+			Recommended: unlimited,
+			Status:      limitStatus(s.Memlock, -1),
+			File:        LimitsFile,
+		},
+		s.shmSetting(),
+		{
+			Name:        "vm.max_map_count",
+			Current:     formatInt(s.MaxMapCount),
+			Recommended: strconv.Itoa(RecommendedMaxMapCount),
+			Status:      statusIf(s.MaxMapCount >= RecommendedMaxMapCount),
+			File:        SysctlFile,
+		},
+		{
+			Name:        "vm.swappiness",
+			Current:     formatInt(s.Swappiness),
+			Recommended: fmt.Sprintf("<= %d", RecommendedSwappiness),
+			Status:      statusIf(s.Swappiness >= 0 && s.Swappiness <= RecommendedSwappiness),
+			File:        SysctlFile,
+		},
+		{
+			Name:        "transparent hugepages",
+			Current:     orUnknown(s.THP),
+			Recommended: RecommendedTHP,
+			Status:      statusIf(s.THP == RecommendedTHP),
+			File:        TmpfilesFile,
+		},
+	}
+	if s.THP == "" {
+		settings[len(settings)-1].Status = StatusNA
+	}
+	return append(settings, s.dockerSettings()...)
+}
+
+func (s State) shmSetting() types.TuneSetting {
+	want := s.MemBytes / 2
+	setting := types.TuneSetting{
+		Name:        "/dev/shm",
+		Current:     gpu.FormatBytes(&s.ShmBytes),
+		Recommended: ">= " + gpu.FormatBytes(&want) + " (half of RAM)",
+		Status:      StatusOK,
+		File:        "/etc/fstab",
+	}
+	if s.ShmBytes == 0 || s.MemBytes == 0 {
+		setting.Current = "unknown"
+		setting.Recommended = ">= half of RAM"
+		setting.Status = StatusManual
+	} else if s.ShmBytes < want {
+		setting.Status = StatusManual
+	}
+	if setting.Status == StatusManual {
+		setting.Hint = "add 'tmpfs /dev/shm tmpfs defaults,size=50% 0 0' to /etc/fstab and run 'sudo mount -o remount /dev/shm'; containers get their own /dev/shm from Docker"
+	}
+	return setting
+}
+
+func (s State) dockerSettings() []types.TuneSetting {
+	ulimits := types.TuneSetting{Name: "docker default-ulimits", Recommended: formatUlimits(recommendedUlimits), File: DaemonFile}
+	shm := types.TuneSetting{Name: "docker default-shm-size", Recommended: RecommendedDockerShm, File: DaemonFile}
+	if !s.Docker {
+		ulimits.Current, ulimits.Status = "docker not installed", StatusNA
+		shm.Current, shm.Status = "docker not installed", StatusNA
+		return []types.TuneSetting{ulimits, shm}
+	}
+
+	d, err := parseDaemon(s.Files[DaemonFile])
+	if err != nil {
+		ulimits.Current, ulimits.Status, ulimits.Hint = "unreadable", StatusManual, err.Error()
+		shm.Current, shm.Status = "unreadable", StatusManual
+		return []types.TuneSetting{ulimits, shm}
+	}
+
+	var current []dockerUlimit
+	ulimitsOK := true
+	for _, want := range recommendedUlimits {
+		have, ok := d.ulimits[want.Name]
+		if ok {
+			current = append(current, have)
+		}
+		if !ok || !atLeast(have.Soft, want.Soft) || !atLeast(have.Hard, want.Hard) {
+			ulimitsOK = false
+		}
+	}
+	ulimits.Current = formatUlimits(current)
+	ulimits.Status = statusIf(ulimitsOK)
+
+	shm.Current = d.shmSize
+	if shm.Current == "" {
+		shm.Current = dockerDefaultShm + " (default)"
+	}
+	shm.Status = statusIf(shmSizeOK(d.shmSize))
+	return []types.TuneSetting{ulimits, shm}
+}
+
+// File is a managed file and the content dgx would write
+type File struct {
+	Path   string
+	Old    string
+	Exists bool
+	New    string
+}
+
+// Diff returns the unified diff from the file's current to its new content
+func (f File) Diff() string {
+	return unifiedDiff(f.Path, f.Old, f.New, f.Exists)
+}
+
+// Plan returns the files that need to change to meet the recommended
+// profile. A file is only rewritten when one of its settings needs a
+// change, so applying twice is a no-op.
+func (s State) Plan() ([]File, error) {
+	needs := make(map[string]bool)
+	for _, setting := range s.Settings() {
+		if setting.Status == StatusChange {
+			needs[setting.File] = true
+		}
+	}
+
+	var files []File
+	add := func(path, content string) {
+		old, exists := s.Files[path]
+		if content != old {
+			files = append(files, File{Path: path, Old: old, Exists: exists, New: content})
+		}
+	}
+
+	if needs[SysctlFile] {
+		add(SysctlFile, managedHeader+fmt.Sprintf("vm.max_map_count = %d\nvm.swappiness = %d\n",
+			max(s.MaxMapCount, RecommendedMaxMapCount), lowerOf(s.Swappiness, RecommendedSwappiness)))
+	}
+	if needs[LimitsFile] {
+		add(LimitsFile, limitsContent(s))
+	}
+	if needs[TmpfilesFile] {
+		add(TmpfilesFile, managedHeader+fmt.Sprintf("w %s - - - - %s\n", thpPath, RecommendedTHP))
+	}
+	if needs[DaemonFile] {
+		content, err := s.daemonContent()
+		if err != nil {
+			return nil, err
+		}
+		add(DaemonFile, content)
+	}
+	return files, nil
+}
+
+// limitsContent raises nofile and memlock for every user. pam_limits does
+// not apply the * wildcard to root, so root gets its own lines.
+func limitsContent(s State) string {
+	soft := higherLimit(s.NoFile[0], RecommendedNoFile)
+	hard := higherLimit(s.NoFile[1], RecommendedNoFile)
+	var sb strings.Builder
+	sb.WriteString(managedHeader)
+	w := tabwriter.NewWriter(&sb, 0, 0, 4, ' ', 0)
+	for _, domain := range []string{"*", "root"} {
+		fmt.Fprintf(w, "%s\tsoft\tnofile\t%s\n", domain, soft)
+		fmt.Fprintf(w, "%s\thard\tnofile\t%s\n", domain, hard)
+		fmt.Fprintf(w, "%s\tsoft\tmemlock\t%s\n", domain, unlimited)
+		fmt.Fprintf(w, "%s\thard\tmemlock\t%s\n", domain, unlimited)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// daemonContent merges the recommended defaults into daemon.json, keeping
+// every other key and any larger values already set
+func (s State) daemonContent() (string, error) {
+	d, err := parseDaemon(s.Files[DaemonFile])
+	if err != nil {
+		return "", fmt.Errorf("%w; fix it before applying", err)
+	}
+	for _, want := range recommendedUlimits {
+		have, ok := d.ulimits[want.Name]
+		if ok {
+			want.Soft = higherUlimit(have.Soft, want.Soft)
+			want.Hard = higherUlimit(have.Hard, want.Hard)
+		}
+		d.ulimits[want.Name] = want
+	}
+	ulimits, err := json.Marshal(d.ulimits)
+	if err != nil {
+		return "", err
+	}
+	d.raw["default-ulimits"] = ulimits
+	if !shmSizeOK(d.shmSize) {
+		d.raw["default-shm-size"], _ = json.Marshal(RecommendedDockerShm)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(d.raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ApplyScript writes the files with sudo and loads the new settings.
+// daemon.json is backed up first; Docker is only restarted when asked,
+// since that stops running containers.
+func ApplyScript(files []File, restartDocker bool) string {
+	var sb strings.Builder
+	sb.WriteString("set -e\nsudo -v\n")
+	for _, f := range files {
+		if f.Path == DaemonFile {
+			fmt.Fprintf(&sb, "sudo install -d -m 0755 /etc/docker\n")
+			fmt.Fprintf(&sb, "if [ -f %s ]; then sudo cp -p %s %s; fi\n", DaemonFile, DaemonFile, daemonBackup)
+		}
+		fmt.Fprintf(&sb, "echo '%s' | base64 -d | sudo tee %s >/dev/null\n",
+			base64.StdEncoding.EncodeToString([]byte(f.New)), f.Path)
+		fmt.Fprintf(&sb, "echo 'wrote %s'\n", f.Path)
+	}
+	for _, cmd := range FollowUp(files, restartDocker) {
+		fmt.Fprintf(&sb, "%s\n", cmd)
+	}
+	return sb.String()
+}
+
+// FollowUp returns the commands run after writing files to load them
+func FollowUp(files []File, restartDocker bool) []string {
+	var cmds []string
+	for _, f := range files {
+		switch f.Path {
+		case SysctlFile:
+			cmds = append(cmds, "sudo sysctl -p "+SysctlFile)
+		case TmpfilesFile:
+			cmds = append(cmds, "sudo systemd-tmpfiles --create "+TmpfilesFile)
+		case DaemonFile:
+			if restartDocker {
+				cmds = append(cmds, "sudo systemctl restart docker")
+			}
+		}
+	}
+	return cmds
+}
+
+// Notes explains what still has to happen for changed files to take effect
+func Notes(files []File, restartDocker bool) []string {
+	var notes []string
+	for _, f := range files {
+		switch f.Path {
+		case LimitsFile:
+			notes = append(notes, "ulimits apply to new login sessions; reconnect and restart long-running services")
+		case DaemonFile:
+			if restartDocker {
+				notes = append(notes, "Docker defaults apply to containers started from now on; previous daemon.json saved as "+daemonBackup)
+			} else {
+				notes = append(notes, "restart Docker to apply daemon.json (stops running containers): sudo systemctl restart docker, or rerun with --restart-docker")
+			}
+		}
+	}
+	return notes
+}
+
+// Format formats settings as a table followed by hints for the ones dgx
+// cannot change itself
+func Format(settings []types.TuneSetting) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tCURRENT\tRECOMMENDED\tSTATUS\tFILE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Current, s.Recommended, s.Status, s.File)
+	}
+	w.Flush()
+
+	first := true
+	for _, s := range settings {
+		if s.Hint == "" {
+			continue
+		}
+		if first {
+			sb.WriteString("\nHints:\n")
+			first = false
+		}
+		fmt.Fprintf(&sb, "  %s: %s\n", s.Name, s.Hint)
+	}
+	return sb.String()
+}
+
+func statusIf(ok bool) string {
+	if ok {
+		return StatusOK
+	}
+	return StatusChange
+}
+
+// limitStatus checks that soft and hard limits are at least want; -1
+// means unlimited
+func limitStatus(limit [2]string, want int64) string {
+	for _, v := range limit {
+		n, ok := parseLimit(v)
+		if !ok || !atLeast(n, want) {
+			return StatusChange
+		}
+	}
+	return StatusOK
+}
+
+// parseLimit parses a ulimit value, returning -1 for unlimited
+func parseLimit(v string) (int64, bool) {
+	if v == unlimited {
+		return -1, true
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	return n, err == nil
+}
+
+// atLeast compares limits where -1 is unlimited
+func atLeast(have, want int64) bool {
+	return have == -1 || (want != -1 && have >= want)
+}
+
+func higherUlimit(have, want int64) int64 {
+	if atLeast(have, want) {
+		return have
+	}
+	return want
+}
+
+func higherLimit(have string, want int64) string {
+	if n, ok := parseLimit(have); ok && atLeast(n, want) {
+		return have
+	}
+	return strconv.FormatInt(want, 10)
+}
+
+func lowerOf(have, want int64) int64 {
+	if have >= 0 && have < want {
+		return have
+	}
+	return want
+}
+
+func formatLimit(limit [2]string) string {
+	if limit[0] == "" {
+		return "unknown"
+	}
+	if limit[0] == limit[1] {
+		return limit[0]
+	}
+	return fmt.Sprintf("%s (hard %s)", limit[0], limit[1])
+}
+
+func formatInt(v int64) string {
+	if v < 0 {
+		return "unknown"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// formatUlimits formats Docker ulimits as name=soft[:hard], sorted by name
+func formatUlimits(ulimits []dockerUlimit) string {
+	if len(ulimits) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(ulimits))
+	for _, u := range ulimits {
+		v := strconv.FormatInt(u.Soft, 10)
+		if u.Hard != u.Soft {
+			v += ":" + strconv.FormatInt(u.Hard, 10)
+		}
+		parts = append(parts, u.Name+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// shmSizeOK reports whether a Docker size such as "16G" or "1gb" is at
+// least the recommended default-shm-size
+func shmSizeOK(size string) bool {
+	have, ok := parseDockerSize(size)
+	want, _ := parseDockerSize(RecommendedDockerShm)
+	return ok && have >= want
+}
+
+// parseDockerSize parses a Docker memory size: a number with an optional
+// binary unit b, k, m, g or t, optionally followed by "b" or "ib"
+func parseDockerSize(size string) (uint64, bool) {
+	s := strings.ToLower(strings.TrimSpace(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "ib"), "b")
+	mult := uint64(1)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("kmgt", s[n-1]); i >= 0 {
+			mult = 1 << (10 * (i + 1))
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return uint64(n * float64(mult)), true
+}
@@ -0,0 +1,208 @@
+package tune
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weatherman/dgx-manager/internal/gpu"
+)
+
+// stockResults are the sections read from a DGX with distribution defaults
+// and the daemon.json in testdata/daemon
+func stockResults(t *testing.T, daemon string) map[string]gpu.SectionResult {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", daemon))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", daemon, err)
+	}
+	return map[string]gpu.SectionResult{
+		"nofile":  {Output: "1024\n524288\n"},
+		"memlock": {Output: "8192\n8192\n"},
+		"shm": {Output: "tmpfs 8000000000 0 8000000000 0% /dev/shm\n" +
+			"MemTotal:       127535708 kB\n"},
+		"vm":                 {Output: "65530\n60\n"},
+		"thp":                {Output: "[always] madvise never\n"},
+		"docker":             {Output: "/usr/bin/docker\n"},
+		"file " + SysctlFile: {Output: "cat: " + SysctlFile + ": No such file or directory\n", Exit: 1},
+		"file " + DaemonFile: {Output: string(data)},
+	}
+}
+
+func settingStatus(t *testing.T, s State, name string) string {
+	t.Helper()
+	for _, setting := range s.Settings() {
+		if setting.Name == name {
+			return setting.Status
+		}
+	}
+	t.Fatalf("no setting %q", name)
+	return ""
+}
+
+func TestParseAndSettings(t *testing.T) {
+	s := Parse(stockResults(t, "daemon-runtime.json"))
+	if s.NoFile != [2]string{"1024", "524288"} || s.MaxMapCount != 65530 || s.Swappiness != 60 || s.THP != "always" {
+		t.Fatalf("unexpected state: %+v", s)
+	}
+	if _, ok := s.Files[SysctlFile]; ok {
+		t.Fatalf("missing file should not be recorded")
+	}
+
+	want := map[string]string{
+		"ulimit -n":               StatusChange,
+		"ulimit -l":               StatusChange,
+		"/dev/shm":                StatusManual, // 8 GB of 128 GB RAM
+		"vm.max_map_count":        StatusChange,
+		"vm.swappiness":           StatusChange,
+		"transparent hugepages":   StatusChange,
+		"docker default-ulimits":  StatusChange,
+		"docker default-shm-size": StatusChange,
+	}
+	for name, status := range want {
+		if got := settingStatus(t, s, name); got != status {
+			t.Fatalf("%s: expected %s, got %s", name, status, got)
+		}
+	}
+}
+
+func TestPlanMergesDaemonJSON(t *testing.T) {
+	s := Parse(stockResults(t, "daemon-runtime.json"))
+	files, err := s.Plan()
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %d", len(files))
+	}
+
+	var daemon File
+	for _, f := range files {
+		if f.Path == DaemonFile {
+			daemon = f
+		}
+	}
+	if !daemon.Exists || !strings.Contains(daemon.New, `"nvidia-container-runtime"`) || !strings.Contains(daemon.New, `"default-shm-size": "16G"`) {
+		t.Fatalf("daemon.json should keep the runtime and add defaults:\n%s", daemon.New)
+	}
+	if !strings.Contains(daemon.Diff(), `+    "default-shm-size": "16G",`) {
+		t.Fatalf("unexpected diff:\n%s", daemon.Diff())
+	}
+}
+
+func TestPlanKeepsHigherValues(t *testing.T) {
+	results := stockResults(t, "daemon-tuned.json")
+	results["nofile"] = gpu.SectionResult{Output: "1024\n4194304\n"}
+	results["vm"] = gpu.SectionResult{Output: "2097152\n1\n"}
+	s := Parse(results)
+
+	files, err := s.Plan()
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	for _, f := range files {
+		switch f.Path {
+		case DaemonFile:
+			t.Fatalf("daemon.json already meets the profile, got diff:\n%s", f.Diff())
+		case SysctlFile:
+			if f.New != managedHeader+"vm.max_map_count = 2097152\nvm.swappiness = 1\n" {
+				t.Fatalf("sysctl values were lowered:\n%s", f.New)
+			}
+		case LimitsFile:
+			if !strings.Contains(f.New, "hard    nofile     4194304") {
+				t.Fatalf("hard nofile was lowered:\n%s", f.New)
+			}
+		}
+	}
+}
+
+func TestPlanIsIdempotent(t *testing.T) {
+	s := Parse(stockResults(t, "daemon-runtime.json"))
+	files, err := s.Plan()
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+
+	// What the DGX reports once the files are in place
+	applied := map[string]gpu.SectionResult{
+		"nofile":  {Output: "1048576\n1048576\n"},
+		"memlock": {Output: "unlimited\nunlimited\n"},
+		"vm":      {Output: "1048576\n10\n"},
+		"thp":     {Output: "always [madvise] never\n"},
+		"docker":  {Output: "/usr/bin/docker\n"},
+	}
+	for _, f := range files {
+		applied["file "+f.Path] = gpu.SectionResult{Output: f.New}
+	}
+	again, err := Parse(applied).Plan()
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("expected no changes after apply, got %d: %s", len(again), again[0].Diff())
+	}
+}
+
+func TestInvalidDaemonJSONIsManual(t *testing.T) {
+	results := stockResults(t, "daemon-runtime.json")
+	results["file "+DaemonFile] = gpu.SectionResult{Output: "{ not json"}
+	s := Parse(results)
+	if got := settingStatus(t, s, "docker default-ulimits"); got != StatusManual {
+		t.Fatalf("expected manual, got %s", got)
+	}
+	files, err := s.Plan()
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	for _, f := range files {
+		if f.Path == DaemonFile {
+			t.Fatalf("invalid daemon.json must not be rewritten")
+		}
+	}
+}
+
+func TestParseDockerSize(t *testing.T) {
+	tests := map[string]uint64{
+		"64M":   64 << 20,
+		"16g":   16 << 30,
+		"16GB":  16 << 30,
+		"1gib":  1 << 30,
+		"2048":  2048,
+		"512kb": 512 << 10,
+	}
+	for in, want := range tests {
+		if got, ok := parseDockerSize(in); !ok || got != want {
+			t.Fatalf("%s: expected %d, got %d (ok=%v)", in, want, got, ok)
+		}
+	}
+	if _, ok := parseDockerSize("lots"); ok {
+		t.Fatalf("expected an error for an invalid size")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- f.conf
++++ f.conf
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := unifiedDiff("f.conf", old, new, true); got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if got := unifiedDiff("f.conf", old, old, true); got != "" {
+		t.Fatalf("expected no diff for equal content, got:\n%s", got)
+	}
+}
@@ -0,0 +1,94 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/config"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Control socket operations understood by the tunnel daemon
+const (
+	opPing     = "ping"
+	opCreate   = "create"
+	opList     = "list"
+	opKill     = "kill"
+	opShutdown = "shutdown"
+)
+
+// controlRequest is a single request sent over the daemon control socket
+type controlRequest struct {
+	Op     string       `json:"op"`
+	ID     string       `json:"id,omitempty"`
+	Tunnel types.Tunnel `json:"tunnel,omitempty"`
+}
+
+// controlResponse is the daemon's reply to a controlRequest
+type controlResponse struct {
+	Error   string         `json:"error,omitempty"`
+	PID     int            `json:"pid,omitempty"`
+	Tunnels []types.Tunnel `json:"tunnels,omitempty"`
+}
+
+// daemonPaths holds the per-profile files used by the tunnel daemon
+type daemonPaths struct {
+	Socket string
+	PID    string
+	Log    string
+	Events string
+}
+
+// runtimeDir returns the directory holding daemon sockets, PID files and logs
+func runtimeDir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "run")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	return dir, nil
+}
+
+func pathsFor(profile string) (daemonPaths, error) {
+	dir, err := runtimeDir()
+	if err != nil {
+		return daemonPaths{}, err
+	}
+	base := filepath.Join(dir, "tunnel-"+profile)
+	return daemonPaths{
+		Socket: base + ".sock",
+		PID:    base + ".pid",
+		Log:    base + ".log",
+		Events: base + ".events",
+	}, nil
+}
+
+// call sends one request to the daemon listening on socketPath
+func call(socketPath string, req controlRequest) (*controlResponse, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to tunnel daemon: %w", err)
+	}
+
+	var resp controlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read tunnel daemon response: %w", err)
+	}
+	if resp.Error != "" {
+		return &resp, fmt.Errorf("%s", resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,267 @@
+package tunnel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/weatherman/dgx-manager/internal/ssh"
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Daemon owns every native tunnel for one profile over a single
+// multiplexed SSH connection and serves a local control socket
+type Daemon struct {
+	// KeepaliveInterval is how often the SSH connection is checked
+	KeepaliveInterval time.Duration
+	// ProbeInterval is how often forwarded services are health-checked
+	ProbeInterval time.Duration
+
+	config     *types.Config
+	client     *ssh.Client
+	mu         sync.Mutex
+	forwards   map[string]*forward
+	done       chan struct{}
+	stopOnce   sync.Once
+	eventsPath string
+}
+
+// forward is a running local, reverse or dynamic forward
+type forward struct {
+	tunnel   types.Tunnel
+	listener net.Listener
+}
+
+// NewDaemon creates a tunnel daemon for the given profile
+func NewDaemon(config *types.Config) *Daemon {
+	// The daemon runs detached, so host key prompts could not be answered
+	client, _ := ssh.NewClient(config)
+	client.SetBatchMode(true)
+	return &Daemon{
+		KeepaliveInterval: DefaultKeepaliveInterval,
+		ProbeInterval:     DefaultProbeInterval,
+		config:            config,
+		client:            client,
+		forwards:          make(map[string]*forward),
+		done:              make(chan struct{}),
+	}
+}
+
+// Run connects to the DGX and serves control requests until the daemon is
+// shut down, it receives SIGINT/SIGTERM, or its last tunnel is killed
+func (d *Daemon) Run() error {
+	paths, err := pathsFor(d.config.Name)
+	if err != nil {
+		return err
+	}
+	d.eventsPath = paths.Events
+
+	if _, err := call(paths.Socket, controlRequest{Op: opPing}); err == nil {
+		return fmt.Errorf("tunnel daemon for profile %s is already running", d.config.Name)
+	}
+	os.Remove(paths.Socket)
+
+	if err := d.client.Connect(); err != nil {
+		return err
+	}
+	defer d.client.Close()
+
+	listener, err := net.Listen("unix", paths.Socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	defer os.Remove(paths.Socket)
+	defer listener.Close()
+
+	if err := os.WriteFile(paths.PID, []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	defer os.Remove(paths.PID)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			d.stop()
+		case <-d.done:
+		}
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				d.stop()
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+
+	fmt.Printf("%s Tunnel daemon started for %s@%s (PID %d)\n",
+		time.Now().Format(time.RFC3339), d.config.User, d.config.Host, os.Getpid())
+	d.restore()
+	go d.supervise()
+	<-d.done
+
+	d.mu.Lock()
+	for id, f := range d.forwards {
+		f.listener.Close()
+		delete(d.forwards, id)
+	}
+	d.mu.Unlock()
+
+	fmt.Printf("%s Tunnel daemon stopped\n", time.Now().Format(time.RFC3339))
+	return nil
+}
+
+// restore brings up saved tunnels marked autostart
+func (d *Daemon) restore() {
+	for _, t := range d.config.Tunnels {
+		if !t.Autostart || t.Backend == BackendSSH {
+			continue
+		}
+		if err := d.create(t); err != nil {
+			fmt.Printf("%s Failed to restore %s: %v\n", time.Now().Format(time.RFC3339), t.ID, err)
+		}
+	}
+}
+
+func (d *Daemon) stop() {
+	d.stopOnce.Do(func() { close(d.done) })
+}
+
+// serve handles a single control connection
+func (d *Daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	var req controlRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	resp := controlResponse{PID: os.Getpid()}
+	var err error
+	switch req.Op {
+	case opPing:
+	case opCreate:
+		err = d.create(req.Tunnel)
+	case opList:
+		resp.Tunnels = d.list()
+	case opKill:
+		err = d.kill(req.ID)
+	case opShutdown:
+		defer d.stop()
+	default:
+		err = fmt.Errorf("unknown operation: %s", req.Op)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// create starts a tunnel over the daemon's SSH connection
+func (d *Daemon) create(t types.Tunnel) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t.ID == "" {
+		return fmt.Errorf("tunnel ID required")
+	}
+	if existing, exists := d.forwards[t.ID]; exists {
+		// Restored autostart tunnels may be requested again by 'tunnel up'
+		if bindKey(existing.tunnel) == bindKey(t) && sameTarget(existing.tunnel, t) {
+			return nil
+		}
+		return fmt.Errorf("tunnel already exists: %s", t.ID)
+	}
+	for _, f := range d.forwards {
+		if bindKey(f.tunnel) == bindKey(t) {
+			if KindOf(t) == KindRemote {
+				return fmt.Errorf("remote port %d is already forwarded by %s", t.RemotePort, f.tunnel.ID)
+			}
+			return fmt.Errorf("local port %d is already forwarded by %s", t.LocalPort, f.tunnel.ID)
+		}
+	}
+	if t.RemoteHost == "" {
+		t.RemoteHost = "localhost"
+	}
+
+	listener, err := d.listen(t)
+	if err != nil {
+		return err
+	}
+
+	t.Backend = BackendDaemon
+	t.Health = HealthUp
+	t.PID = os.Getpid()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	f := &forward{tunnel: t, listener: listener}
+	d.forwards[t.ID] = f
+	go d.check(f)
+
+	fmt.Printf("%s Forwarding %s (%s)\n", time.Now().Format(time.RFC3339), Describe(t), t.ID)
+	return nil
+}
+
+// listen opens the listener for a tunnel on the daemon's SSH connection
+func (d *Daemon) listen(t types.Tunnel) (net.Listener, error) {
+	switch KindOf(t) {
+	case KindRemote:
+		return d.client.ReverseForward(t.RemotePort, fmt.Sprintf("localhost:%d", t.LocalPort))
+	case KindDynamic:
+		return d.client.DynamicForward(t.LocalPort)
+	case KindLocal:
+		return d.client.ForwardPort(t.LocalPort, t.RemotePort, t.RemoteHost)
+	default:
+		return nil, fmt.Errorf("unknown tunnel kind: %s", t.Kind)
+	}
+}
+
+func (d *Daemon) list() []types.Tunnel {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tunnels := make([]types.Tunnel, 0, len(d.forwards))
+	for _, f := range d.forwards {
+		tunnels = append(tunnels, f.tunnel)
+	}
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].LocalPort < tunnels[j].LocalPort
+	})
+	return tunnels
+}
+
+// kill stops a forward and shuts the daemon down once nothing is left
+func (d *Daemon) kill(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, ok := d.forwards[id]
+	if !ok {
+		return fmt.Errorf("tunnel not found: %s", id)
+	}
+	f.listener.Close()
+	delete(d.forwards, id)
+
+	fmt.Printf("%s Stopped %s (%s)\n", time.Now().Format(time.RFC3339), Describe(f.tunnel), id)
+
+	if len(d.forwards) == 0 {
+		go d.stop()
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package tunnel
+
+import "syscall"
+
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the daemon without a console so it outlives the CLI
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -0,0 +1,51 @@
+package tunnel
+
+import (
+	"fmt"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Tunnel kinds
+const (
+	// KindLocal forwards a local port to a port on (or reachable from) the DGX (-L)
+	KindLocal = "local"
+	// KindRemote opens a port on the DGX that connects back to a local port (-R)
+	KindRemote = "remote"
+	// KindDynamic runs a local SOCKS5 proxy whose connections leave from the DGX (-D)
+	KindDynamic = "dynamic"
+)
+
+// KindOf returns a tunnel's kind, treating an empty kind as local
+func KindOf(t types.Tunnel) string {
+	if t.Kind == "" {
+		return KindLocal
+	}
+	return t.Kind
+}
+
+// Describe formats a tunnel's forwarding direction for display
+func Describe(t types.Tunnel) string {
+	host := t.RemoteHost
+	if host == "" {
+		host = "localhost"
+	}
+
+	switch KindOf(t) {
+	case KindRemote:
+		return fmt.Sprintf("dgx:%d -> localhost:%d", t.RemotePort, t.LocalPort)
+	case KindDynamic:
+		return fmt.Sprintf("localhost:%d -> SOCKS5 via dgx", t.LocalPort)
+	default:
+		return fmt.Sprintf("localhost:%d -> %s:%d", t.LocalPort, host, t.RemotePort)
+	}
+}
+
+// bindKey identifies the port a tunnel listens on. Local and dynamic
+// tunnels listen locally; remote tunnels listen on the DGX.
+func bindKey(t types.Tunnel) string {
+	if KindOf(t) == KindRemote {
+		return fmt.Sprintf("remote:%d", t.RemotePort)
+	}
+	return fmt.Sprintf("local:%d", t.LocalPort)
+}
@@ -2,6 +2,7 @@ package tunnel
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"strconv"
@@ -9,9 +10,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/weatherman/dgx-manager/internal/ssh"
 	"github.com/weatherman/dgx-manager/pkg/types"
 )
 
+// Tunnel backends
+const (
+	// BackendDaemon forwards ports natively in Go inside the tunnel daemon
+	BackendDaemon = "daemon"
+	// BackendSSH forwards ports with a detached OpenSSH subprocess
+	BackendSSH = "ssh"
+)
+
 // Manager handles SSH tunnel management
 type Manager struct {
 	config *types.Config
@@ -26,15 +36,194 @@ func NewManager(config *types.Config) *Manager {
 
 // Create creates a new SSH tunnel in the background
 func (m *Manager) Create(tunnel types.Tunnel) error {
+	if tunnel.Backend == BackendSSH {
+		return m.createSSH(tunnel)
+	}
+
+	paths, err := m.ensureDaemon()
+	if err != nil {
+		return err
+	}
+
+	tunnel.Backend = BackendDaemon
+	if _, err := call(paths.Socket, controlRequest{Op: opCreate, Tunnel: tunnel}); err != nil {
+		return fmt.Errorf("failed to create tunnel: %w", err)
+	}
+
+	fmt.Printf("Tunnel created: %s (ID: %s)\n", Describe(tunnel), tunnel.ID)
+	return nil
+}
+
+// List returns all active SSH tunnels for the configured host
+func (m *Manager) List() ([]types.Tunnel, error) {
+	var tunnels []types.Tunnel
+
+	paths, err := pathsFor(m.config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if resp, err := call(paths.Socket, controlRequest{Op: opList}); err == nil {
+		tunnels = append(tunnels, resp.Tunnels...)
+	}
+
+	sshTunnels, err := m.listSSH()
+	if err != nil {
+		return tunnels, err
+	}
+	return append(tunnels, sshTunnels...), nil
+}
+
+// Kill terminates a tunnel by ID. Bare numbers are treated as the PID of
+// an ssh-backend tunnel.
+func (m *Manager) Kill(id string) error {
+	if pid, err := strconv.Atoi(id); err == nil {
+		id = sshTunnelID(pid)
+	}
+
+	tunnels, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tunnels {
+		if t.ID != id {
+			continue
+		}
+		if t.Backend == BackendSSH {
+			return m.killSSH(t.PID)
+		}
+
+		paths, err := pathsFor(m.config.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := call(paths.Socket, controlRequest{Op: opKill, ID: id}); err != nil {
+			return err
+		}
+		fmt.Printf("Tunnel %s terminated\n", id)
+		return nil
+	}
+
+	return fmt.Errorf("tunnel not found: %s", id)
+}
+
+// KillAll terminates all tunnels to the DGX host and stops the daemon
+func (m *Manager) KillAll() error {
+	paths, err := pathsFor(m.config.Name)
+	if err != nil {
+		return err
+	}
+	call(paths.Socket, controlRequest{Op: opShutdown})
+
+	tunnels, err := m.listSSH()
+	if err != nil {
+		return err
+	}
+
+	for _, tunnel := range tunnels {
+		if err := m.killSSH(tunnel.PID); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to kill tunnel %d: %v\n", tunnel.PID, err)
+		}
+	}
+
+	return nil
+}
+
+// DaemonRunning reports whether the tunnel daemon for this profile is up
+func (m *Manager) DaemonRunning() bool {
+	paths, err := pathsFor(m.config.Name)
+	if err != nil {
+		return false
+	}
+	_, err = call(paths.Socket, controlRequest{Op: opPing})
+	return err == nil
+}
+
+// Events returns the most recent tunnel state transitions recorded by the
+// daemon for this profile (all of them when limit <= 0)
+func (m *Manager) Events(limit int) ([]Event, error) {
+	paths, err := pathsFor(m.config.Name)
+	if err != nil {
+		return nil, err
+	}
+	return readEvents(paths.Events, limit)
+}
+
+// ensureDaemon starts the tunnel daemon for this profile if needed and
+// returns its paths once the control socket answers
+func (m *Manager) ensureDaemon() (daemonPaths, error) {
+	paths, err := pathsFor(m.config.Name)
+	if err != nil {
+		return paths, err
+	}
+	if _, err := call(paths.Socket, controlRequest{Op: opPing}); err == nil {
+		return paths, nil
+	}
+
+	// Connect once in the foreground so host key prompts reach the user
+	client, err := ssh.NewClient(m.config)
+	if err != nil {
+		return paths, err
+	}
+	if _, err := client.CheckConnection(); err != nil {
+		return paths, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return paths, fmt.Errorf("failed to locate dgx binary: %w", err)
+	}
+
+	logFile, err := os.OpenFile(paths.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return paths, fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "--profile", m.config.Name, "tunnel", "daemon")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return paths, fmt.Errorf("failed to start tunnel daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := call(paths.Socket, controlRequest{Op: opPing}); err == nil {
+			return paths, nil
+		}
+		select {
+		case <-exited:
+			return paths, fmt.Errorf("tunnel daemon exited during startup; see %s", paths.Log)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	return paths, fmt.Errorf("tunnel daemon did not start; see %s", paths.Log)
+}
+
+// createSSH creates a tunnel with a detached OpenSSH process
+func (m *Manager) createSSH(tunnel types.Tunnel) error {
+	forward, err := sshForwardArgs(tunnel)
+	if err != nil {
+		return err
+	}
+
 	// Build SSH command for port forwarding
 	args := []string{
 		"-N", // Don't execute remote command
 		"-f", // Go to background
+		"-o", fmt.Sprintf("ServerAliveInterval=%d", int(DefaultKeepaliveInterval.Seconds())),
+		"-o", "ServerAliveCountMax=3",
+		"-o", "ExitOnForwardFailure=yes",
 		"-i", m.config.IdentityFile,
 		"-p", fmt.Sprintf("%d", m.config.Port),
-		"-L", fmt.Sprintf("%d:%s:%d", tunnel.LocalPort, tunnel.RemoteHost, tunnel.RemotePort),
-		fmt.Sprintf("%s@%s", m.config.User, m.config.Host),
 	}
+	args = append(args, forward...)
+	args = append(args, fmt.Sprintf("%s@%s", m.config.User, m.config.Host))
 
 	cmd := exec.Command("ssh", args...)
 	cmd.Stdout = os.Stdout
@@ -44,55 +233,66 @@ func (m *Manager) Create(tunnel types.Tunnel) error {
 		return fmt.Errorf("failed to create tunnel: %w", err)
 	}
 
-	// Find the PID of the SSH process we just created
-	pid, err := m.findTunnelPID(tunnel.LocalPort)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Could not find tunnel PID: %v\n", err)
-	} else {
-		tunnel.PID = pid
-	}
+	fmt.Printf("Tunnel created: %s (ssh subprocess)\n", Describe(tunnel))
 
-	tunnel.CreatedAt = time.Now()
+	return nil
+}
 
-	fmt.Printf("Tunnel created: localhost:%d -> %s:%d (PID: %d)\n",
-		tunnel.LocalPort, tunnel.RemoteHost, tunnel.RemotePort, tunnel.PID)
+// sshForwardArgs returns the OpenSSH -L/-R/-D flag for a tunnel
+func sshForwardArgs(tunnel types.Tunnel) ([]string, error) {
+	host := tunnel.RemoteHost
+	if host == "" {
+		host = "localhost"
+	}
 
-	return nil
+	switch KindOf(tunnel) {
+	case KindLocal:
+		return []string{"-L", fmt.Sprintf("%d:%s:%d", tunnel.LocalPort, host, tunnel.RemotePort)}, nil
+	case KindRemote:
+		return []string{"-R", fmt.Sprintf("%d:localhost:%d", tunnel.RemotePort, tunnel.LocalPort)}, nil
+	case KindDynamic:
+		return []string{"-D", fmt.Sprintf("%d", tunnel.LocalPort)}, nil
+	default:
+		return nil, fmt.Errorf("unknown tunnel kind: %s", tunnel.Kind)
+	}
 }
 
-// List returns all active SSH tunnels
-func (m *Manager) List() ([]types.Tunnel, error) {
-	cmd := exec.Command("ps", "aux")
+// listSSH returns tunnels held by OpenSSH subprocesses for our DGX host
+func (m *Manager) listSSH() ([]types.Tunnel, error) {
+	cmd := exec.Command("ps", "ax", "-o", "pid=,args=")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list processes: %w", err)
+		// ps is unavailable on some platforms; only daemon tunnels are visible there
+		return nil, nil
 	}
 
 	var activeTunnels []types.Tunnel
 	lines := strings.Split(string(output), "\n")
 
 	for _, line := range lines {
-		if !strings.Contains(line, "ssh") || !strings.Contains(line, "-L") {
+		if !strings.Contains(line, "ssh") ||
+			!(strings.Contains(line, "-L") || strings.Contains(line, "-R") || strings.Contains(line, "-D")) {
 			continue
 		}
 
-		// Parse SSH command line to extract tunnel info
-		tunnel, err := m.parseTunnelFromPS(line)
-		if err != nil {
+		// Check if this tunnel is for our DGX host
+		if !strings.Contains(line, m.config.Host) {
 			continue
 		}
 
-		// Check if this tunnel is for our DGX host
-		if strings.Contains(line, m.config.Host) {
-			activeTunnels = append(activeTunnels, tunnel)
+		// Parse SSH command line to extract tunnel info
+		tunnel, err := parseTunnelFromPS(line)
+		if err != nil {
+			continue
 		}
+		activeTunnels = append(activeTunnels, tunnel)
 	}
 
 	return activeTunnels, nil
 }
 
-// Kill terminates a tunnel by PID
-func (m *Manager) Kill(pid int) error {
+// killSSH terminates an ssh-backend tunnel by PID
+func (m *Manager) killSSH(pid int) error {
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		return fmt.Errorf("failed to find process %d: %w", pid, err)
@@ -106,89 +306,83 @@ func (m *Manager) Kill(pid int) error {
 	return nil
 }
 
-// KillAll terminates all tunnels to the DGX host
-func (m *Manager) KillAll() error {
-	tunnels, err := m.List()
-	if err != nil {
-		return err
-	}
-
-	for _, tunnel := range tunnels {
-		if err := m.Kill(tunnel.PID); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to kill tunnel %d: %v\n", tunnel.PID, err)
-		}
-	}
-
-	return nil
-}
-
-// findTunnelPID finds the PID of an SSH tunnel by local port
-func (m *Manager) findTunnelPID(localPort int) (int, error) {
-	// Use lsof to find the process listening on the local port
-	cmd := exec.Command("lsof", "-ti", fmt.Sprintf("tcp:%d", localPort))
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
-	}
-
-	pidStr := strings.TrimSpace(string(output))
-	lines := strings.Split(pidStr, "\n")
-	if len(lines) == 0 {
-		return 0, fmt.Errorf("no process found on port %d", localPort)
-	}
-
-	// Return the first PID (SSH process)
-	pid, err := strconv.Atoi(lines[0])
-	if err != nil {
-		return 0, fmt.Errorf("invalid PID: %s", lines[0])
-	}
-
-	return pid, nil
-}
-
-// parseTunnelFromPS extracts tunnel information from ps output
-func (m *Manager) parseTunnelFromPS(line string) (types.Tunnel, error) {
+// parseTunnelFromPS extracts tunnel information from a "pid args" ps line
+func parseTunnelFromPS(line string) (types.Tunnel, error) {
 	fields := strings.Fields(line)
-	if len(fields) < 11 {
+	if len(fields) < 2 {
 		return types.Tunnel{}, fmt.Errorf("invalid ps line")
 	}
 
-	// Get PID (usually second field)
-	pid, err := strconv.Atoi(fields[1])
+	pid, err := strconv.Atoi(fields[0])
 	if err != nil {
 		return types.Tunnel{}, fmt.Errorf("invalid PID")
 	}
 
-	// Find the -L flag and parse the port forwarding spec
-	var localPort, remotePort int
-	var remoteHost string
+	if !strings.HasSuffix(fields[1], "ssh") {
+		return types.Tunnel{}, fmt.Errorf("not an ssh process")
+	}
 
+	// Find the first -L/-R/-D flag and parse its forwarding spec
 	for i, field := range fields {
-		if field == "-L" && i+1 < len(fields) {
-			// Parse format: localPort:remoteHost:remotePort
-			parts := strings.Split(fields[i+1], ":")
-			if len(parts) == 3 {
-				localPort, _ = strconv.Atoi(parts[0])
-				remoteHost = parts[1]
-				remotePort, _ = strconv.Atoi(parts[2])
-			}
+		if i+1 >= len(fields) {
 			break
 		}
+		spec := fields[i+1]
+		parts := strings.Split(spec, ":")
+
+		tunnel := types.Tunnel{
+			ID:      sshTunnelID(pid),
+			PID:     pid,
+			Backend: BackendSSH,
+		}
+		switch field {
+		case "-L":
+			// Format: localPort:remoteHost:remotePort
+			if len(parts) != 3 {
+				continue
+			}
+			tunnel.Kind = KindLocal
+			tunnel.LocalPort, _ = strconv.Atoi(parts[0])
+			tunnel.RemoteHost = parts[1]
+			tunnel.RemotePort, _ = strconv.Atoi(parts[2])
+		case "-R":
+			// Format: remotePort:localHost:localPort
+			if len(parts) != 3 {
+				continue
+			}
+			tunnel.Kind = KindRemote
+			tunnel.RemotePort, _ = strconv.Atoi(parts[0])
+			tunnel.RemoteHost = "localhost"
+			tunnel.LocalPort, _ = strconv.Atoi(parts[2])
+		case "-D":
+			// Format: [bindAddress:]localPort
+			port, err := strconv.Atoi(parts[len(parts)-1])
+			if err != nil {
+				continue
+			}
+			tunnel.Kind = KindDynamic
+			tunnel.LocalPort = port
+		default:
+			continue
+		}
+		return tunnel, nil
 	}
 
-	return types.Tunnel{
-		PID:        pid,
-		LocalPort:  localPort,
-		RemotePort: remotePort,
-		RemoteHost: remoteHost,
-	}, nil
+	return types.Tunnel{}, fmt.Errorf("no -L/-R/-D forward")
+}
+
+func sshTunnelID(pid int) string {
+	return fmt.Sprintf("ssh-%d", pid)
 }
 
 // IsPortInUse checks if a local port is already in use
 func (m *Manager) IsPortInUse(port int) bool {
-	cmd := exec.Command("lsof", "-ti", fmt.Sprintf("tcp:%d", port))
-	output, err := cmd.Output()
-	return err == nil && len(output) > 0
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	return false
 }
 
 // FindAvailablePort finds an available port starting from the given port
@@ -0,0 +1,41 @@
+package tunnel
+
+import "testing"
+
+func TestParseTunnelFromPS(t *testing.T) {
+	line := "  4242 /usr/bin/ssh -N -f -i /home/alice/.ssh/id_ed25519 -p 22 -L 8000:localhost:8000 alice@192.168.0.10"
+
+	tunnel, err := parseTunnelFromPS(line)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if tunnel.PID != 4242 || tunnel.ID != "ssh-4242" {
+		t.Fatalf("unexpected PID/ID %d/%q", tunnel.PID, tunnel.ID)
+	}
+	if tunnel.LocalPort != 8000 || tunnel.RemotePort != 8000 || tunnel.RemoteHost != "localhost" {
+		t.Fatalf("unexpected forward %+v", tunnel)
+	}
+	if tunnel.Backend != BackendSSH {
+		t.Fatalf("unexpected backend %q", tunnel.Backend)
+	}
+
+	reverse, err := parseTunnelFromPS("4244 ssh -N -f -p 22 -R 5000:localhost:5001 alice@192.168.0.10")
+	if err != nil {
+		t.Fatalf("parse reverse: %v", err)
+	}
+	if reverse.Kind != KindRemote || reverse.RemotePort != 5000 || reverse.LocalPort != 5001 {
+		t.Fatalf("unexpected reverse forward %+v", reverse)
+	}
+
+	socks, err := parseTunnelFromPS("4245 ssh -N -f -p 22 -D 1080 alice@192.168.0.10")
+	if err != nil {
+		t.Fatalf("parse dynamic: %v", err)
+	}
+	if socks.Kind != KindDynamic || socks.LocalPort != 1080 {
+		t.Fatalf("unexpected dynamic forward %+v", socks)
+	}
+
+	if _, err := parseTunnelFromPS("4243 /usr/bin/sshd -D"); err == nil {
+		t.Fatalf("expected sshd line to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package tunnel
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Service is a well-known service on the DGX that can be tunneled by name
+type Service struct {
+	Name        string   `json:"name"`
+	Port        int      `json:"port"`        // Port the service listens on on the DGX
+	HealthPath  string   `json:"health_path"` // HTTP path that answers once the service is ready
+	URLPath     string   `json:"url_path"`    // Path appended to the printed URL
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// services is the registry of named service ports used by 'dgx tunnel open'
+// and by playbook 'serve --tunnel'
+var services = []Service{
+	{Name: "ollama", Port: 11434, HealthPath: "/api/version", Description: "Ollama"},
+	{Name: "vllm", Port: 8000, HealthPath: "/health", URLPath: "/v1", Description: "vLLM"},
+	{Name: "nim", Port: 8000, HealthPath: "/v1/health/ready", URLPath: "/v1", Description: "NVIDIA NIM"},
+	{Name: "trt-llm", Port: 8355, HealthPath: "/health", URLPath: "/v1", Description: "TensorRT-LLM", Aliases: []string{"trtllm"}},
+	{Name: "dmr", Port: 12434, HealthPath: "/models", Description: "Docker Model Runner"},
+	{Name: "jupyter", Port: 8888, HealthPath: "/", Description: "Jupyter", Aliases: []string{"jupyterlab"}},
+	{Name: "open-webui", Port: 8080, HealthPath: "/health", Description: "Open WebUI", Aliases: []string{"openwebui", "webui"}},
+	{Name: "comfyui", Port: 8188, HealthPath: "/", Description: "ComfyUI"},
+	{Name: "tensorboard", Port: 6006, HealthPath: "/", Description: "TensorBoard"},
+	{Name: "llama-factory", Port: 7860, HealthPath: "/", Description: "LLaMA-Factory WebUI"},
+}
+
+// Services returns the service registry sorted by name
+func Services() []Service {
+	list := make([]Service, len(services))
+	copy(list, services)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupService finds a registered service by name or alias
+func LookupService(name string) (Service, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, s := range services {
+		if s.Name == name {
+			return s, nil
+		}
+		for _, alias := range s.Aliases {
+			if alias == name {
+				return s, nil
+			}
+		}
+	}
+	return Service{}, fmt.Errorf("unknown service: %s (see 'dgx tunnel open' for the list)", name)
+}
+
+// URL returns the local URL for a service tunneled to localPort
+func (s Service) URL(localPort int) string {
+	return fmt.Sprintf("http://localhost:%d%s", localPort, s.URLPath)
+}
+
+// Tunnel returns the tunnel definition for a service on localPort
+func (s Service) Tunnel(localPort int) types.Tunnel {
+	return types.Tunnel{
+		ID:          s.Name,
+		LocalPort:   localPort,
+		RemotePort:  s.Port,
+		RemoteHost:  "localhost",
+		Description: s.Description,
+		HealthPath:  s.HealthPath,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// Open tunnels a service to the first free local port at or above its
+// DGX port, or reuses a running tunnel with the service's ID
+func (m *Manager) Open(s Service) (types.Tunnel, error) {
+	live, err := m.List()
+	if err != nil {
+		return types.Tunnel{}, err
+	}
+	for _, t := range live {
+		if t.ID == s.Name {
+			return t, nil
+		}
+	}
+
+	localPort := m.FindAvailablePort(s.Port)
+	if localPort == 0 {
+		return types.Tunnel{}, fmt.Errorf("no free local port near %d", s.Port)
+	}
+
+	t := s.Tunnel(localPort)
+	if err := m.Create(t); err != nil {
+		return types.Tunnel{}, err
+	}
+	return t, nil
+}
+
+// WaitForService polls the service through its tunnel until it answers
+// with a non-5xx status or the timeout expires
+func WaitForService(s Service, localPort int, timeout time.Duration) error {
+	url := fmt.Sprintf("http://localhost:%d%s", localPort, s.HealthPath)
+	client := http.Client{Timeout: probeTimeout}
+	deadline := time.Now().Add(timeout)
+
+	var lastErr error
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return nil
+			}
+			lastErr = fmt.Errorf("%s returned %s", url, resp.Status)
+		} else {
+			lastErr = err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s did not answer within %v: %w", s.Description, timeout, lastErr)
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,34 @@
+package tunnel
+
+import "testing"
+
+func TestLookupService(t *testing.T) {
+	s, err := LookupService("vllm")
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if s.Port != 8000 || s.URL(8001) != "http://localhost:8001/v1" {
+		t.Fatalf("unexpected vllm service %+v", s)
+	}
+
+	alias, err := LookupService("OpenWebUI")
+	if err != nil || alias.Name != "open-webui" {
+		t.Fatalf("expected alias to resolve to open-webui, got %+v (%v)", alias, err)
+	}
+
+	if _, err := LookupService("nope"); err == nil {
+		t.Fatalf("expected unknown service to fail")
+	}
+}
+
+func TestServiceNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, s := range Services() {
+		for _, name := range append([]string{s.Name}, s.Aliases...) {
+			if seen[name] {
+				t.Fatalf("duplicate service name %q", name)
+			}
+			seen[name] = true
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package tunnel
+
+import (
+	"sort"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Tunnel states reported after joining saved definitions with live tunnels
+const (
+	StateUp       = "up"       // Saved and running
+	StateDown     = "down"     // Saved but not running
+	StateUnsaved  = "unsaved"  // Running but not saved
+	StateMismatch = "mismatch" // Saved and running on the same port with a different target
+)
+
+// Status is a tunnel seen from both the config file and the live tunnel list
+type Status struct {
+	Saved *types.Tunnel
+	Live  *types.Tunnel
+	State string
+}
+
+// Tunnel returns the most specific view of the tunnel, preferring live
+// data, with its state and kind filled in
+func (s Status) Tunnel() types.Tunnel {
+	var t types.Tunnel
+	if s.Live != nil {
+		t = *s.Live
+		if s.Saved != nil {
+			if t.Description == "" {
+				t.Description = s.Saved.Description
+			}
+			t.Autostart = s.Saved.Autostart
+		}
+	} else {
+		t = *s.Saved
+	}
+	t.Kind = KindOf(t)
+	t.State = s.State
+	return t
+}
+
+// Drifted reports whether saved and live state disagree
+func (s Status) Drifted() bool {
+	return s.State != StateUp
+}
+
+// Reconcile joins saved tunnel definitions with live tunnels on the port
+// they listen on: the local port, or the DGX port for reverse tunnels
+func Reconcile(saved, live []types.Tunnel) []Status {
+	byKey := make(map[string]*types.Tunnel, len(live))
+	for i := range live {
+		byKey[bindKey(live[i])] = &live[i]
+	}
+
+	statuses := make([]Status, 0, len(saved)+len(live))
+	matched := make(map[string]bool)
+	for i := range saved {
+		s := Status{Saved: &saved[i], State: StateDown}
+		if l, ok := byKey[bindKey(saved[i])]; ok && !matched[bindKey(*l)] {
+			matched[bindKey(*l)] = true
+			s.Live = l
+			s.State = StateUp
+			if !sameTarget(saved[i], *l) {
+				s.State = StateMismatch
+			}
+		}
+		statuses = append(statuses, s)
+	}
+
+	for i := range live {
+		if !matched[bindKey(live[i])] {
+			statuses = append(statuses, Status{Live: &live[i], State: StateUnsaved})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Tunnel().LocalPort < statuses[j].Tunnel().LocalPort
+	})
+	return statuses
+}
+
+func sameTarget(a, b types.Tunnel) bool {
+	if KindOf(a) != KindOf(b) {
+		return false
+	}
+	switch KindOf(a) {
+	case KindRemote:
+		return a.LocalPort == b.LocalPort
+	case KindDynamic:
+		return true
+	}
+
+	hostA, hostB := a.RemoteHost, b.RemoteHost
+	if hostA == "" {
+		hostA = "localhost"
+	}
+	if hostB == "" {
+		hostB = "localhost"
+	}
+	return a.RemotePort == b.RemotePort && hostA == hostB
+}
@@ -0,0 +1,48 @@
+package tunnel
+
+import (
+	"testing"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+func TestReconcile(t *testing.T) {
+	saved := []types.Tunnel{
+		{ID: "vllm", LocalPort: 8000, RemotePort: 8000, RemoteHost: "localhost"},
+		{ID: "jupyter", LocalPort: 8888, RemotePort: 8888, RemoteHost: "localhost"},
+		{ID: "ollama", LocalPort: 11434, RemotePort: 11434, RemoteHost: "localhost"},
+		{ID: "mlflow", LocalPort: 5000, RemotePort: 8000, Kind: KindRemote},
+	}
+	live := []types.Tunnel{
+		{ID: "vllm", LocalPort: 8000, RemotePort: 8000, RemoteHost: "localhost", Backend: BackendDaemon},
+		{ID: "ssh-4242", LocalPort: 6006, RemotePort: 6006, RemoteHost: "localhost", Backend: BackendSSH, PID: 4242},
+		{ID: "ssh-4243", LocalPort: 11434, RemotePort: 11435, RemoteHost: "localhost", Backend: BackendSSH, PID: 4243},
+		{ID: "ssh-4244", LocalPort: 5000, RemotePort: 8000, RemoteHost: "localhost", Kind: KindRemote, Backend: BackendSSH, PID: 4244},
+		{ID: "ssh-4245", LocalPort: 1080, Kind: KindDynamic, Backend: BackendSSH, PID: 4245},
+	}
+
+	statuses := Reconcile(saved, live)
+
+	want := map[int]string{
+		1080:  StateUnsaved,
+		5000:  StateUp,
+		6006:  StateUnsaved,
+		8000:  StateUp,
+		8888:  StateDown,
+		11434: StateMismatch,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d statuses, got %d", len(want), len(statuses))
+	}
+	prev := 0
+	for _, st := range statuses {
+		port := st.Tunnel().LocalPort
+		if port < prev {
+			t.Fatalf("statuses not sorted by local port")
+		}
+		prev = port
+		if st.State != want[port] {
+			t.Fatalf("port %d: expected %s, got %s", port, want[port], st.State)
+		}
+	}
+}
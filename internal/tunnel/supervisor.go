@@ -0,0 +1,280 @@
+package tunnel
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/weatherman/dgx-manager/pkg/types"
+)
+
+// Supervision defaults for daemon tunnels
+const (
+	DefaultKeepaliveInterval = 15 * time.Second
+	DefaultProbeInterval     = 30 * time.Second
+
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+	probeTimeout   = 5 * time.Second
+)
+
+// Tunnel health states reported by the daemon
+const (
+	HealthUp           = "up"           // SSH connection alive and the forwarded service answers
+	HealthDegraded     = "degraded"     // SSH connection alive but the forwarded service does not answer
+	HealthReconnecting = "reconnecting" // SSH connection lost; re-dialing with backoff
+)
+
+// Event records a tunnel or connection state transition
+type Event struct {
+	Time   time.Time `json:"time"`
+	Tunnel string    `json:"tunnel,omitempty"` // Empty for connection-level events
+	From   string    `json:"from,omitempty"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// String formats an event for the event log and CLI
+func (e Event) String() string {
+	subject := "connection"
+	if e.Tunnel != "" {
+		subject = e.Tunnel
+	}
+	from := e.From
+	if from == "" {
+		from = "-"
+	}
+	line := fmt.Sprintf("%s %s: %s -> %s", e.Time.Format(time.RFC3339), subject, from, e.To)
+	if e.Reason != "" {
+		line += " (" + e.Reason + ")"
+	}
+	return line
+}
+
+// nextBackoff doubles the delay up to maxBackoff
+func nextBackoff(current time.Duration) time.Duration {
+	if current <= 0 {
+		return initialBackoff
+	}
+	next := current * 2
+	if next > maxBackoff {
+		return maxBackoff
+	}
+	return next
+}
+
+// supervise keeps the SSH connection alive, re-dials it with exponential
+// backoff when keepalives fail, and probes every forward periodically
+func (d *Daemon) supervise() {
+	keepalive := time.NewTicker(d.KeepaliveInterval)
+	defer keepalive.Stop()
+	probe := time.NewTicker(d.ProbeInterval)
+	defer probe.Stop()
+
+	d.probeAll()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-keepalive.C:
+			if err := d.client.Keepalive(d.KeepaliveInterval); err != nil {
+				d.reconnect(err)
+				d.probeAll()
+			}
+		case <-probe.C:
+			d.probeAll()
+		}
+	}
+}
+
+// reconnect marks every forward as reconnecting and re-dials until the
+// connection is back or the daemon stops
+func (d *Daemon) reconnect(cause error) {
+	d.recordEvent(Event{From: HealthUp, To: HealthReconnecting, Reason: cause.Error()})
+	d.setAllHealth(HealthReconnecting, "ssh connection lost")
+
+	backoff := time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		backoff = nextBackoff(backoff)
+		select {
+		case <-d.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := d.client.Connect(); err != nil {
+			fmt.Printf("%s Reconnect attempt %d failed: %v (next in %v)\n",
+				time.Now().Format(time.RFC3339), attempt, err, nextBackoff(backoff))
+			continue
+		}
+
+		d.recordEvent(Event{From: HealthReconnecting, To: HealthUp, Reason: fmt.Sprintf("reconnected after %d attempt(s)", attempt)})
+		d.rebind()
+		return
+	}
+}
+
+// rebind re-creates reverse listeners, which live on the SSH connection
+// and die with it. Local and dynamic listeners are local and survive.
+func (d *Daemon) rebind() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, f := range d.forwards {
+		if KindOf(f.tunnel) != KindRemote {
+			continue
+		}
+		f.listener.Close()
+		listener, err := d.listen(f.tunnel)
+		if err != nil {
+			fmt.Printf("%s Failed to re-open %s: %v\n", time.Now().Format(time.RFC3339), Describe(f.tunnel), err)
+			continue
+		}
+		f.listener = listener
+	}
+}
+
+// probeAll checks that every forwarded service answers
+func (d *Daemon) probeAll() {
+	d.mu.Lock()
+	forwards := make([]*forward, 0, len(d.forwards))
+	for _, f := range d.forwards {
+		forwards = append(forwards, f)
+	}
+	d.mu.Unlock()
+
+	for _, f := range forwards {
+		d.check(f)
+	}
+}
+
+// check probes a single forward and updates its health
+func (d *Daemon) check(f *forward) {
+	d.mu.Lock()
+	t := f.tunnel
+	d.mu.Unlock()
+
+	if err := d.probe(t); err != nil {
+		d.setHealth(f, HealthDegraded, err.Error())
+	} else {
+		d.setHealth(f, HealthUp, "")
+	}
+}
+
+// probe dials the remote service over SSH, or requests HealthPath through
+// the local forward when one is configured. Reverse tunnels probe the local
+// service they expose; dynamic tunnels have no single target and are healthy
+// while the connection is.
+func (d *Daemon) probe(t types.Tunnel) error {
+	switch KindOf(t) {
+	case KindDynamic:
+		return nil
+	case KindRemote:
+		conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", t.LocalPort), probeTimeout)
+		if err != nil {
+			return fmt.Errorf("local port not answering: %w", err)
+		}
+		conn.Close()
+		return nil
+	}
+
+	if t.HealthPath != "" {
+		path := t.HealthPath
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		client := http.Client{Timeout: probeTimeout}
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%d%s", t.LocalPort, path))
+		if err != nil {
+			return fmt.Errorf("health check failed: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("health check returned %s", resp.Status)
+		}
+		return nil
+	}
+
+	conn, err := d.client.Dial("tcp", net.JoinHostPort(t.RemoteHost, fmt.Sprint(t.RemotePort)))
+	if err != nil {
+		return fmt.Errorf("remote port not answering: %w", err)
+	}
+	conn.Close()
+	return nil
+}
+
+// setHealth updates a forward's health and records the transition
+func (d *Daemon) setHealth(f *forward, health, reason string) {
+	d.mu.Lock()
+	from := f.tunnel.Health
+	if from == health {
+		d.mu.Unlock()
+		return
+	}
+	f.tunnel.Health = health
+	d.mu.Unlock()
+
+	d.recordEvent(Event{Tunnel: f.tunnel.ID, From: from, To: health, Reason: reason})
+}
+
+func (d *Daemon) setAllHealth(health, reason string) {
+	d.mu.Lock()
+	forwards := make([]*forward, 0, len(d.forwards))
+	for _, f := range d.forwards {
+		forwards = append(forwards, f)
+	}
+	d.mu.Unlock()
+
+	for _, f := range forwards {
+		d.setHealth(f, health, reason)
+	}
+}
+
+// recordEvent appends an event to the daemon log and the event log
+func (d *Daemon) recordEvent(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	fmt.Println(e.String())
+
+	if d.eventsPath == "" {
+		return
+	}
+	f, err := os.OpenFile(d.eventsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	json.NewEncoder(f).Encode(e)
+}
+
+// readEvents returns the last limit events from an event log (all when limit <= 0)
+func readEvents(path string, limit int) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+		if limit > 0 && len(events) > limit {
+			events = events[1:]
+		}
+	}
+	return events, scanner.Err()
+}
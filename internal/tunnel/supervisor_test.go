@@ -0,0 +1,49 @@
+package tunnel
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	want := []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second,
+		16 * time.Second, 32 * time.Second, time.Minute, time.Minute,
+	}
+	backoff := time.Duration(0)
+	for i, w := range want {
+		backoff = nextBackoff(backoff)
+		if backoff != w {
+			t.Fatalf("step %d: expected %v, got %v", i, w, backoff)
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tunnel-lab.events")
+	d := &Daemon{eventsPath: path}
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.recordEvent(Event{Time: base, From: HealthUp, To: HealthReconnecting, Reason: "keepalive failed"})
+	d.recordEvent(Event{Time: base.Add(time.Second), Tunnel: "tunnel-8000", From: HealthReconnecting, To: HealthUp})
+	d.recordEvent(Event{Time: base.Add(2 * time.Second), Tunnel: "tunnel-8000", From: HealthUp, To: HealthDegraded})
+
+	events, err := readEvents(path, 2)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[1].To != HealthDegraded || events[1].Tunnel != "tunnel-8000" {
+		t.Fatalf("unexpected last event %+v", events[1])
+	}
+	if got := events[0].String(); got != "2025-01-01T00:00:01Z tunnel-8000: reconnecting -> up" {
+		t.Fatalf("unexpected format %q", got)
+	}
+
+	if events, err := readEvents(filepath.Join(t.TempDir(), "missing"), 0); err != nil || events != nil {
+		t.Fatalf("missing log should be empty, got %v, %v", events, err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package version compares and extracts the version strings reported by
+// DGX tools such as nvidia-smi, nvcc and dmidecode
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Compare compares dotted numeric versions such as 12.8 and 580.95.05,
+// returning -1, 0 or 1; missing components count as 0
+func Compare(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// FirstLine returns the first non-blank line of a command's output, trimmed
+func FirstLine(output string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,12 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	if Compare("13.0", "12.8") != 1 || Compare("12.8", "12.8.0") != 0 || Compare("12.10", "12.9") != 1 {
+		t.Fatalf("unexpected version ordering")
+	}
+	if Compare("580.82", "580.95.05") != -1 {
+		t.Fatalf("expected 580.82 to be older than 580.95.05")
+	}
+}
@@ -2,48 +2,205 @@ package types
 
 import "time"
 
-// Config represents the DGX connection configuration
+// Config represents the DGX connection configuration for a single host
 type Config struct {
-	Host         string `yaml:"host"`
-	Port         int    `yaml:"port"`
-	User         string `yaml:"user"`
-	IdentityFile string `yaml:"identity_file"`
+	Name         string   `yaml:"-"` // Profile name, filled in when loaded
+	Host         string   `yaml:"host"`
+	Port         int      `yaml:"port"`
+	User         string   `yaml:"user"`
+	IdentityFile string   `yaml:"identity_file"`
+	Tunnels      []Tunnel `yaml:"tunnels,omitempty"`
+	Alerts       Alerts   `yaml:"alerts,omitempty"`
+}
+
+// ConfigFile represents the on-disk layout of config.yaml: named host
+// profiles plus a pointer to the one commands use by default
+type ConfigFile struct {
+	CurrentProfile string             `yaml:"current_profile,omitempty"`
+	Profiles       map[string]*Config `yaml:"profiles,omitempty"`
+
+	// Legacy single-host layout, migrated into a "default" profile on load
+	Host         string   `yaml:"host,omitempty"`
+	Port         int      `yaml:"port,omitempty"`
+	User         string   `yaml:"user,omitempty"`
+	IdentityFile string   `yaml:"identity_file,omitempty"`
 	Tunnels      []Tunnel `yaml:"tunnels,omitempty"`
 }
 
 // Tunnel represents an SSH tunnel configuration
 type Tunnel struct {
-	ID          string    `yaml:"id"`
-	LocalPort   int       `yaml:"local_port"`
-	RemotePort  int       `yaml:"remote_port"`
-	RemoteHost  string    `yaml:"remote_host"` // Usually "localhost"
-	Description string    `yaml:"description,omitempty"`
-	PID         int       `yaml:"-"` // Process ID, not saved to config
-	CreatedAt   time.Time `yaml:"created_at,omitempty"`
+	ID          string    `yaml:"id" json:"id"`
+	LocalPort   int       `yaml:"local_port" json:"local_port"`
+	RemotePort  int       `yaml:"remote_port" json:"remote_port"`
+	RemoteHost  string    `yaml:"remote_host" json:"remote_host"` // Usually "localhost"
+	Kind        string    `yaml:"kind,omitempty" json:"kind"`     // "local" (default, -L), "remote" (-R) or "dynamic" (-D SOCKS5)
+	Description string    `yaml:"description,omitempty" json:"description"`
+	Backend     string    `yaml:"backend,omitempty" json:"backend"`         // "daemon" (native Go) or "ssh" (OpenSSH subprocess)
+	Autostart   bool      `yaml:"autostart,omitempty" json:"autostart"`     // Restored whenever the tunnel daemon starts
+	HealthPath  string    `yaml:"health_path,omitempty" json:"health_path"` // Optional HTTP path probed instead of a TCP connect
+	State       string    `yaml:"-" json:"state,omitempty"`                 // Saved vs live state (up/down/unsaved/mismatch), not saved to config
+	Health      string    `yaml:"-" json:"health,omitempty"`                // Live health reported by the tunnel daemon (up/degraded/reconnecting)
+	PID         int       `yaml:"-" json:"pid,omitempty"`                   // Process ID of an ssh-backend tunnel, not saved to config
+	CreatedAt   time.Time `yaml:"created_at,omitempty" json:"created_at"`
+}
+
+// Alerts holds the GPU alert rules of a profile and where to send them
+type Alerts struct {
+	Rules  []AlertRule     `yaml:"rules,omitempty" json:"rules"`
+	Notify []AlertNotifier `yaml:"notify,omitempty" json:"notify"`
+}
+
+// AlertRule is a GPU condition checked by 'dgx gpu alert watch'
+type AlertRule struct {
+	Name           string  `yaml:"name" json:"name"`
+	Metric         string  `yaml:"metric" json:"metric"`                                       // temperature, memory, utilization, power or process
+	Op             string  `yaml:"op,omitempty" json:"op,omitempty"`                           // >, >=, <, <=, == or !=
+	Threshold      float64 `yaml:"threshold,omitempty" json:"threshold,omitempty"`             // In the metric's unit: °C, %, W, or % / GiB for memory
+	Unit           string  `yaml:"unit,omitempty" json:"unit,omitempty"`                       // "%" or "GiB" for memory rules
+	For            string  `yaml:"for,omitempty" json:"for,omitempty"`                         // How long the condition must hold before firing, e.g. 2m
+	GPU            *int    `yaml:"gpu,omitempty" json:"gpu,omitempty"`                         // Only check this GPU index
+	Process        string  `yaml:"process,omitempty" json:"process,omitempty"`                 // Process name for "process" rules
+	WhileContainer string  `yaml:"while_container,omitempty" json:"while_container,omitempty"` // Only check while a matching container runs
+}
+
+// AlertNotifier is a destination for fired and resolved alerts
+type AlertNotifier struct {
+	Webhook string `yaml:"webhook,omitempty" json:"webhook,omitempty"` // Slack-compatible incoming webhook URL
+	Command string `yaml:"command,omitempty" json:"command,omitempty"` // Local shell command run with DGX_ALERT_* variables
 }
 
-// GPUInfo represents GPU status information
+// GPUInfo represents GPU status information. Metrics the driver reports
+// as [N/A] or [Not Supported] (e.g. memory.total on GB10 unified memory)
+// are nil.
 type GPUInfo struct {
-	ID          int
-	Name        string
-	MemoryUsed  string
-	MemoryTotal string
-	Utilization string
-	Temperature string
-	Processes   []GPUProcess
+	ID                 int          `json:"id"`
+	Name               string       `json:"name"`
+	MemoryUsedBytes    *uint64      `json:"memory_used_bytes"`
+	MemoryTotalBytes   *uint64      `json:"memory_total_bytes"`
+	UtilizationPercent *float64     `json:"utilization_percent"`
+	TemperatureCelsius *float64     `json:"temperature_celsius"`
+	PowerDrawWatts     *float64     `json:"power_draw_watts"`
+	PowerLimitWatts    *float64     `json:"power_limit_watts"`
+	GraphicsClockMHz   *float64     `json:"graphics_clock_mhz"`
+	MemoryClockMHz     *float64     `json:"memory_clock_mhz"`
+	Processes          []GPUProcess `json:"processes"`
 }
 
 // GPUProcess represents a process using the GPU
 type GPUProcess struct {
-	PID         int
-	Name        string
-	MemoryUsage string
+	PID             int     `json:"pid"`
+	Name            string  `json:"name"`
+	MemoryUsedBytes *uint64 `json:"memory_used_bytes"`
+}
+
+// HostInfo is a snapshot of the DGX host's resources. Values that could
+// not be read are nil.
+type HostInfo struct {
+	OS                   string   `json:"os,omitempty"`
+	Kernel               string   `json:"kernel,omitempty"`
+	UptimeSeconds        *float64 `json:"uptime_seconds"`
+	Load1                *float64 `json:"load1"`
+	Load5                *float64 `json:"load5"`
+	Load15               *float64 `json:"load15"`
+	CPUs                 int      `json:"cpus,omitempty"`
+	MemoryTotalBytes     *uint64  `json:"memory_total_bytes"`
+	MemoryAvailableBytes *uint64  `json:"memory_available_bytes"`
+	SwapTotalBytes       *uint64  `json:"swap_total_bytes"`
+	SwapFreeBytes        *uint64  `json:"swap_free_bytes"`
+	// MemoryPressurePercent is the share of the last minute some task
+	// stalled waiting for memory (PSI "some avg60")
+	MemoryPressurePercent *float64    `json:"memory_pressure_percent"`
+	TemperatureCelsius    *float64    `json:"temperature_celsius"` // Hottest thermal zone
+	Disks                 []DiskUsage `json:"disks"`
+}
+
+// DiskUsage is the usage of the filesystem holding Path
+type DiskUsage struct {
+	Path           string `json:"path"`
+	Mount          string `json:"mount"`
+	TotalBytes     uint64 `json:"total_bytes"`
+	UsedBytes      uint64 `json:"used_bytes"`
+	AvailableBytes uint64 `json:"available_bytes"`
 }
 
 // ConnectionStatus represents the current connection state
 type ConnectionStatus struct {
-	Connected     bool
-	Host          string
-	ActiveTunnels int
-	Latency       time.Duration
+	Profile       string    `json:"profile"`
+	Host          string    `json:"host"`
+	Port          int       `json:"port"`
+	User          string    `json:"user"`
+	Connected     bool      `json:"connected"`
+	Error         string    `json:"error,omitempty"`
+	LatencyMS     float64   `json:"latency_ms"`
+	ActiveTunnels int       `json:"active_tunnels"`
+	System        *HostInfo `json:"system,omitempty"`
+	GPUs          []GPUInfo `json:"gpus,omitempty"`
+	// Health is the worst status among Checks
+	Health   string           `json:"health,omitempty"`
+	Checks   []HealthCheck    `json:"checks,omitempty"`
+	Services []PlaybookStatus `json:"services,omitempty"`
+	Tunnels  []Tunnel         `json:"tunnels,omitempty"`
+}
+
+// HealthCheck is the result of one status probe
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // "ok", "warn" or "fail"
+	Summary string `json:"summary"`
+}
+
+// DiagnosticResult is the outcome of one dgx diagnostics check
+type DiagnosticResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "pass", "warn" or "fail"
+	Detail string `json:"detail"`
+	Hint   string `json:"hint,omitempty"` // How to fix a warning or failure
+}
+
+// DiagnosticsReport is the result of dgx diagnostics on one profile
+type DiagnosticsReport struct {
+	Profile string             `json:"profile"`
+	Status  string             `json:"status"` // The worst check status
+	Checks  []DiagnosticResult `json:"checks"`
+}
+
+// PlaybookStatus represents the state of a playbook's service on the DGX
+type PlaybookStatus struct {
+	Playbook  string `json:"playbook"`
+	Running   bool   `json:"running"`
+	Container string `json:"container,omitempty"` // Container ID and status for containerized services
+	PID       string `json:"pid,omitempty"`       // Process IDs for host services
+	Version   string `json:"version,omitempty"`
+	Health    string `json:"health,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// TuneSetting compares one host limit with the dgx tune recommendation
+type TuneSetting struct {
+	Name        string `json:"name"`
+	Current     string `json:"current"`
+	Recommended string `json:"recommended"`
+	Status      string `json:"status"` // "ok", "change", "manual" or "n/a"
+	File        string `json:"file"`   // Where the setting is configured
+	Hint        string `json:"hint,omitempty"`
+}
+
+// FirmwareComponent compares one installed version with the firmware
+// compatibility matrix
+type FirmwareComponent struct {
+	Name        string `json:"name"`
+	Current     string `json:"current"`
+	Recommended string `json:"recommended,omitempty"`
+	Minimum     string `json:"minimum,omitempty"`
+	Status      string `json:"status"` // "ok", "missing", "upgrade" or "unsupported"
+	Advice      string `json:"advice,omitempty"`
+}
+
+// FirmwareReport is the result of dgx firmware check on one profile
+type FirmwareReport struct {
+	Profile       string              `json:"profile"`
+	Matrix        string              `json:"matrix"` // "embedded" or the local matrix file
+	MatrixVersion string              `json:"matrix_version"`
+	Status        string              `json:"status"` // The worst component status
+	Components    []FirmwareComponent `json:"components"`
 }